@@ -1,6 +1,18 @@
 package gytes
 
-type Access uint32
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Access is implemented by all the typed access flags, the same bit can have different meanings
+// depending on where it's found (0x0020 is ACC_SUPER on a class and ACC_SYNCHRONIZED on a method),
+// so the flags are decoded according to the type holding them.
+type Access interface {
+	Flags() uint16
+	String() string
+}
 
 const (
 	ACC_PUBLIC       = 0x0001 // class, field, method
@@ -27,3 +39,257 @@ const (
 	ACC_MANDATED     = 0x8000 // parameter, module, module *
 	ACC_MODULE       = 0x8000 // class
 )
+
+var InvalidAccessFlagsError = errors.New("Illegal access flags")
+
+// Access flags of a class, see JVMS 4.1
+type ClassAccess uint16
+
+// Access flags of a field, see JVMS 4.5
+type FieldAccess uint16
+
+// Access flags of a method, see JVMS 4.6
+type MethodAccess uint16
+
+// Access flags of a method parameter, see JVMS 4.7.24
+type ParameterAccess uint16
+
+// Access flags of a module, see JVMS 4.7.25
+type ModuleAccess uint16
+
+// Access flags of a module requires entry, see JVMS 4.7.25
+type RequiresAccess uint16
+
+// Access flags of a module exports or opens entry, see JVMS 4.7.25
+type ExportsAccess uint16
+
+type accessFlagName struct {
+	flag uint16
+	name string
+}
+
+// The flag names are listed in the order in which the Java language expects modifiers
+// (JLS 8.1.1, 8.3.1, 8.4.3), flags without a source keyword come last.
+var (
+	classFlagNames = []accessFlagName{
+		{ACC_PUBLIC, "public"},
+		{ACC_ABSTRACT, "abstract"},
+		{ACC_FINAL, "final"},
+		{ACC_SUPER, "super"},
+		{ACC_INTERFACE, "interface"},
+		{ACC_ANNOTATION, "annotation"},
+		{ACC_ENUM, "enum"},
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_MODULE, "module"},
+	}
+	fieldFlagNames = []accessFlagName{
+		{ACC_PUBLIC, "public"},
+		{ACC_PROTECTED, "protected"},
+		{ACC_PRIVATE, "private"},
+		{ACC_STATIC, "static"},
+		{ACC_FINAL, "final"},
+		{ACC_TRANSIENT, "transient"},
+		{ACC_VOLATILE, "volatile"},
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_ENUM, "enum"},
+	}
+	methodFlagNames = []accessFlagName{
+		{ACC_PUBLIC, "public"},
+		{ACC_PROTECTED, "protected"},
+		{ACC_PRIVATE, "private"},
+		{ACC_ABSTRACT, "abstract"},
+		{ACC_STATIC, "static"},
+		{ACC_FINAL, "final"},
+		{ACC_SYNCHRONIZED, "synchronized"},
+		{ACC_NATIVE, "native"},
+		{ACC_STRICT, "strictfp"},
+		{ACC_BRIDGE, "bridge"},
+		{ACC_VARARGS, "varargs"},
+		{ACC_SYNTHETIC, "synthetic"},
+	}
+	parameterFlagNames = []accessFlagName{
+		{ACC_FINAL, "final"},
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_MANDATED, "mandated"},
+	}
+	moduleFlagNames = []accessFlagName{
+		{ACC_OPEN, "open"},
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_MANDATED, "mandated"},
+	}
+	requiresFlagNames = []accessFlagName{
+		{ACC_TRANSITIVE, "transitive"},
+		{ACC_STATIC_PHASE, "static"},
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_MANDATED, "mandated"},
+	}
+	exportsFlagNames = []accessFlagName{
+		{ACC_SYNTHETIC, "synthetic"},
+		{ACC_MANDATED, "mandated"},
+	}
+)
+
+// Formats the flags as space separated names, bits that have no meaning in the given context
+// are printed in hexadecimal at the end.
+func accessString(flags uint16, names []accessFlagName) string {
+	parts := make([]string, 0)
+	known := uint16(0)
+	for _, n := range names {
+		known |= n.flag
+		if flags&n.flag == n.flag {
+			parts = append(parts, n.name)
+		}
+	}
+	if unknown := flags &^ known; unknown != 0 {
+		parts = append(parts, fmt.Sprintf("0x%04x", unknown))
+	}
+	return strings.Join(parts, " ")
+}
+
+func invalidAccess(kind string, flags Access, reason string) error {
+	return fmt.Errorf("%w on %s [%s]: %s", InvalidAccessFlagsError, kind, flags, reason)
+}
+
+// Returns true if more than one of the public, private and protected flags is set.
+func conflictingVisibility(flags uint16) bool {
+	count := 0
+	for _, f := range []uint16{ACC_PUBLIC, ACC_PRIVATE, ACC_PROTECTED} {
+		if flags&f != 0 {
+			count++
+		}
+	}
+	return count > 1
+}
+
+func (a ClassAccess) Has(flag ClassAccess) bool { return a&flag == flag }
+func (a ClassAccess) Flags() uint16             { return uint16(a) }
+func (a ClassAccess) String() string            { return accessString(uint16(a), classFlagNames) }
+
+// Validate checks the class flags against the rules of JVMS 4.1
+func (a ClassAccess) Validate() error {
+	if a.Has(ACC_MODULE) && a != ACC_MODULE {
+		return invalidAccess("class", a, "a module cannot have any other flag")
+	}
+	if a.Has(ACC_INTERFACE) {
+		if !a.Has(ACC_ABSTRACT) {
+			return invalidAccess("class", a, "an interface must be abstract")
+		}
+		if a&(ACC_FINAL|ACC_SUPER|ACC_ENUM) != 0 {
+			return invalidAccess("class", a, "an interface cannot be final, super or enum")
+		}
+		return nil
+	}
+	if a.Has(ACC_ANNOTATION) {
+		return invalidAccess("class", a, "an annotation must be an interface")
+	}
+	if a.Has(ACC_FINAL | ACC_ABSTRACT) {
+		return invalidAccess("class", a, "a class cannot be both final and abstract")
+	}
+	return nil
+}
+
+func (a FieldAccess) Has(flag FieldAccess) bool { return a&flag == flag }
+func (a FieldAccess) Flags() uint16             { return uint16(a) }
+func (a FieldAccess) String() string            { return accessString(uint16(a), fieldFlagNames) }
+
+// Validate checks the field flags against the rules of JVMS 4.5, owner is the access of the declaring class.
+func (a FieldAccess) Validate(owner ClassAccess) error {
+	if owner.Has(ACC_INTERFACE) {
+		if !a.Has(ACC_PUBLIC | ACC_STATIC | ACC_FINAL) {
+			return invalidAccess("field", a, "an interface field must be public static final")
+		}
+		if a&^(ACC_PUBLIC|ACC_STATIC|ACC_FINAL|ACC_SYNTHETIC) != 0 {
+			return invalidAccess("field", a, "an interface field can only be public static final and synthetic")
+		}
+		return nil
+	}
+	if conflictingVisibility(uint16(a)) {
+		return invalidAccess("field", a, "at most one of public, private and protected is allowed")
+	}
+	if a.Has(ACC_FINAL | ACC_VOLATILE) {
+		return invalidAccess("field", a, "a field cannot be both final and volatile")
+	}
+	return nil
+}
+
+func (a MethodAccess) Has(flag MethodAccess) bool { return a&flag == flag }
+func (a MethodAccess) Flags() uint16              { return uint16(a) }
+func (a MethodAccess) String() string             { return accessString(uint16(a), methodFlagNames) }
+
+// Validate checks the method flags against the rules of JVMS 4.6 for class files of version 52 and above,
+// owner is the access of the declaring class and name the name of the method.
+func (a MethodAccess) Validate(owner ClassAccess, name string) error {
+	if name == "<clinit>" {
+		// Only ACC_STATIC matters, the other flags are ignored by the JVM
+		if !a.Has(ACC_STATIC) {
+			return invalidAccess("method", a, "a class initializer must be static")
+		}
+		return nil
+	}
+	if conflictingVisibility(uint16(a)) {
+		return invalidAccess("method", a, "at most one of public, private and protected is allowed")
+	}
+	if owner.Has(ACC_INTERFACE) {
+		if a&(ACC_PROTECTED|ACC_FINAL|ACC_SYNCHRONIZED|ACC_NATIVE) != 0 {
+			return invalidAccess("method", a, "an interface method cannot be protected, final, synchronized or native")
+		}
+		if a&(ACC_PUBLIC|ACC_PRIVATE) == 0 {
+			return invalidAccess("method", a, "an interface method must be either public or private")
+		}
+	}
+	if a.Has(ACC_ABSTRACT) && a&(ACC_PRIVATE|ACC_STATIC|ACC_FINAL|ACC_SYNCHRONIZED|ACC_NATIVE|ACC_STRICT) != 0 {
+		return invalidAccess("method", a, "an abstract method cannot be private, static, final, synchronized, native or strict")
+	}
+	if name == "<init>" && a&^(ACC_PUBLIC|ACC_PRIVATE|ACC_PROTECTED|ACC_VARARGS|ACC_STRICT|ACC_SYNTHETIC) != 0 {
+		return invalidAccess("method", a, "a constructor can only be public, private, protected, varargs, strict and synthetic")
+	}
+	return nil
+}
+
+func (a ParameterAccess) Has(flag ParameterAccess) bool { return a&flag == flag }
+func (a ParameterAccess) Flags() uint16                 { return uint16(a) }
+func (a ParameterAccess) String() string                { return accessString(uint16(a), parameterFlagNames) }
+
+// Validate checks that only the flags allowed by JVMS 4.7.24 are set.
+func (a ParameterAccess) Validate() error {
+	if a&^(ACC_FINAL|ACC_SYNTHETIC|ACC_MANDATED) != 0 {
+		return invalidAccess("parameter", a, "only final, synthetic and mandated are allowed")
+	}
+	return nil
+}
+
+func (a ModuleAccess) Has(flag ModuleAccess) bool { return a&flag == flag }
+func (a ModuleAccess) Flags() uint16              { return uint16(a) }
+func (a ModuleAccess) String() string             { return accessString(uint16(a), moduleFlagNames) }
+
+// Validate checks that only the flags allowed by JVMS 4.7.25 are set.
+func (a ModuleAccess) Validate() error {
+	if a&^(ACC_OPEN|ACC_SYNTHETIC|ACC_MANDATED) != 0 {
+		return invalidAccess("module", a, "only open, synthetic and mandated are allowed")
+	}
+	return nil
+}
+
+func (a RequiresAccess) Has(flag RequiresAccess) bool { return a&flag == flag }
+func (a RequiresAccess) Flags() uint16                { return uint16(a) }
+func (a RequiresAccess) String() string               { return accessString(uint16(a), requiresFlagNames) }
+
+// Validate checks that only the flags allowed by JVMS 4.7.25 are set.
+func (a RequiresAccess) Validate() error {
+	if a&^(ACC_TRANSITIVE|ACC_STATIC_PHASE|ACC_SYNTHETIC|ACC_MANDATED) != 0 {
+		return invalidAccess("requires", a, "only transitive, static phase, synthetic and mandated are allowed")
+	}
+	return nil
+}
+
+func (a ExportsAccess) Has(flag ExportsAccess) bool { return a&flag == flag }
+func (a ExportsAccess) Flags() uint16               { return uint16(a) }
+func (a ExportsAccess) String() string              { return accessString(uint16(a), exportsFlagNames) }
+
+// Validate checks that only the flags allowed by JVMS 4.7.25 are set.
+func (a ExportsAccess) Validate() error {
+	if a&^(ACC_SYNTHETIC|ACC_MANDATED) != 0 {
+		return invalidAccess("exports", a, "only synthetic and mandated are allowed")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package gytes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessFlagsAreDecodedByContext(t *testing.T) {
+	assert.Equal(t, "public super", ClassAccess(ACC_PUBLIC|ACC_SUPER).String())
+	assert.Equal(t, "public synchronized", MethodAccess(ACC_PUBLIC|ACC_SYNCHRONIZED).String())
+	assert.Equal(t, "open", ModuleAccess(ACC_OPEN).String())
+	assert.Equal(t, "transitive static", RequiresAccess(ACC_TRANSITIVE|ACC_STATIC_PHASE).String())
+	assert.Equal(t, "private volatile", FieldAccess(ACC_PRIVATE|ACC_VOLATILE).String())
+	assert.Equal(t, "public static bridge synthetic", MethodAccess(ACC_PUBLIC|ACC_STATIC|ACC_BRIDGE|ACC_SYNTHETIC).String())
+	assert.Equal(t, "final mandated", ParameterAccess(ACC_FINAL|ACC_MANDATED).String())
+	assert.Equal(t, "public 0x0100", FieldAccess(ACC_PUBLIC|ACC_NATIVE).String())
+
+	assert.True(t, MethodAccess(ACC_PUBLIC|ACC_STATIC).Has(ACC_STATIC))
+	assert.False(t, MethodAccess(ACC_PUBLIC|ACC_STATIC).Has(ACC_PUBLIC|ACC_FINAL))
+}
+
+func TestAccessFlagsAreWrittenInSourceOrder(t *testing.T) {
+	access := MethodAccess(ACC_FINAL | ACC_STATIC | ACC_SYNCHRONIZED | ACC_PUBLIC)
+	assert.Equal(t, "public static final synchronized", access.String())
+}
+
+func TestIllegalAccessFlagsAreRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"final abstract class", ClassAccess(ACC_FINAL | ACC_ABSTRACT).Validate()},
+		{"non abstract interface", ClassAccess(ACC_INTERFACE).Validate()},
+		{"annotation class", ClassAccess(ACC_ANNOTATION | ACC_ABSTRACT).Validate()},
+		{"module with flags", ClassAccess(ACC_MODULE | ACC_PUBLIC).Validate()},
+		{"public private field", FieldAccess(ACC_PUBLIC | ACC_PRIVATE).Validate(ACC_PUBLIC)},
+		{"final volatile field", FieldAccess(ACC_FINAL | ACC_VOLATILE).Validate(ACC_PUBLIC)},
+		{"interface instance field", FieldAccess(ACC_PUBLIC).Validate(ACC_INTERFACE | ACC_ABSTRACT)},
+		{"abstract static method", MethodAccess(ACC_ABSTRACT|ACC_STATIC).Validate(ACC_PUBLIC, "run")},
+		{"interface protected method", MethodAccess(ACC_PROTECTED).Validate(ACC_INTERFACE|ACC_ABSTRACT, "run")},
+		{"static constructor", MethodAccess(ACC_STATIC).Validate(ACC_PUBLIC, "<init>")},
+		{"parameter with public", ParameterAccess(ACC_PUBLIC).Validate()},
+	}
+	for _, c := range cases {
+		assert.True(t, errors.Is(c.err, InvalidAccessFlagsError), c.name)
+	}
+
+	assert.Nil(t, ClassAccess(ACC_PUBLIC|ACC_INTERFACE|ACC_ABSTRACT|ACC_ANNOTATION).Validate())
+	assert.Nil(t, FieldAccess(ACC_PUBLIC|ACC_STATIC|ACC_FINAL).Validate(ACC_INTERFACE|ACC_ABSTRACT))
+	assert.Nil(t, MethodAccess(ACC_PUBLIC|ACC_ABSTRACT).Validate(ACC_INTERFACE|ACC_ABSTRACT, "run"))
+	assert.Nil(t, MethodAccess(ACC_STATIC).Validate(ACC_PUBLIC, "<clinit>"))
+}
+
+func TestReadClassHasValidAccess(t *testing.T) {
+	jclass, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	assert.Nil(t, jclass.ValidateAccess())
+	assert.Equal(t, "public static final", jclass.Fields[0].Modifiers.String())
+}
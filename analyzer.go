@@ -0,0 +1,597 @@
+package gytes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Infers the types of the locals and of the operand stack before each instruction of a CodeWriter,
+// to compute the stack map frames of the method. The types are the ones of Frame, except that long
+// and double values take two slots, the second one holding TopType.
+type frameAnalyzer struct {
+	c       *CodeWriter
+	targets map[*Label]int
+	// The types before each instruction, nil for the instructions that are not reached
+	states []*typeState
+	work   []int
+	// The labels of the new instructions, by instruction index, and the classes they create
+	newLabels map[int]*Label
+	news      map[*Label]ClassName
+}
+
+type typeState struct {
+	locals, stack []interface{}
+	// Set when an instruction pops more values than the stack holds
+	underflow bool
+}
+
+func (s *typeState) copy() *typeState {
+	return &typeState{
+		locals: append([]interface{}(nil), s.locals...),
+		stack:  append([]interface{}(nil), s.stack...),
+	}
+}
+
+func isWideType(t interface{}) bool {
+	return t == LongType || t == DoubleType
+}
+
+// Pushes a value, and the top half of long and double values.
+func (s *typeState) push(t interface{}) {
+	s.stack = append(s.stack, t)
+	if isWideType(t) {
+		s.stack = append(s.stack, TopType)
+	}
+}
+
+// Pops n slots and returns them, the first one being the deepest in the stack.
+func (s *typeState) pop(n int) []interface{} {
+	if n > len(s.stack) {
+		s.underflow = true
+		s.stack = s.stack[:0]
+		return make([]interface{}, n)
+	}
+	values := append([]interface{}(nil), s.stack[len(s.stack)-n:]...)
+	s.stack = s.stack[:len(s.stack)-n]
+	return values
+}
+
+func (s *typeState) setLocal(index int, t interface{}) {
+	if index > 0 && isWideType(s.locals[index-1]) {
+		// The value whose top half is overwritten
+		s.locals[index-1] = TopType
+	}
+	s.locals[index] = t
+	if isWideType(t) {
+		s.locals[index+1] = TopType
+	}
+}
+
+// Replaces an uninitialized type by the initialized one, after the call to its constructor.
+func (s *typeState) initialize(uninitialized, initialized interface{}) {
+	for _, values := range [][]interface{}{s.locals, s.stack} {
+		for i, t := range values {
+			if t == uninitialized {
+				values[i] = initialized
+			}
+		}
+	}
+}
+
+// Returns the types of the values pushed by ldc.
+func constantType(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return IntegerType
+	case float32:
+		return FloatType
+	case int64:
+		return LongType
+	case float64:
+		return DoubleType
+	case string:
+		return ClassName("java/lang/String")
+	case ClassName:
+		return ClassName("java/lang/Class")
+	case MethodType:
+		return ClassName("java/lang/invoke/MethodType")
+	case Handle:
+		return ClassName("java/lang/invoke/MethodHandle")
+	case ConstantDynamic:
+		return descriptorFrameType(v.Descriptor)
+	}
+	return TopType
+}
+
+// The descriptors of the arrays created by newarray, by array type
+var newArrayTypes = map[int]ClassName{
+	T_BOOLEAN: "[Z", T_CHAR: "[C", T_FLOAT: "[F", T_DOUBLE: "[D", T_BYTE: "[B", T_SHORT: "[S", T_INT: "[I", T_LONG: "[J",
+}
+
+// Returns the label of a new instruction, used as the type of the uninitialized object it creates.
+func (a *frameAnalyzer) newLabel(index int, insn *codeInsn) *Label {
+	if label, ok := a.newLabels[index]; ok {
+		return label
+	}
+	label := &Label{Offset: insn.offset}
+	a.c.labels[label] = insn.offset
+	a.newLabels[index] = label
+	a.news[label] = insn.owner
+	return label
+}
+
+// Simulates the execution of an instruction on s.
+func (a *frameAnalyzer) execute(index int, insn *codeInsn, s *typeState) error {
+	switch op := insn.opcode; op {
+	case NOP, INEG, LNEG, FNEG, DNEG, I2B, I2C, I2S, GOTO, GOTO_W, RETURN:
+	case ACONST_NULL:
+		s.push(NullType)
+	case ICONST_M1, ICONST_0, ICONST_1, ICONST_2, ICONST_3, ICONST_4, ICONST_5, BIPUSH, SIPUSH:
+		s.push(IntegerType)
+	case LCONST_0, LCONST_1:
+		s.push(LongType)
+	case FCONST_0, FCONST_1, FCONST_2:
+		s.push(FloatType)
+	case DCONST_0, DCONST_1:
+		s.push(DoubleType)
+	case LDC:
+		s.push(constantType(insn.value))
+	case ILOAD:
+		s.push(IntegerType)
+	case LLOAD:
+		s.push(LongType)
+	case FLOAD:
+		s.push(FloatType)
+	case DLOAD:
+		s.push(DoubleType)
+	case ALOAD:
+		s.push(s.locals[insn.operand])
+	case IALOAD, BALOAD, CALOAD, SALOAD:
+		s.pop(2)
+		s.push(IntegerType)
+	case LALOAD:
+		s.pop(2)
+		s.push(LongType)
+	case FALOAD:
+		s.pop(2)
+		s.push(FloatType)
+	case DALOAD:
+		s.pop(2)
+		s.push(DoubleType)
+	case AALOAD:
+		array := s.pop(2)[0]
+		if name, ok := array.(ClassName); ok && strings.HasPrefix(string(name), "[") {
+			s.push(descriptorFrameType(string(name[1:])))
+		} else {
+			s.push(NullType)
+		}
+	case ISTORE, FSTORE, ASTORE:
+		s.setLocal(insn.operand, s.pop(1)[0])
+	case LSTORE, DSTORE:
+		s.setLocal(insn.operand, s.pop(2)[0])
+	case IASTORE, FASTORE, AASTORE, BASTORE, CASTORE, SASTORE:
+		s.pop(3)
+	case LASTORE, DASTORE:
+		s.pop(4)
+	case POP:
+		s.pop(1)
+	case POP2:
+		s.pop(2)
+	case DUP:
+		v := s.pop(1)
+		s.stack = append(s.stack, v[0], v[0])
+	case DUP_X1:
+		v := s.pop(2)
+		s.stack = append(s.stack, v[1], v[0], v[1])
+	case DUP_X2:
+		v := s.pop(3)
+		s.stack = append(s.stack, v[2], v[0], v[1], v[2])
+	case DUP2:
+		v := s.pop(2)
+		s.stack = append(s.stack, v[0], v[1], v[0], v[1])
+	case DUP2_X1:
+		v := s.pop(3)
+		s.stack = append(s.stack, v[1], v[2], v[0], v[1], v[2])
+	case DUP2_X2:
+		v := s.pop(4)
+		s.stack = append(s.stack, v[2], v[3], v[0], v[1], v[2], v[3])
+	case SWAP:
+		v := s.pop(2)
+		s.stack = append(s.stack, v[1], v[0])
+	case IADD, ISUB, IMUL, IDIV, IREM, ISHL, ISHR, IUSHR, IAND, IOR, IXOR, FCMPL, FCMPG:
+		s.pop(2)
+		s.push(IntegerType)
+	case LADD, LSUB, LMUL, LDIV, LREM, LAND, LOR, LXOR:
+		s.pop(4)
+		s.push(LongType)
+	case FADD, FSUB, FMUL, FDIV, FREM:
+		s.pop(2)
+		s.push(FloatType)
+	case DADD, DSUB, DMUL, DDIV, DREM:
+		s.pop(4)
+		s.push(DoubleType)
+	case LSHL, LSHR, LUSHR:
+		s.pop(3)
+		s.push(LongType)
+	case IINC:
+		s.setLocal(insn.operand, IntegerType)
+	case I2L, F2L:
+		s.pop(1)
+		s.push(LongType)
+	case I2F:
+		s.pop(1)
+		s.push(FloatType)
+	case I2D, F2D:
+		s.pop(1)
+		s.push(DoubleType)
+	case L2I, D2I:
+		s.pop(2)
+		s.push(IntegerType)
+	case L2F, D2F:
+		s.pop(2)
+		s.push(FloatType)
+	case L2D:
+		s.pop(2)
+		s.push(DoubleType)
+	case D2L:
+		s.pop(2)
+		s.push(LongType)
+	case F2I, ARRAYLENGTH, INSTANCEOF:
+		s.pop(1)
+		s.push(IntegerType)
+	case LCMP, DCMPL, DCMPG:
+		s.pop(4)
+		s.push(IntegerType)
+	case IFEQ, IFNE, IFLT, IFGE, IFGT, IFLE, IFNULL, IFNONNULL, TABLESWITCH, LOOKUPSWITCH,
+		IRETURN, FRETURN, ARETURN, ATHROW, MONITORENTER, MONITOREXIT:
+		s.pop(1)
+	case IF_ICMPEQ, IF_ICMPNE, IF_ICMPLT, IF_ICMPGE, IF_ICMPGT, IF_ICMPLE, IF_ACMPEQ, IF_ACMPNE, LRETURN, DRETURN:
+		s.pop(2)
+	case GETSTATIC:
+		s.push(descriptorFrameType(insn.descriptor))
+	case PUTSTATIC:
+		s.pop(descriptorSize(insn.descriptor))
+	case GETFIELD:
+		s.pop(1)
+		s.push(descriptorFrameType(insn.descriptor))
+	case PUTFIELD:
+		s.pop(descriptorSize(insn.descriptor) + 1)
+	case INVOKEVIRTUAL, INVOKESPECIAL, INVOKESTATIC, INVOKEINTERFACE, INVOKEDYNAMIC:
+		args, ret, _ := SplitMethodDescriptor(insn.descriptor)
+		for i := len(args) - 1; i >= 0; i-- {
+			s.pop(descriptorSize(args[i]))
+		}
+		if op != INVOKESTATIC && op != INVOKEDYNAMIC {
+			receiver := s.pop(1)[0]
+			if op == INVOKESPECIAL && insn.name == "<init>" {
+				switch r := receiver.(type) {
+				case VerificationType:
+					if r == UninitializedThisType {
+						s.initialize(r, a.c.mw.cw.name)
+					}
+				case *Label:
+					s.initialize(r, a.news[r])
+				}
+			}
+		}
+		if ret != "V" {
+			s.push(descriptorFrameType(ret))
+		}
+	case NEW:
+		s.push(a.newLabel(index, insn))
+	case NEWARRAY:
+		s.pop(1)
+		s.push(newArrayTypes[insn.operand])
+	case ANEWARRAY:
+		s.pop(1)
+		if strings.HasPrefix(string(insn.owner), "[") {
+			s.push("[" + insn.owner)
+		} else {
+			s.push("[L" + insn.owner + ";")
+		}
+	case CHECKCAST:
+		s.pop(1)
+		s.push(insn.owner)
+	case MULTIANEWARRAY:
+		s.pop(insn.operand)
+		s.push(ClassName(insn.descriptor))
+	case JSR, JSR_W, RET:
+		return fmt.Errorf("%w: the frames of %s%s can't be computed, it uses subroutines", InvalidInstructionError, a.c.mw.name, a.c.mw.descriptor)
+	}
+	if s.underflow {
+		return fmt.Errorf("%w: the stack of %s%s underflows at offset %d", InvalidInstructionError, a.c.mw.name, a.c.mw.descriptor, insn.offset)
+	}
+	return nil
+}
+
+// Returns the type both x and y are assignable to.
+func (a *frameAnalyzer) mergeType(x, y interface{}) (interface{}, error) {
+	if x == y {
+		return x, nil
+	}
+	xName, xOk := x.(ClassName)
+	yName, yOk := y.(ClassName)
+	switch {
+	case x == NullType && yOk:
+		return y, nil
+	case y == NullType && xOk:
+		return x, nil
+	case xOk && yOk:
+		return CommonSuperClass(a, xName, yName)
+	}
+	return TopType, nil
+}
+
+// Header implements ClassHierarchy, the class being written is known even if the Hierarchy of the writer doesn't.
+func (a *frameAnalyzer) Header(name ClassName) (*ClassHeader, error) {
+	cw := a.c.mw.cw
+	if name == cw.name {
+		return &ClassHeader{
+			Version:    cw.version,
+			Access:     cw.access,
+			Name:       cw.name,
+			SuperName:  cw.superName,
+			Interfaces: cw.interfaceNames,
+		}, nil
+	}
+	if cw.Hierarchy == nil {
+		return CoreHierarchy.Header(name)
+	}
+	return cw.Hierarchy.Header(name)
+}
+
+// Merges s into the state before the instruction at index, and schedules the instruction if its state changed.
+func (a *frameAnalyzer) merge(index int, s *typeState) error {
+	if index >= len(a.states) {
+		return fmt.Errorf("%w: the execution of %s%s falls off the end of the code", InvalidInstructionError, a.c.mw.name, a.c.mw.descriptor)
+	}
+	current := a.states[index]
+	if current == nil {
+		a.states[index] = s.copy()
+		a.work = append(a.work, index)
+		return nil
+	}
+	if len(current.stack) != len(s.stack) {
+		return fmt.Errorf("%w: inconsistent stack sizes at offset %d of %s%s", InvalidInstructionError, a.c.insns[index].offset, a.c.mw.name, a.c.mw.descriptor)
+	}
+	changed := false
+	for _, values := range [][2][]interface{}{{current.locals, s.locals}, {current.stack, s.stack}} {
+		for i := range values[0] {
+			merged, err := a.mergeType(values[0][i], values[1][i])
+			if err != nil {
+				return err
+			}
+			if merged != values[0][i] {
+				values[0][i] = merged
+				changed = true
+			}
+		}
+	}
+	if changed {
+		a.work = append(a.work, index)
+	}
+	return nil
+}
+
+// Converts a state to an expanded frame, where long and double values take a single element.
+func (s *typeState) frame() Frame {
+	elements := func(slots []interface{}) []interface{} {
+		values := make([]interface{}, 0, len(slots))
+		for i := 0; i < len(slots); i++ {
+			values = append(values, slots[i])
+			if isWideType(slots[i]) {
+				i++
+			}
+		}
+		return values
+	}
+	locals := elements(s.locals)
+	for len(locals) > 0 && locals[len(locals)-1] == TopType {
+		locals = locals[:len(locals)-1]
+	}
+	return Frame{Type: FrameNew, Locals: locals, Stack: elements(s.stack)}
+}
+
+// Computes the stack map frames of the method, see ComputeFrames. The maxs must be computed first.
+// The code that can't be reached is replaced by nops followed by an athrow, as no types can be inferred for it,
+// and it's removed from the ranges of the exception handlers.
+func (c *CodeWriter) computeFrames() error {
+	a := &frameAnalyzer{
+		c:         c,
+		targets:   c.labelTargets(),
+		states:    make([]*typeState, len(c.insns)),
+		newLabels: make(map[int]*Label),
+		news:      make(map[*Label]ClassName),
+	}
+	index := func(label *Label) (int, error) {
+		if _, err := c.offset(label); err != nil {
+			return 0, err
+		}
+		return a.targets[label], nil
+	}
+	type handler struct {
+		start, end, handler int
+		typ                 ClassName
+	}
+	handlers := make([]handler, len(c.handlers))
+	for i, h := range c.handlers {
+		var err error
+		if handlers[i].start, err = index(h.start); err != nil {
+			return err
+		}
+		if handlers[i].end, err = index(h.end); err != nil {
+			return err
+		}
+		if handlers[i].handler, err = index(h.handler); err != nil {
+			return err
+		}
+		handlers[i].typ = h.typ
+		if h.typ == "" {
+			handlers[i].typ = "java/lang/Throwable"
+		}
+	}
+
+	arguments, err := initialFrameLocals(c.mw.cw.name, c.mw.access, c.mw.name, c.mw.descriptor)
+	if err != nil {
+		return err
+	}
+	start := &typeState{locals: make([]interface{}, c.maxLocals)}
+	for i := range start.locals {
+		start.locals[i] = TopType
+	}
+	slot := 0
+	for _, t := range arguments {
+		start.setLocal(slot, t)
+		slot++
+		if isWideType(t) {
+			slot++
+		}
+	}
+	if len(c.insns) == 0 {
+		return nil
+	}
+	if err := a.merge(0, start); err != nil {
+		return err
+	}
+	for len(a.work) > 0 {
+		i := a.work[len(a.work)-1]
+		a.work = a.work[:len(a.work)-1]
+		insn := &c.insns[i]
+		before := a.states[i]
+		after := before.copy()
+		if err := a.execute(i, insn, after); err != nil {
+			return err
+		}
+		for _, h := range handlers {
+			if i < h.start || i >= h.end {
+				continue
+			}
+			// The handler can be reached before or after the instruction changes the locals
+			caught := &typeState{locals: append([]interface{}(nil), before.locals...), stack: []interface{}{h.typ}}
+			for j, t := range after.locals {
+				if caught.locals[j], err = a.mergeType(caught.locals[j], t); err != nil {
+					return err
+				}
+			}
+			if err := a.merge(h.handler, caught); err != nil {
+				return err
+			}
+		}
+		for _, label := range insn.targets {
+			target, err := index(label)
+			if err != nil {
+				return err
+			}
+			if err := a.merge(target, after); err != nil {
+				return err
+			}
+		}
+		if fallsThrough(insn) {
+			if err := a.merge(i+1, after); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Frames are needed at the targets of the jumps and of the handlers, and after the unconditional jumps
+	needed := make(map[int]bool)
+	for i := range c.insns {
+		for _, label := range c.insns[i].targets {
+			needed[a.targets[label]] = true
+		}
+		if !fallsThrough(&c.insns[i]) {
+			needed[i+1] = true
+		}
+	}
+	for _, h := range handlers {
+		needed[h.handler] = true
+	}
+	c.frames = c.frames[:0]
+	c.states = make(map[int]Frame)
+	for i, state := range a.states {
+		if state == nil {
+			continue
+		}
+		frame := state.frame()
+		c.states[c.insns[i].offset] = frame
+		if needed[i] {
+			c.frames = append(c.frames, frameAt{c.insns[i].offset, frame})
+		}
+	}
+	c.removeDeadCode(a.states)
+	sort.Slice(c.frames, func(i, j int) bool { return c.frames[i].offset < c.frames[j].offset })
+	return nil
+}
+
+// Replaces the instructions that are never reached, i.e. the ones without state, by nops ending with an athrow.
+func (c *CodeWriter) removeDeadCode(states []*typeState) {
+	dead := func(i int) bool { return i < len(states) && states[i] == nil }
+	// The offset of each instruction and of the end of the code
+	offset := func(i int) int {
+		if i < len(c.insns) {
+			return c.insns[i].offset
+		}
+		return c.code.Len()
+	}
+	labelAt := func(i int) *Label {
+		label := &Label{Offset: offset(i)}
+		c.labels[label] = label.Offset
+		return label
+	}
+	code := c.code.Bytes()
+	found := false
+	for i := 0; i < len(states); {
+		if !dead(i) {
+			i++
+			continue
+		}
+		found = true
+		start := i
+		for dead(i) {
+			i++
+		}
+		for pc := offset(start); pc < offset(i)-1; pc++ {
+			code[pc] = NOP
+		}
+		code[offset(i)-1] = ATHROW
+		c.frames = append(c.frames, frameAt{offset(start), Frame{Type: FrameNew, Stack: []interface{}{ClassName("java/lang/Throwable")}}})
+	}
+	if !found {
+		return
+	}
+	if c.maxStack < 1 {
+		c.maxStack = 1
+	}
+	jumps := c.jumps[:0]
+	starts := c.labelTargets()
+	for _, j := range c.jumps {
+		if source, ok := c.instructionAt(j.source); !ok || !dead(source) {
+			jumps = append(jumps, j)
+		}
+	}
+	c.jumps = jumps
+	// The handlers only cover the live instructions of their range
+	var handlers []tryCatchBlock
+	for _, h := range c.handlers {
+		for i := starts[h.start]; i < starts[h.end]; {
+			for i < starts[h.end] && dead(i) {
+				i++
+			}
+			start := i
+			for i < starts[h.end] && !dead(i) {
+				i++
+			}
+			if start < i {
+				handlers = append(handlers, tryCatchBlock{labelAt(start), labelAt(i), h.handler, h.typ})
+			}
+		}
+	}
+	c.handlers = handlers
+}
+
+// Returns the index of the instruction starting at offset.
+func (c *CodeWriter) instructionAt(offset int) (int, bool) {
+	i := sort.Search(len(c.insns), func(i int) bool { return c.insns[i].offset >= offset })
+	return i, i < len(c.insns) && c.insns[i].offset == offset
+}
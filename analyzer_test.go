@@ -0,0 +1,206 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A ClassHierarchy of the classes used by the tests
+type testHierarchy map[ClassName]ClassName
+
+func (h testHierarchy) Header(name ClassName) (*ClassHeader, error) {
+	super, ok := h[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", UnknownClassError, name)
+	}
+	return &ClassHeader{Name: name, SuperName: super}, nil
+}
+
+var shapes = testHierarchy{"gen/A": "gen/Base", "gen/B": "gen/Base", "gen/Base": ObjectClassName, "gen/Failure": ObjectClassName}
+
+type frameCollector struct {
+	CodeVisitorAdapter
+	frames []Frame
+}
+
+func (c *frameCollector) VisitFrame(frame Frame) {
+	c.frames = append(c.frames, frame)
+}
+
+// Returns the expanded frames of a method of a class file
+func expandedFrames(t *testing.T, b []byte, method string) []Frame {
+	tracer := &methodTracer{method: method}
+	frames := &frameCollector{}
+	tracer.code = &codeTracer{CodeVisitorAdapter: CodeVisitorAdapter{Next: frames}}
+	assert.Nil(t, (&ClassReader{Options: ExpandFrames}).AcceptBytes(b, tracer))
+	return frames.frames
+}
+
+func computedFrames(hierarchy ClassHierarchy, access MethodAccess, descriptor string, write func(c *CodeWriter)) (*CodeWriter, []byte, error) {
+	cw := NewClassWriter()
+	cw.Options = ComputeFrames
+	cw.Hierarchy = hierarchy
+	cw.Visit(V8, 0, ACC_PUBLIC, "gen/Frames", "", ObjectClassName, nil)
+	mw := cw.NewMethod(access, "f", descriptor, "", nil)
+	write(mw.Code())
+	mw.VisitEnd()
+	b, err := cw.Bytes()
+	return mw.Code(), b, err
+}
+
+// static Base f(int i) { Base b; try { b = i != 0 ? new A() : new B(); } catch (Failure e) { return null; } return b; }
+func writeShapes(c *CodeWriter) {
+	start, other, join, handler := &Label{}, &Label{}, &Label{}, &Label{}
+	c.VisitTryCatchBlock(start, join, handler, "gen/Failure")
+	c.VisitVarInsn(ILOAD, 0)
+	c.VisitJumpInsn(IFEQ, other)
+	c.VisitLabel(start)
+	c.VisitTypeInsn(NEW, "gen/A")
+	c.VisitInsn(DUP)
+	c.VisitMethodInsn(INVOKESPECIAL, "gen/A", "<init>", "()V", false)
+	c.VisitVarInsn(ASTORE, 1)
+	c.VisitJumpInsn(GOTO, join)
+	c.VisitLabel(other)
+	c.VisitTypeInsn(NEW, "gen/B")
+	c.VisitInsn(DUP)
+	c.VisitMethodInsn(INVOKESPECIAL, "gen/B", "<init>", "()V", false)
+	c.VisitVarInsn(ASTORE, 1)
+	c.VisitLabel(join)
+	c.VisitVarInsn(ALOAD, 1)
+	c.VisitInsn(ARETURN)
+	c.VisitLabel(handler)
+	c.VisitVarInsn(ASTORE, 1)
+	c.VisitInsn(ACONST_NULL)
+	c.VisitInsn(ARETURN)
+}
+
+func TestComputeFramesMergesTypes(t *testing.T) {
+	code, b, err := computedFrames(shapes, ACC_STATIC, "(I)Lgen/Base;", writeShapes)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, code.maxStack)
+	assert.Equal(t, 2, code.maxLocals)
+	offsets := make([]int, 0)
+	for _, f := range code.frames {
+		offsets = append(offsets, f.offset)
+	}
+	assert.Equal(t, []int{15, 23, 25}, offsets)
+
+	assert.Equal(t, []Frame{
+		{Type: FrameNew, Locals: []interface{}{IntegerType}},
+		{Type: FrameNew, Locals: []interface{}{IntegerType, ClassName("gen/Base")}},
+		{Type: FrameNew, Locals: []interface{}{IntegerType}, Stack: []interface{}{ClassName("gen/Failure")}},
+	}, expandedFrames(t, b, "f"))
+}
+
+func TestComputeFramesNeedsHierarchy(t *testing.T) {
+	_, _, err := computedFrames(nil, ACC_STATIC, "(I)Lgen/Base;", writeShapes)
+	assert.True(t, errors.Is(err, UnknownClassError))
+}
+
+func TestComputeFramesWithUninitializedValues(t *testing.T) {
+	// static A f(int i) { return new A(i != 0 ? 1 : 0); }
+	code, b, err := computedFrames(shapes, ACC_STATIC, "(I)Lgen/A;", func(c *CodeWriter) {
+		zero, call := &Label{}, &Label{}
+		c.VisitTypeInsn(NEW, "gen/A")
+		c.VisitInsn(DUP)
+		c.VisitVarInsn(ILOAD, 0)
+		c.VisitJumpInsn(IFEQ, zero)
+		c.VisitInsn(ICONST_1)
+		c.VisitJumpInsn(GOTO, call)
+		c.VisitLabel(zero)
+		c.VisitInsn(ICONST_0)
+		c.VisitLabel(call)
+		c.VisitMethodInsn(INVOKESPECIAL, "gen/A", "<init>", "(I)V", false)
+		c.VisitInsn(ARETURN)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, code.maxStack)
+
+	frames := expandedFrames(t, b, "f")
+	assert.Len(t, frames, 2)
+	uninitialized, ok := frames[0].Stack[0].(*Label)
+	assert.True(t, ok)
+	assert.Equal(t, 0, uninitialized.Offset)
+	assert.Equal(t, []interface{}{IntegerType}, frames[0].Locals)
+	assert.Equal(t, []interface{}{uninitialized, uninitialized}, frames[0].Stack)
+	assert.Equal(t, []interface{}{uninitialized, uninitialized, IntegerType}, frames[1].Stack)
+}
+
+func TestComputeFramesReplacesDeadCode(t *testing.T) {
+	code, b, err := computedFrames(shapes, ACC_PUBLIC, "()V", func(c *CodeWriter) {
+		c.VisitInsn(RETURN)
+		c.VisitVarInsn(ALOAD, 0)
+		c.VisitInsn(POP)
+		c.VisitInsn(RETURN)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{RETURN, NOP, NOP, ATHROW}, code.code.Bytes())
+	assert.Equal(t, 1, code.maxStack)
+	assert.Equal(t, []Frame{
+		{Type: FrameNew, Locals: []interface{}{}, Stack: []interface{}{ClassName("java/lang/Throwable")}},
+	}, expandedFrames(t, b, "f"))
+}
+
+func TestComputeFramesOfWidenedJumps(t *testing.T) {
+	code, _, err := computedFrames(shapes, ACC_STATIC, "(I)V", func(c *CodeWriter) {
+		end := &Label{}
+		c.VisitVarInsn(ILOAD, 0)
+		c.VisitJumpInsn(IFEQ, end)
+		for i := 0; i < 40000; i++ {
+			c.VisitInsn(NOP)
+		}
+		c.VisitLabel(end)
+		c.VisitInsn(RETURN)
+	})
+	assert.Nil(t, err)
+	// The instruction following the goto_w is the target of the opposite jump
+	assert.Equal(t, 2, len(code.frames))
+	assert.Equal(t, 9, code.frames[0].offset)
+	assert.Equal(t, 40009, code.frames[1].offset)
+}
+
+func TestComputeFramesIgnoresVisitedFramesOfOldClasses(t *testing.T) {
+	cw := NewClassWriter()
+	cw.Options = ComputeFrames
+	cw.Visit(V5, 0, ACC_PUBLIC, "gen/Old", "", ObjectClassName, nil)
+	mw := cw.NewMethod(ACC_STATIC, "f", "()V", "", nil)
+	code := mw.Code()
+	code.VisitFrame(Frame{Type: FrameSame})
+	code.VisitInsn(RETURN)
+	mw.VisitEnd()
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	assert.False(t, bytes.Contains(b, []byte("StackMapTable")))
+}
+
+func TestCompressFrame(t *testing.T) {
+	previous := []interface{}{IntegerType, LongType}
+	assert.Equal(t, Frame{Type: FrameSame}, compressFrame(previous, Frame{Type: FrameNew, Locals: []interface{}{IntegerType, LongType}}))
+	assert.Equal(t, Frame{Type: FrameSame1, Stack: []interface{}{NullType}},
+		compressFrame(previous, Frame{Type: FrameNew, Locals: []interface{}{IntegerType, LongType}, Stack: []interface{}{NullType}}))
+	assert.Equal(t, Frame{Type: FrameChop, Chopped: 2}, compressFrame(previous, Frame{Type: FrameNew}))
+	assert.Equal(t, Frame{Type: FrameAppend, Locals: []interface{}{FloatType}},
+		compressFrame(previous, Frame{Type: FrameNew, Locals: []interface{}{IntegerType, LongType, FloatType}}))
+	assert.Equal(t, Frame{Type: FrameFull, Locals: []interface{}{FloatType}},
+		compressFrame(previous, Frame{Type: FrameNew, Locals: []interface{}{FloatType}}))
+}
+
+func TestComputeFramesOfReadClass(t *testing.T) {
+	original := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+	cw := NewClassWriter()
+	cw.Options = ComputeFrames
+	assert.Nil(t, (&ClassReader{Options: SkipFrames}).AcceptBytes(original, &maxsEraser{ClassVisitorAdapter{Next: cw}}))
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	class, err := (&ClassReader{}).ReadClassBytes(original)
+	assert.Nil(t, err)
+	for _, method := range class.Methods {
+		assert.Equal(t, expandedFrames(t, original, method.Name), expandedFrames(t, b, method.Name), method.Name)
+	}
+}
@@ -0,0 +1,50 @@
+package gytes
+
+// A Java annotation representation
+//
+// annotation {
+//   u2 type_index;
+//   u2 num_element_value_pairs;
+//   {   u2            element_name_index;
+//       element_value value;
+//   } element_value_pairs[num_element_value_pairs];
+// }
+type Annotation struct {
+	Descriptor string
+	// Visible annotations are stored in the RuntimeVisibleAnnotations attribute,
+	// the others in the RuntimeInvisibleAnnotations attribute
+	Visible bool
+	Values  []AnnotationElement
+}
+
+// A named value of an annotation, Value is one of:
+//
+//   int8, uint16, int16, bool, int32, int64, float32, float64 and string for constants
+//   EnumConstant for enum values
+//   ClassLiteral for class values
+//   *Annotation for nested annotations
+//   []interface{} for arrays of the above
+type AnnotationElement struct {
+	Name  string
+	Value interface{}
+}
+
+// An enum constant used as an annotation value
+type EnumConstant struct {
+	Descriptor string
+	Name       string
+}
+
+// A class literal used as an annotation value, it holds the return descriptor of the class,
+// e.g. Ljava/lang/String; for String.class or V for void.class
+type ClassLiteral string
+
+// Get returns the value of the element with the given name.
+func (a *Annotation) Get(name string) (interface{}, bool) {
+	for _, v := range a.Values {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
@@ -8,222 +8,461 @@ import (
 type ByteCode struct {
 	Name  string
 	Value uint8
-	Size  int
+	// The size of the operands following the opcode, -1 for the variable sized
+	// tableswitch, lookupswitch and wide instructions
+	Size int
+	Kind OperandKind
 }
 
+// OperandKind groups the instructions by the kind of operands they take
+type OperandKind int
+
+const (
+	KindInsn OperandKind = iota
+	KindIntInsn
+	KindVarInsn
+	KindTypeInsn
+	KindFieldInsn
+	KindMethodInsn
+	KindInvokeDynamicInsn
+	KindJumpInsn
+	KindLdcInsn
+	KindIincInsn
+	KindTableSwitchInsn
+	KindLookupSwitchInsn
+	KindMultiANewArrayInsn
+	KindWide
+)
+
 func (bb ByteCode) String() string {
 	return fmt.Sprintf("(%d, %s)", bb.Value, bb.Name)
 }
 
+// The opcodes of all the JVM instructions, named after their mnemonic
+const (
+	NOP             = 0
+	ACONST_NULL     = 1
+	ICONST_M1       = 2
+	ICONST_0        = 3
+	ICONST_1        = 4
+	ICONST_2        = 5
+	ICONST_3        = 6
+	ICONST_4        = 7
+	ICONST_5        = 8
+	LCONST_0        = 9
+	LCONST_1        = 10
+	FCONST_0        = 11
+	FCONST_1        = 12
+	FCONST_2        = 13
+	DCONST_0        = 14
+	DCONST_1        = 15
+	BIPUSH          = 16
+	SIPUSH          = 17
+	LDC             = 18
+	LDC_W           = 19
+	LDC2_W          = 20
+	ILOAD           = 21
+	LLOAD           = 22
+	FLOAD           = 23
+	DLOAD           = 24
+	ALOAD           = 25
+	ILOAD_0         = 26
+	ILOAD_1         = 27
+	ILOAD_2         = 28
+	ILOAD_3         = 29
+	LLOAD_0         = 30
+	LLOAD_1         = 31
+	LLOAD_2         = 32
+	LLOAD_3         = 33
+	FLOAD_0         = 34
+	FLOAD_1         = 35
+	FLOAD_2         = 36
+	FLOAD_3         = 37
+	DLOAD_0         = 38
+	DLOAD_1         = 39
+	DLOAD_2         = 40
+	DLOAD_3         = 41
+	ALOAD_0         = 42
+	ALOAD_1         = 43
+	ALOAD_2         = 44
+	ALOAD_3         = 45
+	IALOAD          = 46
+	LALOAD          = 47
+	FALOAD          = 48
+	DALOAD          = 49
+	AALOAD          = 50
+	BALOAD          = 51
+	CALOAD          = 52
+	SALOAD          = 53
+	ISTORE          = 54
+	LSTORE          = 55
+	FSTORE          = 56
+	DSTORE          = 57
+	ASTORE          = 58
+	ISTORE_0        = 59
+	ISTORE_1        = 60
+	ISTORE_2        = 61
+	ISTORE_3        = 62
+	LSTORE_0        = 63
+	LSTORE_1        = 64
+	LSTORE_2        = 65
+	LSTORE_3        = 66
+	FSTORE_0        = 67
+	FSTORE_1        = 68
+	FSTORE_2        = 69
+	FSTORE_3        = 70
+	DSTORE_0        = 71
+	DSTORE_1        = 72
+	DSTORE_2        = 73
+	DSTORE_3        = 74
+	ASTORE_0        = 75
+	ASTORE_1        = 76
+	ASTORE_2        = 77
+	ASTORE_3        = 78
+	IASTORE         = 79
+	LASTORE         = 80
+	FASTORE         = 81
+	DASTORE         = 82
+	AASTORE         = 83
+	BASTORE         = 84
+	CASTORE         = 85
+	SASTORE         = 86
+	POP             = 87
+	POP2            = 88
+	DUP             = 89
+	DUP_X1          = 90
+	DUP_X2          = 91
+	DUP2            = 92
+	DUP2_X1         = 93
+	DUP2_X2         = 94
+	SWAP            = 95
+	IADD            = 96
+	LADD            = 97
+	FADD            = 98
+	DADD            = 99
+	ISUB            = 100
+	LSUB            = 101
+	FSUB            = 102
+	DSUB            = 103
+	IMUL            = 104
+	LMUL            = 105
+	FMUL            = 106
+	DMUL            = 107
+	IDIV            = 108
+	LDIV            = 109
+	FDIV            = 110
+	DDIV            = 111
+	IREM            = 112
+	LREM            = 113
+	FREM            = 114
+	DREM            = 115
+	INEG            = 116
+	LNEG            = 117
+	FNEG            = 118
+	DNEG            = 119
+	ISHL            = 120
+	LSHL            = 121
+	ISHR            = 122
+	LSHR            = 123
+	IUSHR           = 124
+	LUSHR           = 125
+	IAND            = 126
+	LAND            = 127
+	IOR             = 128
+	LOR             = 129
+	IXOR            = 130
+	LXOR            = 131
+	IINC            = 132
+	I2L             = 133
+	I2F             = 134
+	I2D             = 135
+	L2I             = 136
+	L2F             = 137
+	L2D             = 138
+	F2I             = 139
+	F2L             = 140
+	F2D             = 141
+	D2I             = 142
+	D2L             = 143
+	D2F             = 144
+	I2B             = 145
+	I2C             = 146
+	I2S             = 147
+	LCMP            = 148
+	FCMPL           = 149
+	FCMPG           = 150
+	DCMPL           = 151
+	DCMPG           = 152
+	IFEQ            = 153
+	IFNE            = 154
+	IFLT            = 155
+	IFGE            = 156
+	IFGT            = 157
+	IFLE            = 158
+	IF_ICMPEQ       = 159
+	IF_ICMPNE       = 160
+	IF_ICMPLT       = 161
+	IF_ICMPGE       = 162
+	IF_ICMPGT       = 163
+	IF_ICMPLE       = 164
+	IF_ACMPEQ       = 165
+	IF_ACMPNE       = 166
+	GOTO            = 167
+	JSR             = 168
+	RET             = 169
+	TABLESWITCH     = 170
+	LOOKUPSWITCH    = 171
+	IRETURN         = 172
+	LRETURN         = 173
+	FRETURN         = 174
+	DRETURN         = 175
+	ARETURN         = 176
+	RETURN          = 177
+	GETSTATIC       = 178
+	PUTSTATIC       = 179
+	GETFIELD        = 180
+	PUTFIELD        = 181
+	INVOKEVIRTUAL   = 182
+	INVOKESPECIAL   = 183
+	INVOKESTATIC    = 184
+	INVOKEINTERFACE = 185
+	INVOKEDYNAMIC   = 186
+	NEW             = 187
+	NEWARRAY        = 188
+	ANEWARRAY       = 189
+	ARRAYLENGTH     = 190
+	ATHROW          = 191
+	CHECKCAST       = 192
+	INSTANCEOF      = 193
+	MONITORENTER    = 194
+	MONITOREXIT     = 195
+	WIDE            = 196
+	MULTIANEWARRAY  = 197
+	IFNULL          = 198
+	IFNONNULL       = 199
+	GOTO_W          = 200
+	JSR_W           = 201
+	BREAKPOINT      = 202
+)
+
+// The array types of the newarray instruction
+const (
+	T_BOOLEAN = 4
+	T_CHAR    = 5
+	T_FLOAT   = 6
+	T_DOUBLE  = 7
+	T_BYTE    = 8
+	T_SHORT   = 9
+	T_INT     = 10
+	T_LONG    = 11
+)
+
 var UnknownByteCodeError = errors.New("Unknown bytecode number")
 
 var ByteCodes = []ByteCode{
-	{"nop", 0, 0},
-	{"aconst_null", 1, 0},
-	{"iconst_m1", 2, 0},
-	{"iconst_0", 3, 0},
-	{"iconst_1", 4, 0},
-	{"iconst_2", 5, 0},
-	{"iconst_3", 6, 0},
-	{"iconst_4", 7, 0},
-	{"iconst_5", 8, 0},
-	{"lconst_0", 9, 0},
-	{"lconst_1", 10, 0},
-	{"fconst_0", 11, 0},
-	{"fconst_1", 12, 0},
-	{"fconst_2", 13, 0},
-	{"dconst_0", 14, 0},
-	{"dconst_1", 15, 0},
-	{"bipush", 16, 1},
-	{"sipush", 17, 2},
-	{"ldc", 18, 1},
-	{"ldc_w", 19, 2},
-	{"ldc2_w", 20, 2},
-	{"iload", 21, 1},
-	{"lload", 22, 1},
-	{"fload", 23, 1},
-	{"dload", 24, 1},
-	{"aload", 25, 1},
-	{"iload_0", 26, 0},
-	{"iload_1", 27, 0},
-	{"iload_2", 28, 0},
-	{"iload_3", 29, 0},
-	{"lload_0", 30, 0},
-	{"lload_1", 31, 0},
-	{"lload_2", 32, 0},
-	{"lload_3", 33, 0},
-	{"fload_0", 34, 0},
-	{"fload_1", 35, 0},
-	{"fload_2", 36, 0},
-	{"fload_3", 37, 0},
-	{"dload_0", 38, 0},
-	{"dload_1", 39, 0},
-	{"dload_2", 40, 0},
-	{"dload_3", 41, 0},
-	{"aload_0", 42, 0},
-	{"aload_1", 43, 0},
-	{"aload_2", 44, 0},
-	{"aload_3", 45, 0},
-	{"iaload", 46, 0},
-	{"laload", 47, 0},
-	{"faload", 48, 0},
-	{"daload", 49, 0},
-	{"aaload", 50, 0},
-	{"baload", 51, 0},
-	{"caload", 52, 0},
-	{"saload", 53, 0},
-	{"istore", 54, 0},
-	{"lstore", 55, 0},
-	{"fstore", 56, 0},
-	{"dstore", 57, 0},
-	{"astore", 58, 0},
-	{"istore_0", 59, 0},
-	{"istore_1", 60, 0},
-	{"istore_2", 61, 0},
-	{"istore_3", 62, 0},
-	{"lstore_0", 63, 0},
-	{"lstore_1", 64, 0},
-	{"lstore_2", 65, 0},
-	{"lstore_3", 66, 0},
-	{"fstore_0", 67, 0},
-	{"fstore_1", 68, 0},
-	{"fstore_2", 69, 0},
-	{"fstore_3", 70, 0},
-	{"dstore_0", 71, 0},
-	{"dstore_1", 72, 0},
-	{"dstore_2", 73, 0},
-	{"dstore_3", 74, 0},
-	{"astore_0", 75, 0},
-	{"astore_1", 76, 0},
-	{"astore_2", 77, 0},
-	{"astore_3", 78, 0},
-	{"iastore", 79, 0},
-	{"lastore", 80, 0},
-	{"fastore", 81, 0},
-	{"dastore", 82, 0},
-	{"aastore", 83, 0},
-	{"bastore", 84, 0},
-	{"castore", 85, 0},
-	{"sastore", 86, 0},
-	{"pop", 87, 0},
-	{"pop2", 88, 0},
-	{"dup", 89, 0},
-	{"dup_x1", 90, 0},
-	{"dup_x2", 91, 0},
-	{"dup2", 92, 0},
-	{"dup2_x1", 93, 0},
-	{"dup2_x2", 94, 0},
-	{"swap", 95, 0},
-	{"iadd", 96, 0},
-	{"ladd", 97, 0},
-	{"fadd", 98, 0},
-	{"dadd", 99, 0},
-	{"isub", 100, 0},
-	{"lsub", 101, 0},
-	{"fsub", 102, 0},
-	{"dsub", 103, 0},
-	{"imul", 104, 0},
-	{"lmul", 105, 0},
-	{"fmul", 106, 0},
-	{"dmul", 107, 0},
-	{"idiv", 108, 0},
-	{"ldiv", 109, 0},
-	{"fdiv", 110, 0},
-	{"ddiv", 111, 0},
-	{"irem", 112, 0},
-	{"lrem", 113, 0},
-	{"frem", 114, 0},
-	{"drem", 115, 0},
-	{"ineg", 116, 0},
-	{"lneg", 117, 0},
-	{"fneg", 118, 0},
-	{"dneg", 119, 0},
-	{"ishl", 120, 0},
-	{"lshl", 121, 0},
-	{"ishr", 122, 0},
-	{"lshr", 123, 0},
-	{"iushr", 124, 0},
-	{"lushr", 125, 0},
-	{"iand", 126, 0},
-	{"land", 127, 0},
-	{"ior", 128, 0},
-	{"lor", 129, 0},
-	{"ixor", 130, 0},
-	{"lxor", 131, 0},
-	{"iinc", 132, 0},
-	{"i2l", 133, 0},
-	{"i2f", 134, 0},
-	{"i2d", 135, 0},
-	{"l2i", 136, 0},
-	{"l2f", 137, 0},
-	{"l2d", 138, 0},
-	{"f2i", 139, 0},
-	{"f2l", 140, 0},
-	{"f2d", 141, 0},
-	{"d2i", 142, 0},
-	{"d2l", 143, 0},
-	{"d2f", 144, 0},
-	{"i2b", 145, 0},
-	{"i2c", 146, 0},
-	{"i2s", 147, 0},
-	{"lcmp", 148, 0},
-	{"fcmpl", 149, 0},
-	{"fcmpg", 150, 0},
-	{"dcmpl", 151, 0},
-	{"dcmpg", 152, 0},
-	{"ifeq", 153, 2},
-	{"ifne", 154, 2},
-	{"iflt", 155, 2},
-	{"ifge", 156, 2},
-	{"ifgt", 157, 2},
-	{"ifle", 158, 2},
-	{"if_icmpeq", 159, 2},
-	{"if_icmpne", 160, 2},
-	{"if_icmplt", 161, 2},
-	{"if_icmpge", 162, 2},
-	{"if_icmpgt", 163, 2},
-	{"if_icmple", 164, 2},
-	{"if_acmpeq", 165, 2},
-	{"if_acmpne", 166, 2},
-	{"goto", 167, 2},
-	{"jsr", 168, 2},
-	{"ret", 169, 1},
-	// TODO: This is tricky, let's handle it later
-	{"tableswitch", 170, 0},
-	// TODO: This is tricky, let's handle it later
-	{"lookupswitch", 171, 0},
-	{"ireturn", 172, 0},
-	{"lreturn", 173, 0},
-	{"freturn", 174, 0},
-	{"dreturn", 175, 0},
-	{"areturn", 176, 0},
-	{"return", 177, 0},
-	{"getstatic", 178, 2},
-	{"putstatic", 179, 2},
-	{"getfield", 180, 2},
-	{"putfield", 181, 2},
-	{"invokevirtual", 182, 2},
-	{"invokespecial", 183, 2},
-	{"invokestatic", 184, 2},
-	{"invokeinterface", 185, 4},
-	{"invokedynamic", 186, 4},
-	{"new", 187, 2},
-	{"newarray", 188, 1},
-	{"anewarray", 189, 2},
-	{"arraylength", 190, 0},
-	{"athrow", 191, 0},
-	{"checkcast", 192, 2},
-	{"instanceof", 193, 2},
-	{"monitorenter", 194, 0},
-	{"monitorexit", 195, 0},
-	// TODO: this is tricky, let's handle it later
-	{"wide", 196, 0},
-	{"multianewarray", 197, 3},
-	{"ifnull", 198, 2},
-	{"ifnonnull", 199, 2},
-	{"goto_w", 200, 4},
-	{"jsr_w", 201, 4},
-	{"breakpoint", 202, 0},
+	{"nop", 0, 0, KindInsn},
+	{"aconst_null", 1, 0, KindInsn},
+	{"iconst_m1", 2, 0, KindInsn},
+	{"iconst_0", 3, 0, KindInsn},
+	{"iconst_1", 4, 0, KindInsn},
+	{"iconst_2", 5, 0, KindInsn},
+	{"iconst_3", 6, 0, KindInsn},
+	{"iconst_4", 7, 0, KindInsn},
+	{"iconst_5", 8, 0, KindInsn},
+	{"lconst_0", 9, 0, KindInsn},
+	{"lconst_1", 10, 0, KindInsn},
+	{"fconst_0", 11, 0, KindInsn},
+	{"fconst_1", 12, 0, KindInsn},
+	{"fconst_2", 13, 0, KindInsn},
+	{"dconst_0", 14, 0, KindInsn},
+	{"dconst_1", 15, 0, KindInsn},
+	{"bipush", 16, 1, KindIntInsn},
+	{"sipush", 17, 2, KindIntInsn},
+	{"ldc", 18, 1, KindLdcInsn},
+	{"ldc_w", 19, 2, KindLdcInsn},
+	{"ldc2_w", 20, 2, KindLdcInsn},
+	{"iload", 21, 1, KindVarInsn},
+	{"lload", 22, 1, KindVarInsn},
+	{"fload", 23, 1, KindVarInsn},
+	{"dload", 24, 1, KindVarInsn},
+	{"aload", 25, 1, KindVarInsn},
+	{"iload_0", 26, 0, KindVarInsn},
+	{"iload_1", 27, 0, KindVarInsn},
+	{"iload_2", 28, 0, KindVarInsn},
+	{"iload_3", 29, 0, KindVarInsn},
+	{"lload_0", 30, 0, KindVarInsn},
+	{"lload_1", 31, 0, KindVarInsn},
+	{"lload_2", 32, 0, KindVarInsn},
+	{"lload_3", 33, 0, KindVarInsn},
+	{"fload_0", 34, 0, KindVarInsn},
+	{"fload_1", 35, 0, KindVarInsn},
+	{"fload_2", 36, 0, KindVarInsn},
+	{"fload_3", 37, 0, KindVarInsn},
+	{"dload_0", 38, 0, KindVarInsn},
+	{"dload_1", 39, 0, KindVarInsn},
+	{"dload_2", 40, 0, KindVarInsn},
+	{"dload_3", 41, 0, KindVarInsn},
+	{"aload_0", 42, 0, KindVarInsn},
+	{"aload_1", 43, 0, KindVarInsn},
+	{"aload_2", 44, 0, KindVarInsn},
+	{"aload_3", 45, 0, KindVarInsn},
+	{"iaload", 46, 0, KindInsn},
+	{"laload", 47, 0, KindInsn},
+	{"faload", 48, 0, KindInsn},
+	{"daload", 49, 0, KindInsn},
+	{"aaload", 50, 0, KindInsn},
+	{"baload", 51, 0, KindInsn},
+	{"caload", 52, 0, KindInsn},
+	{"saload", 53, 0, KindInsn},
+	{"istore", 54, 1, KindVarInsn},
+	{"lstore", 55, 1, KindVarInsn},
+	{"fstore", 56, 1, KindVarInsn},
+	{"dstore", 57, 1, KindVarInsn},
+	{"astore", 58, 1, KindVarInsn},
+	{"istore_0", 59, 0, KindVarInsn},
+	{"istore_1", 60, 0, KindVarInsn},
+	{"istore_2", 61, 0, KindVarInsn},
+	{"istore_3", 62, 0, KindVarInsn},
+	{"lstore_0", 63, 0, KindVarInsn},
+	{"lstore_1", 64, 0, KindVarInsn},
+	{"lstore_2", 65, 0, KindVarInsn},
+	{"lstore_3", 66, 0, KindVarInsn},
+	{"fstore_0", 67, 0, KindVarInsn},
+	{"fstore_1", 68, 0, KindVarInsn},
+	{"fstore_2", 69, 0, KindVarInsn},
+	{"fstore_3", 70, 0, KindVarInsn},
+	{"dstore_0", 71, 0, KindVarInsn},
+	{"dstore_1", 72, 0, KindVarInsn},
+	{"dstore_2", 73, 0, KindVarInsn},
+	{"dstore_3", 74, 0, KindVarInsn},
+	{"astore_0", 75, 0, KindVarInsn},
+	{"astore_1", 76, 0, KindVarInsn},
+	{"astore_2", 77, 0, KindVarInsn},
+	{"astore_3", 78, 0, KindVarInsn},
+	{"iastore", 79, 0, KindInsn},
+	{"lastore", 80, 0, KindInsn},
+	{"fastore", 81, 0, KindInsn},
+	{"dastore", 82, 0, KindInsn},
+	{"aastore", 83, 0, KindInsn},
+	{"bastore", 84, 0, KindInsn},
+	{"castore", 85, 0, KindInsn},
+	{"sastore", 86, 0, KindInsn},
+	{"pop", 87, 0, KindInsn},
+	{"pop2", 88, 0, KindInsn},
+	{"dup", 89, 0, KindInsn},
+	{"dup_x1", 90, 0, KindInsn},
+	{"dup_x2", 91, 0, KindInsn},
+	{"dup2", 92, 0, KindInsn},
+	{"dup2_x1", 93, 0, KindInsn},
+	{"dup2_x2", 94, 0, KindInsn},
+	{"swap", 95, 0, KindInsn},
+	{"iadd", 96, 0, KindInsn},
+	{"ladd", 97, 0, KindInsn},
+	{"fadd", 98, 0, KindInsn},
+	{"dadd", 99, 0, KindInsn},
+	{"isub", 100, 0, KindInsn},
+	{"lsub", 101, 0, KindInsn},
+	{"fsub", 102, 0, KindInsn},
+	{"dsub", 103, 0, KindInsn},
+	{"imul", 104, 0, KindInsn},
+	{"lmul", 105, 0, KindInsn},
+	{"fmul", 106, 0, KindInsn},
+	{"dmul", 107, 0, KindInsn},
+	{"idiv", 108, 0, KindInsn},
+	{"ldiv", 109, 0, KindInsn},
+	{"fdiv", 110, 0, KindInsn},
+	{"ddiv", 111, 0, KindInsn},
+	{"irem", 112, 0, KindInsn},
+	{"lrem", 113, 0, KindInsn},
+	{"frem", 114, 0, KindInsn},
+	{"drem", 115, 0, KindInsn},
+	{"ineg", 116, 0, KindInsn},
+	{"lneg", 117, 0, KindInsn},
+	{"fneg", 118, 0, KindInsn},
+	{"dneg", 119, 0, KindInsn},
+	{"ishl", 120, 0, KindInsn},
+	{"lshl", 121, 0, KindInsn},
+	{"ishr", 122, 0, KindInsn},
+	{"lshr", 123, 0, KindInsn},
+	{"iushr", 124, 0, KindInsn},
+	{"lushr", 125, 0, KindInsn},
+	{"iand", 126, 0, KindInsn},
+	{"land", 127, 0, KindInsn},
+	{"ior", 128, 0, KindInsn},
+	{"lor", 129, 0, KindInsn},
+	{"ixor", 130, 0, KindInsn},
+	{"lxor", 131, 0, KindInsn},
+	{"iinc", 132, 2, KindIincInsn},
+	{"i2l", 133, 0, KindInsn},
+	{"i2f", 134, 0, KindInsn},
+	{"i2d", 135, 0, KindInsn},
+	{"l2i", 136, 0, KindInsn},
+	{"l2f", 137, 0, KindInsn},
+	{"l2d", 138, 0, KindInsn},
+	{"f2i", 139, 0, KindInsn},
+	{"f2l", 140, 0, KindInsn},
+	{"f2d", 141, 0, KindInsn},
+	{"d2i", 142, 0, KindInsn},
+	{"d2l", 143, 0, KindInsn},
+	{"d2f", 144, 0, KindInsn},
+	{"i2b", 145, 0, KindInsn},
+	{"i2c", 146, 0, KindInsn},
+	{"i2s", 147, 0, KindInsn},
+	{"lcmp", 148, 0, KindInsn},
+	{"fcmpl", 149, 0, KindInsn},
+	{"fcmpg", 150, 0, KindInsn},
+	{"dcmpl", 151, 0, KindInsn},
+	{"dcmpg", 152, 0, KindInsn},
+	{"ifeq", 153, 2, KindJumpInsn},
+	{"ifne", 154, 2, KindJumpInsn},
+	{"iflt", 155, 2, KindJumpInsn},
+	{"ifge", 156, 2, KindJumpInsn},
+	{"ifgt", 157, 2, KindJumpInsn},
+	{"ifle", 158, 2, KindJumpInsn},
+	{"if_icmpeq", 159, 2, KindJumpInsn},
+	{"if_icmpne", 160, 2, KindJumpInsn},
+	{"if_icmplt", 161, 2, KindJumpInsn},
+	{"if_icmpge", 162, 2, KindJumpInsn},
+	{"if_icmpgt", 163, 2, KindJumpInsn},
+	{"if_icmple", 164, 2, KindJumpInsn},
+	{"if_acmpeq", 165, 2, KindJumpInsn},
+	{"if_acmpne", 166, 2, KindJumpInsn},
+	{"goto", 167, 2, KindJumpInsn},
+	{"jsr", 168, 2, KindJumpInsn},
+	{"ret", 169, 1, KindVarInsn},
+	{"tableswitch", 170, -1, KindTableSwitchInsn},
+	{"lookupswitch", 171, -1, KindLookupSwitchInsn},
+	{"ireturn", 172, 0, KindInsn},
+	{"lreturn", 173, 0, KindInsn},
+	{"freturn", 174, 0, KindInsn},
+	{"dreturn", 175, 0, KindInsn},
+	{"areturn", 176, 0, KindInsn},
+	{"return", 177, 0, KindInsn},
+	{"getstatic", 178, 2, KindFieldInsn},
+	{"putstatic", 179, 2, KindFieldInsn},
+	{"getfield", 180, 2, KindFieldInsn},
+	{"putfield", 181, 2, KindFieldInsn},
+	{"invokevirtual", 182, 2, KindMethodInsn},
+	{"invokespecial", 183, 2, KindMethodInsn},
+	{"invokestatic", 184, 2, KindMethodInsn},
+	{"invokeinterface", 185, 4, KindMethodInsn},
+	{"invokedynamic", 186, 4, KindInvokeDynamicInsn},
+	{"new", 187, 2, KindTypeInsn},
+	{"newarray", 188, 1, KindIntInsn},
+	{"anewarray", 189, 2, KindTypeInsn},
+	{"arraylength", 190, 0, KindInsn},
+	{"athrow", 191, 0, KindInsn},
+	{"checkcast", 192, 2, KindTypeInsn},
+	{"instanceof", 193, 2, KindTypeInsn},
+	{"monitorenter", 194, 0, KindInsn},
+	{"monitorexit", 195, 0, KindInsn},
+	{"wide", 196, -1, KindWide},
+	{"multianewarray", 197, 3, KindMultiANewArrayInsn},
+	{"ifnull", 198, 2, KindJumpInsn},
+	{"ifnonnull", 199, 2, KindJumpInsn},
+	{"goto_w", 200, 4, KindJumpInsn},
+	{"jsr_w", 201, 4, KindJumpInsn},
+	{"breakpoint", 202, 0, KindInsn},
 }
 
 // CreateByteCode creates a ByteCode from a byteCode number
@@ -0,0 +1,109 @@
+package gytes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClassName is the name of a class in its internal form, the form used in the class file itself,
+// e.g. java/util/Map$Entry, array classes are named by their descriptor, e.g. [Ljava/lang/String;
+//
+// The other forms of the same name are:
+//
+//	binary:     java.util.Map$Entry
+//	canonical:  java.util.Map.Entry
+//	descriptor: Ljava/util/Map$Entry;
+type ClassName string
+
+const ObjectClassName ClassName = "java/lang/Object"
+
+// NewClassName creates a class name from either its internal or binary form,
+// binary array names such as [Ljava.lang.String; are accepted as well.
+func NewClassName(name string) ClassName {
+	return ClassName(strings.ReplaceAll(name, ".", "/"))
+}
+
+// ClassNameFromDescriptor creates a class name from an object or array field descriptor.
+func ClassNameFromDescriptor(descriptor string) (ClassName, error) {
+	if strings.HasPrefix(descriptor, "[") {
+		return ClassName(descriptor), nil
+	}
+	if len(descriptor) < 3 || descriptor[0] != 'L' || descriptor[len(descriptor)-1] != ';' {
+		return "", fmt.Errorf("Not a class descriptor %s", descriptor)
+	}
+	return ClassName(descriptor[1 : len(descriptor)-1]), nil
+}
+
+// Internal returns the internal form of the name, e.g. java/util/Map$Entry
+func (cn ClassName) Internal() string {
+	return string(cn)
+}
+
+// Binary returns the binary form of the name as defined by JLS 13.1, e.g. java.util.Map$Entry
+func (cn ClassName) Binary() string {
+	return strings.ReplaceAll(string(cn), "/", ".")
+}
+
+// Canonical returns the name as it is written in Java source, e.g. java.util.Map.Entry or java.lang.String[]
+// The class file does not record where the nested class name starts, so a '$' is assumed to
+// separate the outer class from the nested class. A malformed array name is returned unchanged.
+func (cn ClassName) Canonical() string {
+	if cn.IsArray() {
+		dims := strings.LastIndexByte(string(cn), '[') + 1
+		component := string(cn)[dims:]
+		var name string
+		if className, err := ClassNameFromDescriptor(component); err == nil {
+			name = className.Canonical()
+		} else if len(component) == 1 && component[0] != 'L' {
+			name = primitiveName(component[0])
+		} else {
+			return string(cn)
+		}
+		return name + strings.Repeat("[]", dims)
+	}
+	return strings.ReplaceAll(cn.Binary(), "$", ".")
+}
+
+// Descriptor returns the field descriptor of the class, e.g. Ljava/util/Map$Entry;
+func (cn ClassName) Descriptor() string {
+	if cn.IsArray() {
+		return string(cn)
+	}
+	return "L" + string(cn) + ";"
+}
+
+// Package returns the binary name of the package of the class, e.g. java.util
+// Classes in the unnamed package and array classes return an empty string.
+func (cn ClassName) Package() string {
+	if cn.IsArray() {
+		return ""
+	}
+	idx := strings.LastIndexByte(string(cn), '/')
+	if idx < 0 {
+		return ""
+	}
+	return strings.ReplaceAll(string(cn)[:idx], "/", ".")
+}
+
+// SimpleName returns the name of the class without its package and enclosing classes, e.g. Entry
+func (cn ClassName) SimpleName() string {
+	canonical := cn.Canonical()
+	return canonical[strings.LastIndexByte(canonical, '.')+1:]
+}
+
+func (cn ClassName) IsArray() bool {
+	return strings.HasPrefix(string(cn), "[")
+}
+
+func (cn ClassName) String() string {
+	return string(cn)
+}
+
+func primitiveName(vmRep byte) string {
+	for _, t := range []JType{JBool, JByte, JShort, JChar, JInt, JLong, JFloat, JDouble, JVoid} {
+		if t.VMRep[0] == vmRep {
+			return t.Name
+		}
+	}
+	return string(vmRep)
+}
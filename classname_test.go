@@ -0,0 +1,59 @@
+package gytes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassNameConversions(t *testing.T) {
+	name := NewClassName("java.util.Map$Entry")
+	assert.Equal(t, ClassName("java/util/Map$Entry"), name)
+	assert.Equal(t, "java/util/Map$Entry", name.Internal())
+	assert.Equal(t, "java.util.Map$Entry", name.Binary())
+	assert.Equal(t, "java.util.Map.Entry", name.Canonical())
+	assert.Equal(t, "Ljava/util/Map$Entry;", name.Descriptor())
+	assert.Equal(t, "java.util", name.Package())
+	assert.Equal(t, "Entry", name.SimpleName())
+	assert.Equal(t, name, NewClassName(name.Internal()))
+
+	fromDesc, err := ClassNameFromDescriptor(name.Descriptor())
+	assert.Nil(t, err)
+	assert.Equal(t, name, fromDesc)
+
+	_, err = ClassNameFromDescriptor("I")
+	assert.NotNil(t, err)
+}
+
+func TestArrayClassNames(t *testing.T) {
+	strings := NewClassName("[[Ljava.lang.String;")
+	assert.True(t, strings.IsArray())
+	assert.Equal(t, "[[Ljava/lang/String;", strings.Descriptor())
+	assert.Equal(t, "java.lang.String[][]", strings.Canonical())
+	assert.Equal(t, "", strings.Package())
+	assert.Equal(t, "int[]", ClassName("[I").Canonical())
+
+	// Names read from untrusted classes may be malformed
+	for _, name := range []ClassName{"[", "[[", "[L", "[L;", "[Ljava/lang/String", "[II"} {
+		assert.Equal(t, string(name), name.Canonical())
+		assert.Equal(t, "", name.Package())
+	}
+	assert.Equal(t, "[L", ClassName("[L").SimpleName())
+}
+
+func TestBuiltAndReadClassesAgreeOnNames(t *testing.T) {
+	built := NewJavaClass("Hello").SuperClass("java.lang.Object").Implements([]string{"java.io.Serializable"})
+	read, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+
+	assert.Equal(t, read.Name, built.Name)
+	assert.Equal(t, read.SuperName, built.SuperName)
+	assert.Equal(t, read.Interfaces, built.Interfaces)
+	assert.Equal(t, ObjectClassName, NewJavaClass("Hello").SuperName)
+
+	typeName, ok := read.Fields[1].TypeName()
+	assert.True(t, ok)
+	assert.Equal(t, ClassName("java/lang/String"), typeName)
+	_, ok = read.Fields[0].TypeName()
+	assert.False(t, ok)
+}
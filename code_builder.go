@@ -0,0 +1,397 @@
+package gytes
+
+import (
+	"fmt"
+	"math"
+)
+
+// CodeBuilder generates the Instructions of a method with typed helpers, choosing the opcodes matching the types
+// and the shortest encodings of the constants, and with structured if, while and try catch blocks.
+//
+//	b := NewCodeBuilder(&method)
+//	sum := b.NewLocal(JInt)
+//	b.PushInt(0).StoreLocal(JInt, sum)
+//	...
+//	err := b.Build()
+//
+// Build computes the maxs of the method but not its frames, a class of version 50 or later whose code jumps
+// should be written with ComputeFrames, see JavaClass.WriteWith. The helpers don't return errors, the first one
+// is kept and returned by Build.
+type CodeBuilder struct {
+	method *JavaMethod
+	list   *InsnList
+	// The index of the next local variable to allocate
+	nextLocal int
+	err       error
+}
+
+// NewCodeBuilder replaces the code of method by an empty one, the arguments of the method are its first locals.
+func NewCodeBuilder(method *JavaMethod) *CodeBuilder {
+	b := &CodeBuilder{method: method, list: &InsnList{}}
+	method.Body, method.Instructions, method.TryCatchBlocks, method.LocalVariables = nil, b.list, nil, nil
+	b.nextLocal, b.err = argumentsSize(method.Modifiers, method.Descriptor)
+	return b
+}
+
+func (b *CodeBuilder) setErr(err error) *CodeBuilder {
+	if b.err == nil && err != nil {
+		b.err = err
+	}
+	return b
+}
+
+func (b *CodeBuilder) add(n InsnNode) *CodeBuilder {
+	b.list.Add(n)
+	return b
+}
+
+// Build returns the first error of the builder, and sets the MaxStack and MaxLocals of the method.
+func (b *CodeBuilder) Build() error {
+	if b.err != nil {
+		return b.err
+	}
+	// The maxs are computed by writing the code
+	cw := NewClassWriter()
+	cw.Options = ComputeMaxs
+	mw := cw.NewMethod(b.method.Modifiers, b.method.Name, b.method.Descriptor, "", nil)
+	code := mw.Code()
+	b.method.acceptCode(code)
+	mw.VisitEnd()
+	if cw.err != nil {
+		return cw.err
+	}
+	b.method.MaxStack = uint16(code.maxStack)
+	b.method.MaxLocals = uint16(code.maxLocals)
+	if code.maxLocals < b.nextLocal {
+		b.method.MaxLocals = uint16(b.nextLocal)
+	}
+	return nil
+}
+
+// Returns the variant of an int opcode of iload, istore or ireturn for the values of t, e.g. dload for iload.
+func typedOpcode(opcode int, t JType) (int, error) {
+	if t.VMRep == "" {
+		return 0, fmt.Errorf("%w: %s of an empty type", InvalidInstructionError, ByteCodes[opcode].Name)
+	}
+	switch t.VMRep[0] {
+	case 'Z', 'B', 'S', 'C', 'I':
+		return opcode, nil
+	case 'J':
+		return opcode + 1, nil
+	case 'F':
+		return opcode + 2, nil
+	case 'D':
+		return opcode + 3, nil
+	case 'L', '[':
+		return opcode + 4, nil
+	}
+	return 0, fmt.Errorf("%w: %s of a %s value", InvalidInstructionError, ByteCodes[opcode].Name, t.Name)
+}
+
+// Insn adds an instruction without operands, e.g. iadd.
+func (b *CodeBuilder) Insn(opcode int) *CodeBuilder {
+	return b.add(&Insn{Op: opcode})
+}
+
+// PushInt pushes an int, with iconst, bipush, sipush or ldc.
+func (b *CodeBuilder) PushInt(value int32) *CodeBuilder {
+	switch {
+	case value >= -1 && value <= 5:
+		return b.Insn(ICONST_0 + int(value))
+	case value >= math.MinInt8 && value <= math.MaxInt8:
+		return b.add(&IntInsn{Op: BIPUSH, Operand: int(value)})
+	case value >= math.MinInt16 && value <= math.MaxInt16:
+		return b.add(&IntInsn{Op: SIPUSH, Operand: int(value)})
+	}
+	return b.add(&LdcInsn{Value: value})
+}
+
+// PushLong pushes a long, with lconst or ldc2_w.
+func (b *CodeBuilder) PushLong(value int64) *CodeBuilder {
+	if value == 0 || value == 1 {
+		return b.Insn(LCONST_0 + int(value))
+	}
+	return b.add(&LdcInsn{Value: value})
+}
+
+// PushFloat pushes a float, with fconst or ldc.
+func (b *CodeBuilder) PushFloat(value float32) *CodeBuilder {
+	if (value == 0 || value == 1 || value == 2) && !math.Signbit(float64(value)) {
+		return b.Insn(FCONST_0 + int(value))
+	}
+	return b.add(&LdcInsn{Value: value})
+}
+
+// PushDouble pushes a double, with dconst or ldc2_w.
+func (b *CodeBuilder) PushDouble(value float64) *CodeBuilder {
+	if (value == 0 || value == 1) && !math.Signbit(value) {
+		return b.Insn(DCONST_0 + int(value))
+	}
+	return b.add(&LdcInsn{Value: value})
+}
+
+func (b *CodeBuilder) PushString(value string) *CodeBuilder {
+	return b.add(&LdcInsn{Value: value})
+}
+
+func (b *CodeBuilder) PushNull() *CodeBuilder {
+	return b.Insn(ACONST_NULL)
+}
+
+// NewLocal allocates a local variable for the values of t and returns its index.
+func (b *CodeBuilder) NewLocal(t JType) int {
+	index := b.nextLocal
+	b.nextLocal += t.Size()
+	return index
+}
+
+// LoadLocal pushes the local variable at index, holding a value of type t.
+func (b *CodeBuilder) LoadLocal(t JType, index int) *CodeBuilder {
+	opcode, err := typedOpcode(ILOAD, t)
+	if err != nil {
+		return b.setErr(err)
+	}
+	return b.add(&VarInsn{Op: opcode, Var: index})
+}
+
+// StoreLocal pops a value of type t into the local variable at index.
+func (b *CodeBuilder) StoreLocal(t JType, index int) *CodeBuilder {
+	opcode, err := typedOpcode(ISTORE, t)
+	if err != nil {
+		return b.setErr(err)
+	}
+	return b.add(&VarInsn{Op: opcode, Var: index})
+}
+
+// LoadThis pushes the receiver of an instance method.
+func (b *CodeBuilder) LoadThis() *CodeBuilder {
+	if b.method.Modifiers.Has(ACC_STATIC) {
+		return b.setErr(fmt.Errorf("%w: the static method %s%s has no receiver", InvalidInstructionError, b.method.Name, b.method.Descriptor))
+	}
+	return b.add(&VarInsn{Op: ALOAD, Var: 0})
+}
+
+// LoadArg pushes the argument of the method at position i, starting at 0.
+func (b *CodeBuilder) LoadArg(i int) *CodeBuilder {
+	args, _, err := SplitMethodDescriptor(b.method.Descriptor)
+	if err != nil {
+		return b.setErr(err)
+	}
+	if i < 0 || i >= len(args) {
+		return b.setErr(fmt.Errorf("%w: %s%s has no argument %d", InvalidInstructionError, b.method.Name, b.method.Descriptor, i))
+	}
+	index := 0
+	if !b.method.Modifiers.Has(ACC_STATIC) {
+		index++
+	}
+	for _, arg := range args[:i] {
+		index += descriptorSize(arg)
+	}
+	return b.LoadLocal(JType{VMRep: args[i]}, index)
+}
+
+// Increment adds delta to the int local variable at index.
+func (b *CodeBuilder) Increment(index, delta int) *CodeBuilder {
+	return b.add(&IincInsn{Var: index, Increment: delta})
+}
+
+func (b *CodeBuilder) GetStatic(owner ClassName, name string, t JType) *CodeBuilder {
+	return b.add(&FieldInsn{Op: GETSTATIC, Owner: owner, Name: name, Descriptor: t.VMRep})
+}
+
+func (b *CodeBuilder) PutStatic(owner ClassName, name string, t JType) *CodeBuilder {
+	return b.add(&FieldInsn{Op: PUTSTATIC, Owner: owner, Name: name, Descriptor: t.VMRep})
+}
+
+func (b *CodeBuilder) GetField(owner ClassName, name string, t JType) *CodeBuilder {
+	return b.add(&FieldInsn{Op: GETFIELD, Owner: owner, Name: name, Descriptor: t.VMRep})
+}
+
+func (b *CodeBuilder) PutField(owner ClassName, name string, t JType) *CodeBuilder {
+	return b.add(&FieldInsn{Op: PUTFIELD, Owner: owner, Name: name, Descriptor: t.VMRep})
+}
+
+func (b *CodeBuilder) InvokeVirtual(owner ClassName, name, descriptor string) *CodeBuilder {
+	return b.add(&MethodInsn{Op: INVOKEVIRTUAL, Owner: owner, Name: name, Descriptor: descriptor})
+}
+
+func (b *CodeBuilder) InvokeStatic(owner ClassName, name, descriptor string) *CodeBuilder {
+	return b.add(&MethodInsn{Op: INVOKESTATIC, Owner: owner, Name: name, Descriptor: descriptor})
+}
+
+// InvokeSpecial calls a constructor, a private method or a method of the super class.
+func (b *CodeBuilder) InvokeSpecial(owner ClassName, name, descriptor string) *CodeBuilder {
+	return b.add(&MethodInsn{Op: INVOKESPECIAL, Owner: owner, Name: name, Descriptor: descriptor})
+}
+
+func (b *CodeBuilder) InvokeInterface(owner ClassName, name, descriptor string) *CodeBuilder {
+	return b.add(&MethodInsn{Op: INVOKEINTERFACE, Owner: owner, Name: name, Descriptor: descriptor, Interface: true})
+}
+
+// InvokeDynamic calls the call site returned by bootstrap, its entry in the BootstrapMethods attribute is added
+// when the class is written.
+func (b *CodeBuilder) InvokeDynamic(name, descriptor string, bootstrap Handle, arguments ...interface{}) *CodeBuilder {
+	return b.add(&InvokeDynamicInsn{Name: name, Descriptor: descriptor, Bootstrap: bootstrap, Arguments: arguments})
+}
+
+// Lambda replaces the values captured by lambda on the stack by an instance of its functional interface, see LambdaInsn.
+func (b *CodeBuilder) Lambda(lambda Lambda) *CodeBuilder {
+	insn, err := LambdaInsn(lambda)
+	if err != nil {
+		return b.setErr(err)
+	}
+	return b.add(insn)
+}
+
+// Concat replaces the values of types args on the stack by their concatenation following recipe, see ConcatInsn.
+func (b *CodeBuilder) Concat(recipe string, args ...JType) *CodeBuilder {
+	insn, err := ConcatInsn(recipe, args...)
+	if err != nil {
+		return b.setErr(err)
+	}
+	return b.add(insn)
+}
+
+// New creates an uninitialized instance of class, see Construct.
+func (b *CodeBuilder) New(class ClassName) *CodeBuilder {
+	return b.add(&TypeInsn{Op: NEW, Type: class})
+}
+
+func (b *CodeBuilder) Dup() *CodeBuilder {
+	return b.Insn(DUP)
+}
+
+func (b *CodeBuilder) Pop() *CodeBuilder {
+	return b.Insn(POP)
+}
+
+// Construct pushes a new instance of class, created by its constructor of the given descriptor.
+// args pushes the arguments of the constructor.
+func (b *CodeBuilder) Construct(class ClassName, descriptor string, args func(b *CodeBuilder)) *CodeBuilder {
+	b.New(class).Dup()
+	if args != nil {
+		args(b)
+	}
+	return b.InvokeSpecial(class, "<init>", descriptor)
+}
+
+func (b *CodeBuilder) CheckCast(class ClassName) *CodeBuilder {
+	return b.add(&TypeInsn{Op: CHECKCAST, Type: class})
+}
+
+func (b *CodeBuilder) InstanceOf(class ClassName) *CodeBuilder {
+	return b.add(&TypeInsn{Op: INSTANCEOF, Type: class})
+}
+
+// Return returns a value of type t, or nothing if t is JVoid.
+func (b *CodeBuilder) Return(t JType) *CodeBuilder {
+	if t == JVoid {
+		return b.Insn(RETURN)
+	}
+	opcode, err := typedOpcode(IRETURN, t)
+	if err != nil {
+		return b.setErr(err)
+	}
+	return b.Insn(opcode)
+}
+
+// Throw throws the exception on top of the stack.
+func (b *CodeBuilder) Throw() *CodeBuilder {
+	return b.Insn(ATHROW)
+}
+
+// Label marks the position of the next instruction, to jump to it.
+func (b *CodeBuilder) Label(label *Label) *CodeBuilder {
+	return b.add(&LabelNode{Label: label})
+}
+
+func (b *CodeBuilder) Jump(opcode int, target *Label) *CodeBuilder {
+	return b.add(&JumpInsn{Op: opcode, Target: target})
+}
+
+func (b *CodeBuilder) Goto(target *Label) *CodeBuilder {
+	return b.Jump(GOTO, target)
+}
+
+// Tells whether the code added last can complete normally, i.e. if the next instruction can be reached
+// without a jump. A label may be the target of a jump, so the code following it is considered reachable.
+func (b *CodeBuilder) fallsThrough() bool {
+	for n := b.list.Last(); n != nil; n = n.Prev() {
+		switch n.(type) {
+		case *LabelNode:
+			return true
+		case *LineNumberNode, *FrameNode:
+			continue
+		}
+		return fallsThrough(&codeInsn{opcode: n.Opcode()})
+	}
+	return true
+}
+
+func checkCondition(jump int) error {
+	if jump < 0 || jump >= len(ByteCodes) || ByteCodes[jump].Kind != KindJumpInsn ||
+		jump == GOTO || jump == GOTO_W || jump == JSR || jump == JSR_W {
+		return fmt.Errorf("%w: opcode %d is not a conditional jump", InvalidInstructionError, jump)
+	}
+	return nil
+}
+
+// If runs then when the condition of the conditional jump holds, e.g. If(IFEQ, then) runs then if the int
+// on top of the stack is 0. The operands of the condition must be pushed first.
+func (b *CodeBuilder) If(jump int, then func(b *CodeBuilder)) *CodeBuilder {
+	return b.IfElse(jump, then, nil)
+}
+
+// IfElse runs then when the condition of the conditional jump holds, and otherwise else, see If.
+func (b *CodeBuilder) IfElse(jump int, then, otherwise func(b *CodeBuilder)) *CodeBuilder {
+	if err := checkCondition(jump); err != nil {
+		return b.setErr(err)
+	}
+	elseLabel, end := &Label{}, &Label{}
+	b.Jump(oppositeJump(jump), elseLabel)
+	then(b)
+	if otherwise == nil {
+		return b.Label(elseLabel)
+	}
+	if b.fallsThrough() {
+		b.Goto(end)
+	}
+	b.Label(elseLabel)
+	otherwise(b)
+	return b.Label(end)
+}
+
+// While runs body as long as the condition holds. condition pushes the operands of the condition and
+// returns the conditional jump testing them, see If.
+func (b *CodeBuilder) While(condition func(b *CodeBuilder) int, body func(b *CodeBuilder)) *CodeBuilder {
+	start, end := &Label{}, &Label{}
+	b.Label(start)
+	jump := condition(b)
+	if err := checkCondition(jump); err != nil {
+		return b.setErr(err)
+	}
+	b.Jump(oppositeJump(jump), end)
+	body(b)
+	if b.fallsThrough() {
+		b.Goto(start)
+	}
+	return b.Label(end)
+}
+
+// TryCatch runs body, and handler if body throws an exception of class typ, which is on the stack when
+// handler starts. typ can be empty to catch any exception.
+func (b *CodeBuilder) TryCatch(body func(b *CodeBuilder), typ ClassName, handler func(b *CodeBuilder)) *CodeBuilder {
+	start, end, handlerLabel, after := &Label{}, &Label{}, &Label{}, &Label{}
+	b.Label(start)
+	body(b)
+	// The JVM uses the first matching entry, the blocks nested in body come first like with javac
+	b.method.TryCatchBlocks = append(b.method.TryCatchBlocks, TryCatchBlock{start, end, handlerLabel, typ})
+	completes := b.fallsThrough()
+	b.Label(end)
+	if completes {
+		b.Goto(after)
+	}
+	b.Label(handlerLabel)
+	handler(b)
+	return b.Label(after)
+}
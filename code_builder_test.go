@@ -0,0 +1,151 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeBuilderPushesShortestConstants(t *testing.T) {
+	method := JavaMethod{Name: "f", Modifiers: ACC_STATIC, Descriptor: "()V"}
+	b := NewCodeBuilder(&method)
+	for _, v := range []int32{-1, 5, 6, -128, 128, -32768, 32768} {
+		b.PushInt(v)
+	}
+	b.PushLong(1).PushLong(2).PushFloat(2).PushFloat(float32(math.Copysign(0, -1))).PushDouble(1).PushDouble(0.5)
+	assert.Nil(t, b.Build())
+	// The longs and doubles take two slots
+	assert.Equal(t, uint16(17), method.MaxStack)
+	assert.Equal(t, []int{ICONST_M1, ICONST_5, BIPUSH, BIPUSH, SIPUSH, SIPUSH, LDC, LCONST_1, LDC, FCONST_2, LDC, DCONST_1, LDC},
+		opcodes(method.Instructions))
+	assert.Equal(t, &IntInsn{Op: SIPUSH, Operand: -32768}, detachedNode(method.Instructions.Nodes()[5]))
+	assert.Equal(t, &LdcInsn{Value: int32(32768)}, detachedNode(method.Instructions.Nodes()[6]))
+}
+
+func writeBuiltClass(t *testing.T, methods ...JavaMethod) *JavaClass {
+	class := NewJavaClass("gen.Built").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V8).AddMethods(methods)
+	var out bytes.Buffer
+	assert.Nil(t, class.WriteWith(&out, ComputeFrames, nil))
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	return read
+}
+
+func TestCodeBuilderLoops(t *testing.T) {
+	// static int sum(int n) { int sum = 0; for (int i = 0; i < n; i++) { if (i % 2 == 0) sum += i; else sum--; } return sum; }
+	method := JavaMethod{Name: "sum", Modifiers: ACC_PUBLIC | ACC_STATIC, Descriptor: MethodDescriptor(JInt, JInt)}
+	b := NewCodeBuilder(&method)
+	sum, i := b.NewLocal(JInt), b.NewLocal(JInt)
+	assert.Equal(t, []int{1, 2}, []int{sum, i})
+	b.PushInt(0).StoreLocal(JInt, sum)
+	b.PushInt(0).StoreLocal(JInt, i)
+	b.While(func(b *CodeBuilder) int {
+		b.LoadLocal(JInt, i).LoadArg(0)
+		return IF_ICMPLT
+	}, func(b *CodeBuilder) {
+		b.LoadLocal(JInt, i).PushInt(2).Insn(IREM)
+		b.IfElse(IFEQ, func(b *CodeBuilder) {
+			b.LoadLocal(JInt, sum).LoadLocal(JInt, i).Insn(IADD).StoreLocal(JInt, sum)
+		}, func(b *CodeBuilder) {
+			b.Increment(sum, -1)
+		})
+		b.Increment(i, 1)
+	})
+	b.LoadLocal(JInt, sum).Return(JInt)
+	assert.Nil(t, b.Build())
+	assert.Equal(t, uint16(2), method.MaxStack)
+	assert.Equal(t, uint16(3), method.MaxLocals)
+
+	read := writeBuiltClass(t, method)
+	assert.Equal(t, []string{
+		"iconst_0", "istore", "iconst_0", "istore",
+		"iload", "iload", "if_icmpge",
+		"iload", "iconst_2", "irem", "ifne",
+		"iload", "iload", "iadd", "istore", "goto",
+		"iinc",
+		"iinc", "goto",
+		"iload", "ireturn",
+	}, opcodeNames(read.Methods[0]))
+	assert.Equal(t, uint16(2), read.Methods[0].MaxStack)
+	assert.Len(t, read.Methods[0].Instructions.Nodes(), 29)
+}
+
+func TestCodeBuilderTryCatch(t *testing.T) {
+	// String describe(Object o) { try { return o.toString(); } catch (NullPointerException e) { return new String("null"); } }
+	method := JavaMethod{Name: "describe", Modifiers: ACC_PUBLIC, Descriptor: MethodDescriptor(ClassType("java/lang/String"), ClassType(ObjectClassName))}
+	b := NewCodeBuilder(&method)
+	b.TryCatch(func(b *CodeBuilder) {
+		b.LoadArg(0).InvokeVirtual(ObjectClassName, "toString", "()Ljava/lang/String;").Return(ClassType("java/lang/String"))
+	}, "java/lang/NullPointerException", func(b *CodeBuilder) {
+		b.Pop().Construct("java/lang/String", "(Ljava/lang/String;)V", func(b *CodeBuilder) {
+			b.PushString("null")
+		}).Return(ClassType("java/lang/String"))
+	})
+	assert.Nil(t, b.Build())
+
+	read := writeBuiltClass(t, method)
+	// The body returns, so no goto follows it
+	assert.Equal(t, []string{
+		"aload", "invokevirtual", "areturn",
+		"pop", "new", "dup", "ldc", "invokespecial", "areturn",
+	}, opcodeNames(read.Methods[0]))
+	assert.Len(t, read.Methods[0].TryCatchBlocks, 1)
+	assert.Equal(t, ClassName("java/lang/NullPointerException"), read.Methods[0].TryCatchBlocks[0].Type)
+	assert.Equal(t, 5, read.Methods[0].TryCatchBlocks[0].Handler.Offset)
+}
+
+func TestCodeBuilderNestedTryCatch(t *testing.T) {
+	// static int f() { try { try { return 1; } catch (Throwable e) { return 2; } } catch (Throwable e) { return 3; } }
+	method := JavaMethod{Name: "f", Modifiers: ACC_STATIC, Descriptor: "()I"}
+	b := NewCodeBuilder(&method)
+	b.TryCatch(func(b *CodeBuilder) {
+		b.TryCatch(func(b *CodeBuilder) {
+			b.PushInt(1).Return(JInt)
+		}, "java/lang/Throwable", func(b *CodeBuilder) {
+			b.Pop().PushInt(2).Return(JInt)
+		})
+	}, "java/lang/Throwable", func(b *CodeBuilder) {
+		b.Pop().PushInt(3).Return(JInt)
+	})
+	assert.Nil(t, b.Build())
+
+	read := writeBuiltClass(t, method)
+	var table [][3]int
+	for _, block := range read.Methods[0].TryCatchBlocks {
+		table = append(table, [3]int{block.Start.Offset, block.End.Offset, block.Handler.Offset})
+	}
+	// The inner try comes first
+	assert.Equal(t, [][3]int{{0, 2, 2}, {0, 5, 8}}, table)
+}
+
+func TestCodeBuilderMethodWrittenWithoutOptions(t *testing.T) {
+	class := NewJavaClass("gen.Plain").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V8)
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "max", JLong, JLong, JLong).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).LoadArg(1).InvokeStatic("java/lang/Math", "max", "(JJ)J").Return(JLong)
+	}).Add())
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(4), read.Methods[0].MaxStack)
+	assert.Equal(t, uint16(4), read.Methods[0].MaxLocals)
+}
+
+func TestCodeBuilderErrors(t *testing.T) {
+	method := JavaMethod{Name: "f", Modifiers: ACC_STATIC, Descriptor: "(J)V"}
+	assert.True(t, errors.Is(NewCodeBuilder(&method).LoadLocal(JVoid, 0).Build(), InvalidInstructionError))
+	assert.True(t, errors.Is(NewCodeBuilder(&method).LoadThis().Build(), InvalidInstructionError))
+	assert.True(t, errors.Is(NewCodeBuilder(&method).LoadArg(1).Build(), InvalidInstructionError))
+	assert.True(t, errors.Is(NewCodeBuilder(&method).If(GOTO, func(b *CodeBuilder) {}).Build(), InvalidInstructionError))
+
+	b := NewCodeBuilder(&method)
+	assert.Equal(t, 2, b.NewLocal(JDouble))
+	assert.Equal(t, 4, b.NewLocal(ClassType("java/lang/String")))
+	assert.Nil(t, b.LoadArg(0).Insn(POP2).Return(JVoid).Build())
+	assert.Equal(t, uint16(5), method.MaxLocals)
+	assert.Equal(t, &VarInsn{Op: LLOAD, Var: 0}, detachedNode(method.Instructions.First()))
+}
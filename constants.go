@@ -2,8 +2,9 @@ package gytes
 
 const MAGIC = 0xCAFEBABE
 
+// The range of class file major versions supported by gytes, see version.go
 const MIN_VERSION = 45
-const MAJ_VERSION = 58
+const MAJ_VERSION = 71
 
 /*
 Source: https://docs.oracle.com/javase/specs/jvms/se8/html/jvms-4.html#jvms-4.4
@@ -23,7 +24,10 @@ CONSTANT_NameAndType	       | 12 |
 CONSTANT_Utf8	               |  1 |
 CONSTANT_MethodHandle	       | 15 |
 CONSTANT_MethodType	         | 16 |
+CONSTANT_Dynamic	           | 17 |
 CONSTANT_InvokeDynamic	     | 18 |
+CONSTANT_Module	             | 19 |
+CONSTANT_Package	           | 20 |
 -----------------------------------
 */
 
@@ -41,7 +45,10 @@ const (
 	ConstUtf8               = 1
 	ConstMethodHandle       = 15
 	ConstMethodType         = 16
+	ConstDynamic            = 17
 	ConstInvokeDynamic      = 18
+	ConstModule             = 19
+	ConstPackage            = 20
 )
 
 var ConstSizeMap = map[int]int{
@@ -57,5 +64,8 @@ var ConstSizeMap = map[int]int{
 	ConstNameAndType:        5,
 	ConstMethodHandle:       4,
 	ConstMethodType:         3,
+	ConstDynamic:            5,
 	ConstInvokeDynamic:      5,
+	ConstModule:             3,
+	ConstPackage:            3,
 }
@@ -0,0 +1,161 @@
+package gytes
+
+import "fmt"
+
+// CoreHierarchy is a ClassHierarchy of the most used classes and interfaces of java.lang, java.io and java.util,
+// enough to merge the types of most code without a classpath. The versions of its headers are not known.
+var CoreHierarchy ClassHierarchy = coreHierarchy{}
+
+type coreHierarchy struct{}
+
+func (coreHierarchy) Header(name ClassName) (*ClassHeader, error) {
+	class, ok := coreClasses[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a core class", UnknownClassError, name)
+	}
+	header := &ClassHeader{Access: ACC_PUBLIC, Name: name, SuperName: class.super, Interfaces: class.interfaces}
+	if class.iface {
+		header.Access |= ACC_INTERFACE | ACC_ABSTRACT
+		header.SuperName = ObjectClassName
+	}
+	return header, nil
+}
+
+type coreClass struct {
+	super      ClassName
+	iface      bool
+	interfaces []ClassName
+}
+
+const (
+	serializable = ClassName("java/io/Serializable")
+	comparable   = ClassName("java/lang/Comparable")
+	collection   = ClassName("java/util/Collection")
+)
+
+func coreInterface(interfaces ...ClassName) coreClass {
+	return coreClass{iface: true, interfaces: interfaces}
+}
+
+func coreSubclass(super ClassName, interfaces ...ClassName) coreClass {
+	return coreClass{super: super, interfaces: interfaces}
+}
+
+var coreClasses = map[ClassName]coreClass{
+	ObjectClassName: {},
+
+	serializable:                   coreInterface(),
+	comparable:                     coreInterface(),
+	"java/lang/CharSequence":       coreInterface(),
+	"java/lang/Cloneable":          coreInterface(),
+	"java/lang/Runnable":           coreInterface(),
+	"java/lang/AutoCloseable":      coreInterface(),
+	"java/lang/Iterable":           coreInterface(),
+	"java/lang/Appendable":         coreInterface(),
+	"java/io/Closeable":            coreInterface("java/lang/AutoCloseable"),
+	"java/lang/reflect/Type":       coreInterface(),
+	"java/util/Iterator":           coreInterface(),
+	"java/util/RandomAccess":       coreInterface(),
+	"java/util/Comparator":         coreInterface(),
+	collection:                     coreInterface("java/lang/Iterable"),
+	"java/util/List":               coreInterface(collection),
+	"java/util/Set":                coreInterface(collection),
+	"java/util/SortedSet":          coreInterface("java/util/Set"),
+	"java/util/NavigableSet":       coreInterface("java/util/SortedSet"),
+	"java/util/Queue":              coreInterface(collection),
+	"java/util/Deque":              coreInterface("java/util/Queue"),
+	"java/util/Map":                coreInterface(),
+	"java/util/Map$Entry":          coreInterface(),
+	"java/util/SortedMap":          coreInterface("java/util/Map"),
+	"java/util/NavigableMap":       coreInterface("java/util/SortedMap"),
+	"java/util/function/Function":  coreInterface(),
+	"java/util/function/Supplier":  coreInterface(),
+	"java/util/function/Consumer":  coreInterface(),
+	"java/util/function/Predicate": coreInterface(),
+
+	"java/lang/String":                     coreSubclass(ObjectClassName, serializable, comparable, "java/lang/CharSequence"),
+	"java/lang/AbstractStringBuilder":      coreSubclass(ObjectClassName, "java/lang/Appendable", "java/lang/CharSequence"),
+	"java/lang/StringBuilder":              coreSubclass("java/lang/AbstractStringBuilder", serializable, "java/lang/CharSequence"),
+	"java/lang/StringBuffer":               coreSubclass("java/lang/AbstractStringBuilder", serializable, "java/lang/CharSequence"),
+	"java/lang/Class":                      coreSubclass(ObjectClassName, serializable, "java/lang/reflect/Type"),
+	"java/lang/Enum":                       coreSubclass(ObjectClassName, comparable, serializable),
+	"java/lang/Record":                     coreSubclass(ObjectClassName),
+	"java/lang/Math":                       coreSubclass(ObjectClassName),
+	"java/lang/System":                     coreSubclass(ObjectClassName),
+	"java/lang/Thread":                     coreSubclass(ObjectClassName, "java/lang/Runnable"),
+	"java/lang/Number":                     coreSubclass(ObjectClassName, serializable),
+	"java/lang/Byte":                       coreSubclass("java/lang/Number", comparable),
+	"java/lang/Short":                      coreSubclass("java/lang/Number", comparable),
+	"java/lang/Integer":                    coreSubclass("java/lang/Number", comparable),
+	"java/lang/Long":                       coreSubclass("java/lang/Number", comparable),
+	"java/lang/Float":                      coreSubclass("java/lang/Number", comparable),
+	"java/lang/Double":                     coreSubclass("java/lang/Number", comparable),
+	"java/lang/Boolean":                    coreSubclass(ObjectClassName, serializable, comparable),
+	"java/lang/Character":                  coreSubclass(ObjectClassName, serializable, comparable),
+	"java/lang/Void":                       coreSubclass(ObjectClassName),
+	"java/lang/invoke/MethodHandle":        coreSubclass(ObjectClassName),
+	"java/lang/invoke/MethodType":          coreSubclass(ObjectClassName, serializable),
+	"java/lang/invoke/CallSite":            coreSubclass(ObjectClassName),
+	"java/lang/invoke/MethodHandles":       coreSubclass(ObjectClassName),
+	"java/lang/invoke/LambdaMetafactory":   coreSubclass(ObjectClassName),
+	"java/lang/invoke/StringConcatFactory": coreSubclass(ObjectClassName),
+
+	"java/lang/Throwable":                       coreSubclass(ObjectClassName, serializable),
+	"java/lang/Exception":                       coreSubclass("java/lang/Throwable"),
+	"java/lang/Error":                           coreSubclass("java/lang/Throwable"),
+	"java/lang/RuntimeException":                coreSubclass("java/lang/Exception"),
+	"java/lang/ReflectiveOperationException":    coreSubclass("java/lang/Exception"),
+	"java/lang/ClassNotFoundException":          coreSubclass("java/lang/ReflectiveOperationException"),
+	"java/lang/InterruptedException":            coreSubclass("java/lang/Exception"),
+	"java/lang/CloneNotSupportedException":      coreSubclass("java/lang/Exception"),
+	"java/io/IOException":                       coreSubclass("java/lang/Exception"),
+	"java/io/UncheckedIOException":              coreSubclass("java/lang/RuntimeException"),
+	"java/lang/ArithmeticException":             coreSubclass("java/lang/RuntimeException"),
+	"java/lang/ArrayStoreException":             coreSubclass("java/lang/RuntimeException"),
+	"java/lang/ClassCastException":              coreSubclass("java/lang/RuntimeException"),
+	"java/lang/IllegalArgumentException":        coreSubclass("java/lang/RuntimeException"),
+	"java/lang/NumberFormatException":           coreSubclass("java/lang/IllegalArgumentException"),
+	"java/lang/IllegalStateException":           coreSubclass("java/lang/RuntimeException"),
+	"java/lang/IllegalMonitorStateException":    coreSubclass("java/lang/RuntimeException"),
+	"java/lang/IndexOutOfBoundsException":       coreSubclass("java/lang/RuntimeException"),
+	"java/lang/ArrayIndexOutOfBoundsException":  coreSubclass("java/lang/IndexOutOfBoundsException"),
+	"java/lang/StringIndexOutOfBoundsException": coreSubclass("java/lang/IndexOutOfBoundsException"),
+	"java/lang/NegativeArraySizeException":      coreSubclass("java/lang/RuntimeException"),
+	"java/lang/NullPointerException":            coreSubclass("java/lang/RuntimeException"),
+	"java/lang/SecurityException":               coreSubclass("java/lang/RuntimeException"),
+	"java/lang/UnsupportedOperationException":   coreSubclass("java/lang/RuntimeException"),
+	"java/util/ConcurrentModificationException": coreSubclass("java/lang/RuntimeException"),
+	"java/util/NoSuchElementException":          coreSubclass("java/lang/RuntimeException"),
+	"java/lang/AssertionError":                  coreSubclass("java/lang/Error"),
+	"java/lang/LinkageError":                    coreSubclass("java/lang/Error"),
+	"java/lang/NoClassDefFoundError":            coreSubclass("java/lang/LinkageError"),
+	"java/lang/VirtualMachineError":             coreSubclass("java/lang/Error"),
+	"java/lang/OutOfMemoryError":                coreSubclass("java/lang/VirtualMachineError"),
+	"java/lang/StackOverflowError":              coreSubclass("java/lang/VirtualMachineError"),
+
+	"java/io/InputStream":        coreSubclass(ObjectClassName, "java/io/Closeable"),
+	"java/io/OutputStream":       coreSubclass(ObjectClassName, "java/io/Closeable"),
+	"java/io/FilterOutputStream": coreSubclass("java/io/OutputStream"),
+	"java/io/PrintStream":        coreSubclass("java/io/FilterOutputStream", "java/lang/Appendable", "java/io/Closeable"),
+
+	"java/util/Objects":                coreSubclass(ObjectClassName),
+	"java/util/Arrays":                 coreSubclass(ObjectClassName),
+	"java/util/Collections":            coreSubclass(ObjectClassName),
+	"java/util/Optional":               coreSubclass(ObjectClassName),
+	"java/util/AbstractCollection":     coreSubclass(ObjectClassName, collection),
+	"java/util/AbstractList":           coreSubclass("java/util/AbstractCollection", "java/util/List"),
+	"java/util/AbstractSequentialList": coreSubclass("java/util/AbstractList"),
+	"java/util/ArrayList":              coreSubclass("java/util/AbstractList", "java/util/List", "java/util/RandomAccess", "java/lang/Cloneable", serializable),
+	"java/util/LinkedList":             coreSubclass("java/util/AbstractSequentialList", "java/util/List", "java/util/Deque", "java/lang/Cloneable", serializable),
+	"java/util/AbstractSet":            coreSubclass("java/util/AbstractCollection", "java/util/Set"),
+	"java/util/HashSet":                coreSubclass("java/util/AbstractSet", "java/util/Set", "java/lang/Cloneable", serializable),
+	"java/util/LinkedHashSet":          coreSubclass("java/util/HashSet", "java/util/Set", "java/lang/Cloneable", serializable),
+	"java/util/TreeSet":                coreSubclass("java/util/AbstractSet", "java/util/NavigableSet", "java/lang/Cloneable", serializable),
+	"java/util/AbstractQueue":          coreSubclass("java/util/AbstractCollection", "java/util/Queue"),
+	"java/util/PriorityQueue":          coreSubclass("java/util/AbstractQueue", serializable),
+	"java/util/ArrayDeque":             coreSubclass("java/util/AbstractCollection", "java/util/Deque", "java/lang/Cloneable", serializable),
+	"java/util/AbstractMap":            coreSubclass(ObjectClassName, "java/util/Map"),
+	"java/util/HashMap":                coreSubclass("java/util/AbstractMap", "java/util/Map", "java/lang/Cloneable", serializable),
+	"java/util/LinkedHashMap":          coreSubclass("java/util/HashMap", "java/util/Map"),
+	"java/util/TreeMap":                coreSubclass("java/util/AbstractMap", "java/util/NavigableMap", "java/lang/Cloneable", serializable),
+}
@@ -0,0 +1,132 @@
+package gytes
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// The kinds of issues reported by a ClassReader while reading a class
+type DiagnosticKind int
+
+const (
+	// An attribute that is not defined by the JVM specification
+	UnknownAttribute DiagnosticKind = iota
+	// Access flags that are not valid for the class or member that holds them
+	SuspiciousFlags
+	// Malformed content that doesn't prevent reading the class
+	FormatIssue
+	// Malformed content that is skipped by a lenient ClassReader
+	SkippedContent
+)
+
+func (k DiagnosticKind) String() string {
+	return [...]string{"unknown attribute", "suspicious flags", "format issue", "skipped content"}[k]
+}
+
+// A Diagnostic is an issue found while reading a class that is not worth failing for.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	// The class being read, empty if the issue is found before its name is known
+	Class ClassName
+	// The offset in the class file of the structure the issue is about
+	Offset  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Class == "" {
+		return fmt.Sprintf("%v at offset %d: %s", d.Kind, d.Offset, d.Message)
+	}
+	return fmt.Sprintf("%s: %v at offset %d: %s", d.Class, d.Kind, d.Offset, d.Message)
+}
+
+// A DiagnosticHandler receives the diagnostics of a ClassReader, it must be safe for concurrent use
+// if the reader is used by multiple goroutines.
+type DiagnosticHandler interface {
+	Handle(d Diagnostic)
+}
+
+// DiagnosticHandlerFunc adapts a function into a DiagnosticHandler.
+type DiagnosticHandlerFunc func(d Diagnostic)
+
+func (f DiagnosticHandlerFunc) Handle(d Diagnostic) {
+	f(d)
+}
+
+// LogDiagnostics returns a handler printing the diagnostics to logger, or to the standard logger if it's nil.
+func LogDiagnostics(logger *log.Logger) DiagnosticHandler {
+	return DiagnosticHandlerFunc(func(d Diagnostic) {
+		if logger == nil {
+			log.Println(d)
+		} else {
+			logger.Println(d)
+		}
+	})
+}
+
+// Diagnostics is a DiagnosticHandler collecting all the diagnostics it receives, it is safe for concurrent use.
+type Diagnostics struct {
+	mu   sync.Mutex
+	list []Diagnostic
+}
+
+func (ds *Diagnostics) Handle(d Diagnostic) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.list = append(ds.list, d)
+}
+
+// All returns the collected diagnostics, in the order they were reported.
+func (ds *Diagnostics) All() []Diagnostic {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return append([]Diagnostic(nil), ds.list...)
+}
+
+// Count returns the number of collected diagnostics of the given kind.
+func (ds *Diagnostics) Count(kind DiagnosticKind) int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	count := 0
+	for _, d := range ds.list {
+		if d.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// The attributes defined by the JVM specification, see JVMS 4.7
+var standardAttributes = map[string]bool{
+	"ConstantValue":                        true,
+	"Code":                                 true,
+	"StackMapTable":                        true,
+	"Exceptions":                           true,
+	"InnerClasses":                         true,
+	"EnclosingMethod":                      true,
+	"Synthetic":                            true,
+	"Signature":                            true,
+	"SourceFile":                           true,
+	"SourceDebugExtension":                 true,
+	"LineNumberTable":                      true,
+	"LocalVariableTable":                   true,
+	"LocalVariableTypeTable":               true,
+	"Deprecated":                           true,
+	"RuntimeVisibleAnnotations":            true,
+	"RuntimeInvisibleAnnotations":          true,
+	"RuntimeVisibleParameterAnnotations":   true,
+	"RuntimeInvisibleParameterAnnotations": true,
+	"RuntimeVisibleTypeAnnotations":        true,
+	"RuntimeInvisibleTypeAnnotations":      true,
+	"AnnotationDefault":                    true,
+	"BootstrapMethods":                     true,
+	"MethodParameters":                     true,
+	"Module":                               true,
+	"ModulePackages":                       true,
+	"ModuleMainClass":                      true,
+	"NestHost":                             true,
+	"NestMembers":                          true,
+	"Record":                               true,
+	"PermittedSubclasses":                  true,
+}
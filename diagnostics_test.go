@@ -0,0 +1,63 @@
+package gytes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoDiagnosticsForValidClasses(t *testing.T) {
+	diagnostics := &Diagnostics{}
+	reader := &ClassReader{Diagnostics: diagnostics}
+	for _, src := range []string{"testdata/compiled/Hello.class", "testdata/compiled/HelloJavaException.class"} {
+		_, err := reader.ReadClass(readClassFile(src))
+		assert.Nil(t, err)
+	}
+	assert.Empty(t, diagnostics.All())
+}
+
+func TestUnknownAttributeDiagnostic(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	b = bytes.Replace(b, []byte("SourceFile"), []byte("SourceFilX"), 1)
+	diagnostics := &Diagnostics{}
+	jclass, err := (&ClassReader{Diagnostics: diagnostics}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "SourceFilX", jclass.Attributes[0].Name)
+	assert.Equal(t, 1, diagnostics.Count(UnknownAttribute))
+	d := diagnostics.All()[0]
+	assert.Equal(t, ClassName("Hello"), d.Class)
+	assert.Equal(t, "SourceFilX", d.Message)
+	// SourceFile attributes hold a single constant pool index
+	assert.Equal(t, uint32(2), readUnsignedInt(b, d.Offset+2))
+}
+
+func TestSuspiciousFlagsDiagnostic(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	parser := &classParser{}
+	assert.Nil(t, parser.fillPoolItems(b, readUnsignedShort(b, 8)))
+	binary.BigEndian.PutUint16(b[parser.headStart:], ACC_INTERFACE|ACC_FINAL)
+
+	var messages []string
+	reader := &ClassReader{Diagnostics: DiagnosticHandlerFunc(func(d Diagnostic) {
+		assert.Equal(t, SuspiciousFlags, d.Kind)
+		messages = append(messages, d.String())
+	})}
+	_, err := reader.ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, messages)
+	assert.True(t, strings.HasPrefix(messages[0], "Hello: suspicious flags at offset"))
+}
+
+func TestUnsupportedVersionDiagnostic(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	b[7] = 99
+	var out bytes.Buffer
+	reader := &ClassReader{AllowUnsupportedVersions: true, Diagnostics: LogDiagnostics(log.New(&out, "", 0))}
+	_, err := reader.ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "format issue at offset 4: "))
+}
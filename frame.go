@@ -0,0 +1,137 @@
+package gytes
+
+import "fmt"
+
+// The kinds of stack map frames, see JVMS 4.7.4
+type FrameType int
+
+const (
+	// An expanded frame, holding all the locals and stack values
+	FrameNew FrameType = iota
+	FrameFull
+	// The locals of the previous frame with additional locals and an empty stack
+	FrameAppend
+	// The locals of the previous frame without the last Chopped locals and an empty stack
+	FrameChop
+	// The locals of the previous frame and an empty stack
+	FrameSame
+	// The locals of the previous frame and a single stack value
+	FrameSame1
+)
+
+// The verification types of the values of a frame, objects are represented by their ClassName
+// and uninitialized objects by the *Label of the new instruction that created them.
+type VerificationType int
+
+const (
+	TopType VerificationType = iota
+	IntegerType
+	FloatType
+	DoubleType
+	LongType
+	NullType
+	UninitializedThisType
+)
+
+func (vt VerificationType) String() string {
+	return [...]string{"top", "int", "float", "double", "long", "null", "uninitialized this"}[vt]
+}
+
+// A Frame is an entry of the StackMapTable, it gives the types of the locals and of the operand stack
+// at the start of the instruction it precedes.
+// long and double values are represented by a single element, even though they take two slots.
+type Frame struct {
+	Type    FrameType
+	Locals  []interface{}
+	Stack   []interface{}
+	Chopped int
+}
+
+func (f Frame) String() string {
+	if f.Type == FrameChop {
+		return fmt.Sprintf("chop %d", f.Chopped)
+	}
+	return fmt.Sprintf("%v %v", f.Locals, f.Stack)
+}
+
+// Returns the verification type of a value of the given field descriptor.
+func descriptorFrameType(descriptor string) interface{} {
+	switch descriptor[0] {
+	case 'Z', 'B', 'C', 'S', 'I':
+		return IntegerType
+	case 'F':
+		return FloatType
+	case 'J':
+		return LongType
+	case 'D':
+		return DoubleType
+	case '[':
+		return ClassName(descriptor)
+	}
+	return ClassName(descriptor[1 : len(descriptor)-1])
+}
+
+// Returns the locals of the implicit frame at the start of a method.
+func initialFrameLocals(owner ClassName, access MethodAccess, name, descriptor string) ([]interface{}, error) {
+	args, _, err := SplitMethodDescriptor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	locals := make([]interface{}, 0, len(args)+1)
+	if !access.Has(ACC_STATIC) {
+		if name == "<init>" && owner != ObjectClassName {
+			locals = append(locals, UninitializedThisType)
+		} else {
+			locals = append(locals, owner)
+		}
+	}
+	for _, arg := range args {
+		locals = append(locals, descriptorFrameType(arg))
+	}
+	return locals, nil
+}
+
+// Returns the locals of frame, given the locals of the previous frame.
+func frameLocals(previous []interface{}, frame Frame) ([]interface{}, error) {
+	switch frame.Type {
+	case FrameChop:
+		if frame.Chopped > len(previous) {
+			return nil, fmt.Errorf("frame chops %d locals out of %d", frame.Chopped, len(previous))
+		}
+		return previous[:len(previous)-frame.Chopped], nil
+	case FrameAppend:
+		return append(previous[:len(previous):len(previous)], frame.Locals...), nil
+	case FrameFull, FrameNew:
+		return frame.Locals, nil
+	}
+	return previous, nil
+}
+
+// Returns the most compact form of an expanded frame, given the locals of the previous frame.
+func compressFrame(previous []interface{}, frame Frame) Frame {
+	if frame.Type != FrameNew {
+		return frame
+	}
+	locals := frame.Locals
+	same := func(n int) bool {
+		for i := 0; i < n; i++ {
+			if locals[i] != previous[i] {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case len(locals) == len(previous) && same(len(locals)) && len(frame.Stack) <= 1:
+		if len(frame.Stack) == 0 {
+			return Frame{Type: FrameSame}
+		}
+		return Frame{Type: FrameSame1, Stack: frame.Stack}
+	case len(frame.Stack) > 0:
+	case len(locals) < len(previous) && len(locals) >= len(previous)-3 && same(len(locals)):
+		return Frame{Type: FrameChop, Chopped: len(previous) - len(locals)}
+	case len(locals) > len(previous) && len(locals) <= len(previous)+3 && same(len(previous)):
+		return Frame{Type: FrameAppend, Locals: locals[len(previous):]}
+	}
+	return Frame{Type: FrameFull, Locals: locals, Stack: frame.Stack}
+}
@@ -0,0 +1,359 @@
+package gytes
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGenerated = flag.Bool("update-generated", false, "write the classes of testdata/generated again")
+
+const generatedDir = "testdata/generated"
+
+var (
+	genString = ClassType("java/lang/String")
+	genObject = ClassType(ObjectClassName)
+)
+
+// Returns the classes of testdata/generated, which use the features of many class file versions. They are written
+// by gytes to detect the changes of its output, the classes compiled by javac are in testdata/javac.
+func generatedClasses(t *testing.T) []*JavaClass {
+	return []*JavaClass{
+		legacyClass(t), framesClass(t), lambdasClass(t), outerClass(t), innerClass(t),
+		pointClass(t), shapeClass(t), constantsClass(t),
+	}
+}
+
+func addConstructor(t *testing.T, class *JavaClass) {
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "<init>", JVoid).Code(func(b *CodeBuilder) {
+		b.LoadThis().InvokeSpecial(class.SuperName, "<init>", "()V").Return(JVoid)
+	}).Add())
+}
+
+// A class without frames, with a source map
+func legacyClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Legacy").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V1_4)
+	class.SourceName = "Legacy.java"
+	class.SourceDebug = "SMAP\nLegacy.java\nJava\n*S Java\n*F\n1 Legacy.java\n*L\n1#1,10:1\n*E\n"
+	assert.Nil(t, class.NewField(ACC_PUBLIC|ACC_STATIC|ACC_FINAL, "LIMIT", JInt).Value(int32(10)).Add())
+	assert.Nil(t, class.NewField(ACC_PRIVATE, "total", JLong).Add())
+	addConstructor(t, class)
+	// static int count(int n) throws IOException { int sum = 0; for (int i = 0; i < n; i++) sum += i; return sum; }
+	assert.Nil(t, class.NewMethod(ACC_STATIC, "count", JInt, JInt).Throws("java/io/IOException").Code(func(b *CodeBuilder) {
+		sum, i := b.NewLocal(JInt), b.NewLocal(JInt)
+		b.PushInt(0).StoreLocal(JInt, sum).PushInt(0).StoreLocal(JInt, i)
+		b.While(func(b *CodeBuilder) int {
+			b.LoadLocal(JInt, i).LoadArg(0)
+			return IF_ICMPLT
+		}, func(b *CodeBuilder) {
+			b.LoadLocal(JInt, sum).LoadLocal(JInt, i).Insn(IADD).StoreLocal(JInt, sum).Increment(i, 1)
+		})
+		b.LoadLocal(JInt, sum).Return(JInt)
+	}).Add())
+	return class
+}
+
+// A class whose code has switches, exception handlers, lines and locals, and so all kinds of frames
+func framesClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Frames").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V7)
+	class.SourceName = "Frames.java"
+	addConstructor(t, class)
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "describe", genString, JInt, genObject).Code(func(b *CodeBuilder) {
+		start, end, one, two, other, negative, positive, rest := &Label{}, &Label{}, &Label{}, &Label{}, &Label{}, &Label{}, &Label{}, &Label{}
+		b.Label(start).add(&LineNumberNode{Line: 3, Start: start})
+		b.LoadArg(0).add(&TableSwitchInsn{Min: 1, Max: 2, Default: other, Labels: []*Label{one, two}})
+		b.Label(one).PushString("one").Return(genString)
+		b.Label(two).PushString("two").Return(genString)
+		b.Label(other)
+		b.LoadArg(1).InstanceOf("java/lang/String").If(IFEQ, func(b *CodeBuilder) {
+			b.LoadArg(1).CheckCast("java/lang/String").Return(genString)
+		})
+		b.LoadArg(0).add(&LookupSwitchInsn{Default: rest, Keys: []int{-1000, 1000}, Labels: []*Label{negative, positive}})
+		b.Label(negative).PushString("negative").Return(genString)
+		b.Label(positive).PushString("positive").Return(genString)
+		b.Label(rest)
+		b.TryCatch(func(b *CodeBuilder) {
+			b.LoadArg(1).InvokeVirtual(ObjectClassName, "toString", "()Ljava/lang/String;").Return(genString)
+		}, "java/lang/RuntimeException", func(b *CodeBuilder) {
+			b.Pop().PushString("?").Return(genString)
+		})
+		b.Label(end)
+		b.method.LocalVariables = []LocalVariable{
+			{Name: "n", Descriptor: "I", Start: start, End: end, Index: 0},
+			{Name: "o", Descriptor: "Ljava/lang/Object;", Start: start, End: end, Index: 1},
+		}
+	}).Add())
+	// static double average(long[] values) { double sum = 0; for (int i = 0; i < values.length; i++) sum += values[i]; ... }
+	assert.Nil(t, class.NewMethod(ACC_STATIC, "average", JDouble, ClassType("[J")).Code(func(b *CodeBuilder) {
+		sum, i := b.NewLocal(JDouble), b.NewLocal(JInt)
+		b.PushDouble(0).StoreLocal(JDouble, sum).PushInt(0).StoreLocal(JInt, i)
+		b.While(func(b *CodeBuilder) int {
+			b.LoadLocal(JInt, i).LoadArg(0).Insn(ARRAYLENGTH)
+			return IF_ICMPLT
+		}, func(b *CodeBuilder) {
+			b.LoadLocal(JDouble, sum).LoadArg(0).LoadLocal(JInt, i).Insn(LALOAD).Insn(L2D).Insn(DADD).StoreLocal(JDouble, sum)
+			b.Increment(i, 1)
+		})
+		b.LoadLocal(JDouble, sum).LoadArg(0).Insn(ARRAYLENGTH).Insn(I2D).Insn(DDIV).Return(JDouble)
+	}).Add())
+	return class
+}
+
+// A class with lambdas, method references, annotations and generic signatures
+func lambdasClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Lambdas").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V8)
+	class.Signature = "<T:Ljava/lang/Object;>Ljava/lang/Object;"
+	class.Annotations = []Annotation{
+		{Descriptor: "Lgen/writer/Marker;", Visible: true, Values: []AnnotationElement{
+			{Name: "name", Value: "lambdas"},
+			{Name: "level", Value: int32(3)},
+			{Name: "policy", Value: EnumConstant{Descriptor: "Ljava/lang/annotation/RetentionPolicy;", Name: "RUNTIME"}},
+			{Name: "types", Value: []interface{}{ClassLiteral("Ljava/lang/String;"), ClassLiteral("I")}},
+			{Name: "nested", Value: &Annotation{Descriptor: "Lgen/writer/Nested;"}},
+		}},
+		{Descriptor: "Lgen/writer/Hidden;"},
+	}
+	assert.Nil(t, class.NewField(ACC_PRIVATE, "items", ClassType("java/util/List")).
+		Signature("Ljava/util/List<TT;>;").Annotate(Annotation{Descriptor: "Lgen/writer/Hidden;"}).Add())
+	addConstructor(t, class)
+
+	concat := class.NewLambdaMethod(genString, genString, genString).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).LoadArg(1).InvokeVirtual("java/lang/String", "concat", "(Ljava/lang/String;)Ljava/lang/String;").Return(genString)
+	})
+	assert.Nil(t, concat.Add())
+	function := ClassType("java/util/function/Function")
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "prefixer", function, genString).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).Lambda(Lambda{
+			Interface:      "java/util/function/Function",
+			Method:         "apply",
+			Descriptor:     "(Ljava/lang/Object;)Ljava/lang/Object;",
+			Implementation: concat.Handle(),
+			Captured:       []JType{genString},
+		}).Return(function)
+	}).Add())
+
+	answer := class.NewLambdaMethod(JInt).Code(func(b *CodeBuilder) { b.PushInt(42).Return(JInt) })
+	assert.Nil(t, answer.Add())
+	supplier := ClassType("java/util/function/Supplier")
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "answer", supplier).Code(func(b *CodeBuilder) {
+		b.Lambda(Lambda{
+			Interface:      "java/util/function/Supplier",
+			Method:         "get",
+			Descriptor:     "()Ljava/lang/Object;",
+			Implementation: answer.Handle(),
+		}).Return(supplier)
+	}).Add())
+
+	length := ClassType("java/util/function/ToIntFunction")
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "length", length).Code(func(b *CodeBuilder) {
+		b.Lambda(Lambda{
+			Interface:      "java/util/function/ToIntFunction",
+			Method:         "applyAsInt",
+			Descriptor:     "(Ljava/lang/Object;)I",
+			Implementation: Handle{Kind: REF_invokeVirtual, Owner: "java/lang/String", Name: "length", Descriptor: "()I"},
+		}).Return(length)
+	}).Add())
+
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "identity", genObject, genObject).
+		Signature("<U:Ljava/lang/Object;>(TU;)TU;").
+		Annotate(Annotation{Descriptor: "Lgen/writer/Marker;", Visible: true, Values: []AnnotationElement{{Name: "flag", Value: true}}}).
+		Deprecated().
+		Code(func(b *CodeBuilder) { b.LoadArg(0).Return(genObject) }).Add())
+	return class
+}
+
+// A nest host concatenating strings
+func outerClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Outer").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V11)
+	class.NestMembers = []ClassName{"gen/writer/Outer$Inner"}
+	addConstructor(t, class)
+	assert.Nil(t, class.NewMethod(ACC_STATIC, "greet", genString, genString, JInt, JDouble).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).LoadArg(1).LoadArg(2).Concat("Hello \x01, \x01 and \x01!", genString, JInt, JDouble).Return(genString)
+	}).Add())
+	return class
+}
+
+func innerClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Outer$Inner").Visibility(ACC_SUPER).Target(V11)
+	class.NestHost = "gen/writer/Outer"
+	assert.Nil(t, class.NewField(ACC_PRIVATE, "secret", JInt).Add())
+	addConstructor(t, class)
+	return class
+}
+
+// A record implementing a sealed interface, with the methods generated by javac
+func pointClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Point").Visibility(ACC_PUBLIC | ACC_FINAL | ACC_SUPER).
+		SuperClass("java.lang.Record").Implements([]string{"gen.writer.Shape"}).Target(V17)
+	class.RecordComponents = []RecordComponent{
+		{Name: "x", Descriptor: "I", Annotations: []Annotation{{Descriptor: "Lgen/writer/Marker;", Visible: true}}},
+		{Name: "y", Descriptor: "I"},
+	}
+	for _, component := range class.RecordComponents {
+		assert.Nil(t, class.NewField(ACC_PRIVATE|ACC_FINAL, component.Name, JInt).Add())
+		name := component.Name
+		assert.Nil(t, class.NewMethod(ACC_PUBLIC, name, JInt).Code(func(b *CodeBuilder) {
+			b.LoadThis().GetField(class.Name, name, JInt).Return(JInt)
+		}).Add())
+	}
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "<init>", JVoid, JInt, JInt).Code(func(b *CodeBuilder) {
+		b.LoadThis().InvokeSpecial("java/lang/Record", "<init>", "()V")
+		b.LoadThis().LoadArg(0).PutField(class.Name, "x", JInt)
+		b.LoadThis().LoadArg(1).PutField(class.Name, "y", JInt)
+		b.Return(JVoid)
+	}).Add())
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "area", JDouble).Code(func(b *CodeBuilder) {
+		b.LoadThis().GetField(class.Name, "x", JInt).LoadThis().GetField(class.Name, "y", JInt).Insn(IMUL).Insn(I2D).Return(JDouble)
+	}).Add())
+
+	bootstrap := Handle{Kind: REF_invokeStatic, Owner: "java/lang/runtime/ObjectMethods", Name: "bootstrap",
+		Descriptor: "(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/TypeDescriptor;" +
+			"Ljava/lang/Class;Ljava/lang/String;[Ljava/lang/invoke/MethodHandle;)Ljava/lang/Object;"}
+	arguments := []interface{}{class.Name, "x;y",
+		Handle{Kind: REF_getField, Owner: class.Name, Name: "x", Descriptor: "I"},
+		Handle{Kind: REF_getField, Owner: class.Name, Name: "y", Descriptor: "I"},
+	}
+	point := ClassType(class.Name)
+	for _, method := range []struct {
+		name string
+		ret  JType
+		args []JType
+	}{
+		{"toString", genString, nil},
+		{"hashCode", JInt, nil},
+		{"equals", JBool, []JType{genObject}},
+	} {
+		method := method
+		assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_FINAL, method.name, method.ret, method.args...).Code(func(b *CodeBuilder) {
+			b.LoadThis()
+			for i := range method.args {
+				b.LoadArg(i)
+			}
+			descriptor := MethodDescriptor(method.ret, append([]JType{point}, method.args...)...)
+			b.InvokeDynamic(method.name, descriptor, bootstrap, arguments...).Return(method.ret)
+		}).Add())
+	}
+	return class
+}
+
+// A sealed interface with default, static and private methods
+func shapeClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Shape").Visibility(ACC_PUBLIC | ACC_INTERFACE | ACC_ABSTRACT).Target(V17)
+	class.PermittedSubclasses = []ClassName{"gen/writer/Point"}
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_ABSTRACT, "area", JDouble).Add())
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "describe", genString).Code(func(b *CodeBuilder) {
+		b.LoadThis().InvokeInterface(class.Name, "area", "()D").InvokeStatic(class.Name, "square", "(D)D")
+		b.InvokeStatic("java/lang/String", "valueOf", "(D)Ljava/lang/String;").Return(genString)
+	}).Add())
+	assert.Nil(t, class.NewMethod(ACC_PRIVATE|ACC_STATIC, "square", JDouble, JDouble).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).LoadArg(0).Insn(DMUL).Return(JDouble)
+	}).Add())
+	shape := ClassType(class.Name)
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "none", shape).Code(func(b *CodeBuilder) {
+		b.PushNull().Return(shape)
+	}).Add())
+	return class
+}
+
+// A class with all kinds of constants, and the wide forms of the instructions
+func constantsClass(t *testing.T) *JavaClass {
+	class := NewJavaClass("gen.writer.Constants").Visibility(ACC_PUBLIC | ACC_FINAL | ACC_SUPER).Target(V21)
+	for _, field := range []struct {
+		name  string
+		typ   JType
+		value interface{}
+	}{
+		{"LONG", JLong, int64(1) << 40},
+		{"FLOAT", JFloat, float32(1.5)},
+		{"DOUBLE", JDouble, 2.25},
+		{"TEXT", genString, "constant"},
+	} {
+		assert.Nil(t, class.NewField(ACC_PUBLIC|ACC_STATIC|ACC_FINAL, field.name, field.typ).Value(field.value).Add())
+	}
+	addConstructor(t, class)
+	assert.Nil(t, class.NewMethod(ACC_STATIC, "loads", JVoid).Code(func(b *CodeBuilder) {
+		nullConstant := Handle{Kind: REF_invokeStatic, Owner: "java/lang/invoke/ConstantBootstraps", Name: "nullConstant",
+			Descriptor: "(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/Class;)Ljava/lang/Object;"}
+		for _, value := range []interface{}{
+			ClassName("java/lang/String"),
+			MethodType("(I)V"),
+			Handle{Kind: REF_invokeStatic, Owner: "java/lang/Math", Name: "abs", Descriptor: "(I)I"},
+			ConstantDynamic{Name: "_", Descriptor: "Ljava/lang/Object;", Bootstrap: nullConstant},
+		} {
+			b.add(&LdcInsn{Value: value}).Pop()
+		}
+		b.PushLong(123456789).Insn(POP2).PushDouble(0.1).Insn(POP2)
+		// Enough strings for ldc_w
+		for i := 0; i < 300; i++ {
+			b.PushString(fmt.Sprintf("s%d", i)).Pop()
+		}
+		b.PushInt(2).PushInt(3).add(&MultiANewArrayInsn{Descriptor: "[[I", Dimensions: 2}).Pop()
+		b.Return(JVoid)
+	}).Add())
+	// Locals past 255 need wide loads, stores and increments
+	assert.Nil(t, class.NewMethod(ACC_STATIC, "wide", JInt).Code(func(b *CodeBuilder) {
+		var local int
+		for i := 0; i < 300; i++ {
+			local = b.NewLocal(JInt)
+		}
+		b.PushInt(1).StoreLocal(JInt, local).Increment(local, 1000).LoadLocal(JInt, local).Return(JInt)
+	}).Add())
+	return class
+}
+
+// Writes the generated classes to testdata/generated.
+func writeGenerated(t *testing.T) {
+	for _, class := range generatedClasses(t) {
+		var out bytes.Buffer
+		assert.Nil(t, class.WriteWith(&out, ComputeFrames, nil))
+		name := string(class.Name[strings.LastIndex(string(class.Name), "/")+1:])
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(generatedDir, name+".class"), out.Bytes(), 0644))
+	}
+}
+
+func TestGeneratedClassesAreUpToDate(t *testing.T) {
+	if *updateGenerated {
+		writeGenerated(t)
+	}
+	for _, class := range generatedClasses(t) {
+		var out bytes.Buffer
+		assert.Nil(t, class.WriteWith(&out, ComputeFrames, nil))
+		name := string(class.Name[strings.LastIndex(string(class.Name), "/")+1:])
+		// The writer changed if this fails, run go test -run TestGeneratedClassesAreUpToDate -update-generated to write them again
+		assert.Equal(t, readTestBytes(t, filepath.Join(generatedDir, name+".class")), out.Bytes(), name)
+	}
+}
+
+func TestGeneratedClassesCoverClassFeatures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(generatedDir, "*.class"))
+	assert.Nil(t, err)
+	versions := make(map[ClassVersion]bool)
+	features := make(map[Feature]bool)
+	frames, annotations := false, false
+	for _, file := range files {
+		b := readTestBytes(t, file)
+		class, err := (&ClassReader{}).ReadClassBytes(b)
+		assert.Nil(t, err)
+		versions[class.Version()] = true
+		for _, feature := range class.Features() {
+			features[feature] = true
+		}
+		frames = frames || bytes.Contains(b, []byte("StackMapTable"))
+		annotations = annotations || len(class.Annotations) > 0
+	}
+	for _, version := range []ClassVersion{V1_4, V7, V8, V11, V17, V21} {
+		assert.True(t, versions[version], "%s", version)
+	}
+	for _, feature := range []Feature{FeatureInvokeDynamic, FeatureMethodHandles, FeatureConstantDynamic,
+		FeatureNestMates, FeatureRecords, FeatureSealed} {
+		assert.True(t, features[feature], "%s", feature)
+	}
+	assert.True(t, frames)
+	assert.True(t, annotations)
+}
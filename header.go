@@ -0,0 +1,75 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The header of a class file, i.e. what comes before its fields, enough to index a classpath.
+type ClassHeader struct {
+	Version      ClassVersion
+	MinorVersion uint16
+	// The access_flags item of the class, without the ACC_SYNTHETIC flag given by the Synthetic attribute
+	Access     ClassAccess
+	Name       ClassName
+	SuperName  ClassName
+	Interfaces []ClassName
+}
+
+// ReadHeader reads the header of a class, without decoding its fields, methods and attributes.
+func (c *ClassReader) ReadHeader(reader io.Reader) (*ClassHeader, error) {
+	bytes, err := c.readAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadHeaderBytes(bytes)
+}
+
+// ReadHeaderBytes reads the header of the class held by bytes, only the constant pool and the header are looked at.
+func (c *ClassReader) ReadHeaderBytes(bytes []byte) (*ClassHeader, error) {
+	parser := c.newParser()
+	var header *ClassHeader
+	err := parser.protect(0, "class header", func() error {
+		var err error
+		header, err = parser.readHeader(bytes)
+		return err
+	})
+	return header, err
+}
+
+// Reads the version, the constant pool and the header of the class.
+func (c *classParser) readHeader(bytes []byte) (*ClassHeader, error) {
+	if err := checkLimit("class size", len(bytes), c.limits.MaxClassSize); err != nil {
+		return nil, err
+	}
+	magic := readMagic(bytes)
+	if magic != MAGIC {
+		return nil, errors.New(fmt.Sprintf("Invalid class file, expected magic bit found %v", magic))
+	}
+	minorVersion := readUnsignedShort(bytes, 4)
+	majorVersion := readUnsignedShort(bytes, 6)
+	if err := checkVersion(majorVersion, minorVersion); err != nil {
+		if !c.allowUnsupportedVersions {
+			return nil, err
+		}
+		c.report(FormatIssue, 4, "%v", err)
+	}
+	if err := c.fillPoolItems(bytes, readUnsignedShort(bytes, 8)); err != nil {
+		return nil, err
+	}
+
+	header := &ClassHeader{
+		Version:      ClassVersion(majorVersion),
+		MinorVersion: minorVersion,
+		Access:       ClassAccess(readUnsignedShort(bytes, c.headStart)),
+		Name:         c.readClass(bytes, c.headStart+2),
+		SuperName:    c.readClass(bytes, c.headStart+4),
+		Interfaces:   c.readClasses(bytes, c.headStart+6),
+	}
+	c.name, c.access = header.Name, header.Access
+	if err := header.Access.Validate(); err != nil {
+		c.report(SuspiciousFlags, c.headStart, "%v", err)
+	}
+	return header, nil
+}
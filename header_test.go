@@ -0,0 +1,72 @@
+package gytes
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadHeader(t *testing.T) {
+	for _, src := range []string{"testdata/compiled/Hello.class", "testdata/compiled/HelloJavaException.class"} {
+		jclass, err := readClass(src)
+		assert.Nil(t, err)
+		header, err := (&ClassReader{}).ReadHeader(readClassFile(src))
+		assert.Nil(t, err)
+		assert.Equal(t, &ClassHeader{
+			Version:      ClassVersion(jclass.MajorVersion),
+			MinorVersion: jclass.MinorVersion,
+			Access:       jclass.Access,
+			Name:         jclass.Name,
+			SuperName:    jclass.SuperName,
+			Interfaces:   jclass.Interfaces,
+		}, header)
+	}
+}
+
+func TestReadHeaderOfTruncatedClass(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	parser := &classParser{}
+	assert.Nil(t, parser.fillPoolItems(b, readUnsignedShort(b, 8)))
+
+	// The fields and methods are not needed to read the header
+	headerEnd := parser.headStart + 8 + 2*int(readUnsignedShort(b, parser.headStart+6))
+	header, err := (&ClassReader{}).ReadHeaderBytes(b[:headerEnd])
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("Hello"), header.Name)
+
+	_, err = (&ClassReader{}).ReadHeaderBytes(b[:headerEnd-1])
+	assert.True(t, errors.Is(err, MalformedClassError))
+}
+
+func BenchmarkReadHeader(b *testing.B) {
+	bytes := readBenchmarkClass(b)
+	reader := &ClassReader{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := reader.ReadHeaderBytes(bytes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadClass(b *testing.B) {
+	bytes := readBenchmarkClass(b)
+	reader := &ClassReader{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := reader.ReadClassBytes(bytes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func readBenchmarkClass(b *testing.B) []byte {
+	b.Helper()
+	bytes, err := ioutil.ReadFile("testdata/compiled/HelloJavaException.class")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return bytes
+}
@@ -0,0 +1,274 @@
+package gytes
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Returned when a class needed to analyze some code is not known
+var UnknownClassError = errors.New("Unknown class")
+
+// ClassHierarchy gives the headers of the classes referred to by the code being analyzed, so that the
+// relations between them can be found without loading the classes.
+type ClassHierarchy interface {
+	// Header returns the header of the class, or an error wrapping UnknownClassError if it's not known.
+	Header(name ClassName) (*ClassHeader, error)
+}
+
+// CommonSuperClass returns the closest common super class of a and b, which can be classes or arrays like "[I".
+// Interfaces are merged to java/lang/Object like the verifier does, and arrays of references are merged
+// to arrays of the common super class of their elements.
+func CommonSuperClass(hierarchy ClassHierarchy, a, b ClassName) (ClassName, error) {
+	if a == b {
+		return a, nil
+	}
+	if a == ObjectClassName || b == ObjectClassName {
+		return ObjectClassName, nil
+	}
+	aArray, bArray := a.IsArray(), b.IsArray()
+	if aArray && bArray {
+		aElement, aOk := arrayElement(a)
+		bElement, bOk := arrayElement(b)
+		if !aOk || !bOk {
+			return ObjectClassName, nil
+		}
+		element, err := CommonSuperClass(hierarchy, aElement, bElement)
+		if err != nil {
+			return "", err
+		}
+		return ClassName("[" + element.Descriptor()), nil
+	}
+	if aArray || bArray {
+		return ObjectClassName, nil
+	}
+	supers := make(map[ClassName]bool)
+	for name := a; name != "" && name != ObjectClassName; {
+		header, err := hierarchy.Header(name)
+		if err != nil {
+			return "", err
+		}
+		if header.Access.Has(ACC_INTERFACE) {
+			return ObjectClassName, nil
+		}
+		supers[name] = true
+		name = header.SuperName
+	}
+	for name := b; name != "" && name != ObjectClassName; {
+		if supers[name] {
+			return name, nil
+		}
+		header, err := hierarchy.Header(name)
+		if err != nil {
+			return "", err
+		}
+		if header.Access.Has(ACC_INTERFACE) {
+			return ObjectClassName, nil
+		}
+		name = header.SuperName
+	}
+	return ObjectClassName, nil
+}
+
+// IsAssignable tells whether a value of the class or array from can be assigned to a variable of type to,
+// i.e. if to is from, one of its super classes or one of the interfaces it implements.
+func IsAssignable(hierarchy ClassHierarchy, from, to ClassName) (bool, error) {
+	if from == to || to == ObjectClassName {
+		return true, nil
+	}
+	if from.IsArray() {
+		if to.IsArray() {
+			fromElement, fromOk := arrayElement(from)
+			toElement, toOk := arrayElement(to)
+			if !fromOk || !toOk {
+				return false, nil
+			}
+			return IsAssignable(hierarchy, fromElement, toElement)
+		}
+		return to == "java/lang/Cloneable" || to == "java/io/Serializable", nil
+	}
+	if to.IsArray() {
+		return false, nil
+	}
+	seen := make(map[ClassName]bool)
+	pending := []ClassName{from}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if name == to {
+			return true, nil
+		}
+		if name == "" || name == ObjectClassName || seen[name] {
+			continue
+		}
+		seen[name] = true
+		header, err := hierarchy.Header(name)
+		if err != nil {
+			return false, err
+		}
+		pending = append(append(pending, header.SuperName), header.Interfaces...)
+	}
+	return false, nil
+}
+
+// Returns the class of the elements of an array, false if they are primitive values.
+func arrayElement(array ClassName) (ClassName, bool) {
+	element := array[1:]
+	switch {
+	case strings.HasPrefix(string(element), "L"):
+		return element[1 : len(element)-1], true
+	case element.IsArray():
+		return element, true
+	}
+	return "", false
+}
+
+// Hierarchies is a ClassHierarchy looking up the classes in each of its hierarchies in turn.
+type Hierarchies []ClassHierarchy
+
+func (h Hierarchies) Header(name ClassName) (*ClassHeader, error) {
+	for _, hierarchy := range h {
+		header, err := hierarchy.Header(name)
+		if err == nil || !errors.Is(err, UnknownClassError) {
+			return header, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", UnknownClassError, name)
+}
+
+// ClassesHierarchy is a ClassHierarchy of the headers of a set of classes, by name.
+type ClassesHierarchy map[ClassName]*ClassHeader
+
+// NewClassesHierarchy returns the hierarchy of classes that are already read or built.
+func NewClassesHierarchy(classes ...*JavaClass) ClassesHierarchy {
+	h := make(ClassesHierarchy, len(classes))
+	for _, jc := range classes {
+		h.Add(jc)
+	}
+	return h
+}
+
+// Add adds the header of a class to the hierarchy.
+func (h ClassesHierarchy) Add(jc *JavaClass) {
+	h[jc.Name] = &ClassHeader{
+		Version:      ClassVersion(jc.MajorVersion),
+		MinorVersion: jc.MinorVersion,
+		Access:       jc.Access,
+		Name:         jc.Name,
+		SuperName:    jc.SuperName,
+		Interfaces:   jc.Interfaces,
+	}
+}
+
+func (h ClassesHierarchy) Header(name ClassName) (*ClassHeader, error) {
+	if header, ok := h[name]; ok {
+		return header, nil
+	}
+	return nil, fmt.Errorf("%w: %s", UnknownClassError, name)
+}
+
+// ClasspathHierarchy is a ClassHierarchy of the classes of a classpath, made of jars and directories.
+// The headers of the classes are read when they are first needed, and kept.
+// A ClasspathHierarchy can be used concurrently, it must be closed to close its jars.
+type ClasspathHierarchy struct {
+	reader ClassReader
+	// The directories and the entries of the jars, in the order of the classpath
+	entries []classpathEntry
+	jars    []*zip.ReadCloser
+
+	mu      sync.Mutex
+	headers map[ClassName]*ClassHeader
+}
+
+// A directory, or the class files of a jar by name
+type classpathEntry struct {
+	directory string
+	files     map[string]*zip.File
+}
+
+// NewClasspathHierarchy opens the jars of the classpath, paths ending with .jar or .zip are read as jars,
+// the other ones as directories of class files.
+func NewClasspathHierarchy(paths ...string) (*ClasspathHierarchy, error) {
+	h := &ClasspathHierarchy{headers: make(map[ClassName]*ClassHeader)}
+	for _, path := range paths {
+		extension := strings.ToLower(filepath.Ext(path))
+		if extension != ".jar" && extension != ".zip" {
+			h.entries = append(h.entries, classpathEntry{directory: path})
+			continue
+		}
+		jar, err := zip.OpenReader(path)
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+		h.jars = append(h.jars, jar)
+		files := make(map[string]*zip.File)
+		for _, file := range jar.File {
+			if strings.HasSuffix(file.Name, ".class") {
+				files[file.Name] = file
+			}
+		}
+		h.entries = append(h.entries, classpathEntry{files: files})
+	}
+	return h, nil
+}
+
+func (h *ClasspathHierarchy) Header(name ClassName) (*ClassHeader, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if header, ok := h.headers[name]; ok {
+		return header, nil
+	}
+	bytes, err := h.classBytes(string(name) + ".class")
+	if err != nil {
+		return nil, err
+	}
+	if bytes == nil {
+		return nil, fmt.Errorf("%w: %s is not in the classpath", UnknownClassError, name)
+	}
+	header, err := h.reader.ReadHeaderBytes(bytes)
+	if err != nil {
+		return nil, err
+	}
+	h.headers[name] = header
+	return header, nil
+}
+
+// Returns the content of the first class file of the classpath named file, nil if there is none.
+func (h *ClasspathHierarchy) classBytes(file string) ([]byte, error) {
+	for _, entry := range h.entries {
+		if entry.files == nil {
+			bytes, err := ioutil.ReadFile(filepath.Join(entry.directory, filepath.FromSlash(file)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			return bytes, err
+		}
+		if f, ok := entry.files[file]; ok {
+			reader, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer reader.Close()
+			return ioutil.ReadAll(reader)
+		}
+	}
+	return nil, nil
+}
+
+// Close closes the jars of the classpath.
+func (h *ClasspathHierarchy) Close() error {
+	var err error
+	for _, jar := range h.jars {
+		if e := jar.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	h.jars = nil
+	return err
+}
@@ -0,0 +1,104 @@
+package gytes
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonSuperClass(t *testing.T) {
+	for _, test := range []struct{ a, b, common ClassName }{
+		{"java/lang/Integer", "java/lang/Long", "java/lang/Number"},
+		{"java/util/ArrayList", "java/util/LinkedList", "java/util/AbstractList"},
+		{"java/lang/NumberFormatException", "java/lang/ArithmeticException", "java/lang/RuntimeException"},
+		{"java/lang/String", "java/lang/Integer", ObjectClassName},
+		{"java/util/List", "java/util/ArrayList", ObjectClassName},
+		{"[Ljava/lang/Integer;", "[Ljava/lang/Long;", "[Ljava/lang/Number;"},
+		{"[[Ljava/util/HashMap;", "[[Ljava/util/TreeMap;", "[[Ljava/util/AbstractMap;"},
+		{"[I", "[J", ObjectClassName},
+		{"[I", "java/lang/String", ObjectClassName},
+	} {
+		common, err := CommonSuperClass(CoreHierarchy, test.a, test.b)
+		assert.Nil(t, err)
+		assert.Equal(t, test.common, common, "%s %s", test.a, test.b)
+	}
+	_, err := CommonSuperClass(CoreHierarchy, "gen/A", "java/lang/String")
+	assert.True(t, errors.Is(err, UnknownClassError))
+}
+
+func TestIsAssignable(t *testing.T) {
+	for _, test := range []struct {
+		from, to   ClassName
+		assignable bool
+	}{
+		{"java/util/ArrayList", "java/util/List", true},
+		{"java/util/ArrayList", "java/lang/Iterable", true},
+		{"java/util/LinkedHashMap", "java/util/Map", true},
+		{"java/lang/String", "java/lang/CharSequence", true},
+		{"java/io/PrintStream", "java/lang/AutoCloseable", true},
+		{"java/util/List", "java/util/ArrayList", false},
+		{"java/lang/Integer", "java/lang/Long", false},
+		{"[Ljava/lang/String;", "[Ljava/lang/Object;", true},
+		{"[Ljava/lang/String;", "[Ljava/lang/CharSequence;", true},
+		{"[I", "java/lang/Cloneable", true},
+		{"[I", "[J", false},
+		{"java/lang/String", "[C", false},
+	} {
+		assignable, err := IsAssignable(CoreHierarchy, test.from, test.to)
+		assert.Nil(t, err)
+		assert.Equal(t, test.assignable, assignable, "%s %s", test.from, test.to)
+	}
+}
+
+func TestClassesHierarchy(t *testing.T) {
+	hello, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	h := NewClassesHierarchy(hello, NewJavaClass("gen.Greeter").SuperClass("Hello"))
+	common, err := CommonSuperClass(Hierarchies{h, CoreHierarchy}, "gen/Greeter", "java/lang/String")
+	assert.Nil(t, err)
+	assert.Equal(t, ObjectClassName, common)
+	common, err = CommonSuperClass(h, "gen/Greeter", "Hello")
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("Hello"), common)
+
+	_, err = Hierarchies{h, CoreHierarchy}.Header("gen/Unknown")
+	assert.True(t, errors.Is(err, UnknownClassError))
+}
+
+func TestClasspathHierarchy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "classpath")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "Hello.class"), readTestBytes(t, "testdata/compiled/Hello.class"), 0644))
+
+	jarPath := filepath.Join(dir, "lib.jar")
+	out, err := os.Create(jarPath)
+	assert.Nil(t, err)
+	jar := zip.NewWriter(out)
+	w, err := jar.Create("HelloJavaException.class")
+	assert.Nil(t, err)
+	_, err = w.Write(readTestBytes(t, "testdata/compiled/HelloJavaException.class"))
+	assert.Nil(t, err)
+	assert.Nil(t, jar.Close())
+	assert.Nil(t, out.Close())
+
+	h, err := NewClasspathHierarchy(dir, jarPath)
+	assert.Nil(t, err)
+	defer h.Close()
+	for _, name := range []ClassName{"Hello", "HelloJavaException"} {
+		header, err := h.Header(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, header.Name)
+		assert.Equal(t, ObjectClassName, header.SuperName)
+	}
+	_, err = h.Header("java/lang/String")
+	assert.True(t, errors.Is(err, UnknownClassError))
+
+	_, err = NewClasspathHierarchy(filepath.Join(dir, "missing.jar"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,352 @@
+package gytes
+
+// InsnNode is a node of an InsnList: an instruction, or a pseudo instruction marking a position in the code
+// (LabelNode), a line number (LineNumberNode) or a stack map frame (FrameNode).
+// Each node is one of the *...Insn or *...Node types of this file.
+type InsnNode interface {
+	// Opcode returns the opcode of the instruction, -1 for the pseudo instructions
+	Opcode() int
+	// Prev returns the previous node of the list, nil for the first one or if the node isn't in a list
+	Prev() InsnNode
+	// Next returns the next node of the list, nil for the last one or if the node isn't in a list
+	Next() InsnNode
+	// Accept sends the node to cv
+	Accept(cv CodeVisitor)
+	links() *insnLinks
+}
+
+// The position of a node in its list, embedded in all the nodes
+type insnLinks struct {
+	list       *InsnList
+	prev, next InsnNode
+}
+
+func (l *insnLinks) links() *insnLinks { return l }
+func (l *insnLinks) Prev() InsnNode    { return l.prev }
+func (l *insnLinks) Next() InsnNode    { return l.next }
+
+// An instruction without operands, e.g. iadd or return
+type Insn struct {
+	insnLinks
+	Op int
+}
+
+// bipush, sipush and newarray
+type IntInsn struct {
+	insnLinks
+	Op      int
+	Operand int
+}
+
+// Loads, stores and ret
+type VarInsn struct {
+	insnLinks
+	Op  int
+	Var int
+}
+
+// new, anewarray, checkcast and instanceof
+type TypeInsn struct {
+	insnLinks
+	Op   int
+	Type ClassName
+}
+
+type FieldInsn struct {
+	insnLinks
+	Op         int
+	Owner      ClassName
+	Name       string
+	Descriptor string
+}
+
+type MethodInsn struct {
+	insnLinks
+	Op         int
+	Owner      ClassName
+	Name       string
+	Descriptor string
+	// Set when Owner is an interface
+	Interface bool
+}
+
+type InvokeDynamicInsn struct {
+	insnLinks
+	Name       string
+	Descriptor string
+	Bootstrap  Handle
+	Arguments  []interface{}
+}
+
+type JumpInsn struct {
+	insnLinks
+	Op     int
+	Target *Label
+}
+
+// ldc, ldc_w and ldc2_w, the shortest one is chosen when the instruction is written
+type LdcInsn struct {
+	insnLinks
+	// One of int32, float32, int64, float64, string, ClassName, MethodType, Handle or ConstantDynamic
+	Value interface{}
+}
+
+type IincInsn struct {
+	insnLinks
+	Var       int
+	Increment int
+}
+
+type TableSwitchInsn struct {
+	insnLinks
+	Min, Max int
+	Default  *Label
+	// The targets of the keys Min to Max
+	Labels []*Label
+}
+
+type LookupSwitchInsn struct {
+	insnLinks
+	Default *Label
+	Keys    []int
+	Labels  []*Label
+}
+
+type MultiANewArrayInsn struct {
+	insnLinks
+	Descriptor string
+	Dimensions int
+}
+
+// LabelNode marks the position of Label in the list, its offset is computed when the code is written.
+type LabelNode struct {
+	insnLinks
+	Label *Label
+}
+
+// The line of the source file starting at Start
+type LineNumberNode struct {
+	insnLinks
+	Line  int
+	Start *Label
+}
+
+// The stack map frame of the instruction following the node
+type FrameNode struct {
+	insnLinks
+	Frame Frame
+}
+
+func (n *Insn) Opcode() int               { return n.Op }
+func (n *IntInsn) Opcode() int            { return n.Op }
+func (n *VarInsn) Opcode() int            { return n.Op }
+func (n *TypeInsn) Opcode() int           { return n.Op }
+func (n *FieldInsn) Opcode() int          { return n.Op }
+func (n *MethodInsn) Opcode() int         { return n.Op }
+func (n *InvokeDynamicInsn) Opcode() int  { return INVOKEDYNAMIC }
+func (n *JumpInsn) Opcode() int           { return n.Op }
+func (n *LdcInsn) Opcode() int            { return LDC }
+func (n *IincInsn) Opcode() int           { return IINC }
+func (n *TableSwitchInsn) Opcode() int    { return TABLESWITCH }
+func (n *LookupSwitchInsn) Opcode() int   { return LOOKUPSWITCH }
+func (n *MultiANewArrayInsn) Opcode() int { return MULTIANEWARRAY }
+func (n *LabelNode) Opcode() int          { return -1 }
+func (n *LineNumberNode) Opcode() int     { return -1 }
+func (n *FrameNode) Opcode() int          { return -1 }
+
+func (n *Insn) Accept(cv CodeVisitor)     { cv.VisitInsn(n.Op) }
+func (n *IntInsn) Accept(cv CodeVisitor)  { cv.VisitIntInsn(n.Op, n.Operand) }
+func (n *VarInsn) Accept(cv CodeVisitor)  { cv.VisitVarInsn(n.Op, n.Var) }
+func (n *TypeInsn) Accept(cv CodeVisitor) { cv.VisitTypeInsn(n.Op, n.Type) }
+func (n *FieldInsn) Accept(cv CodeVisitor) {
+	cv.VisitFieldInsn(n.Op, n.Owner, n.Name, n.Descriptor)
+}
+func (n *MethodInsn) Accept(cv CodeVisitor) {
+	cv.VisitMethodInsn(n.Op, n.Owner, n.Name, n.Descriptor, n.Interface)
+}
+func (n *InvokeDynamicInsn) Accept(cv CodeVisitor) {
+	cv.VisitInvokeDynamicInsn(n.Name, n.Descriptor, n.Bootstrap, n.Arguments)
+}
+func (n *JumpInsn) Accept(cv CodeVisitor) { cv.VisitJumpInsn(n.Op, n.Target) }
+func (n *LdcInsn) Accept(cv CodeVisitor)  { cv.VisitLdcInsn(n.Value) }
+func (n *IincInsn) Accept(cv CodeVisitor) { cv.VisitIincInsn(n.Var, n.Increment) }
+func (n *TableSwitchInsn) Accept(cv CodeVisitor) {
+	cv.VisitTableSwitchInsn(n.Min, n.Max, n.Default, n.Labels)
+}
+func (n *LookupSwitchInsn) Accept(cv CodeVisitor) {
+	cv.VisitLookupSwitchInsn(n.Default, n.Keys, n.Labels)
+}
+func (n *MultiANewArrayInsn) Accept(cv CodeVisitor) {
+	cv.VisitMultiANewArrayInsn(n.Descriptor, n.Dimensions)
+}
+func (n *LabelNode) Accept(cv CodeVisitor)      { cv.VisitLabel(n.Label) }
+func (n *LineNumberNode) Accept(cv CodeVisitor) { cv.VisitLineNumber(n.Line, n.Start) }
+func (n *FrameNode) Accept(cv CodeVisitor)      { cv.VisitFrame(n.Frame) }
+
+// A range of instructions whose exceptions of class Type are handled by the code at Handler
+type TryCatchBlock struct {
+	Start, End, Handler *Label
+	// Empty for handlers that catch any exception, i.e. finally blocks
+	Type ClassName
+}
+
+// A local variable of the source code, as given by the LocalVariableTable and LocalVariableTypeTable attributes
+type LocalVariable struct {
+	Name       string
+	Descriptor string
+	// The generic signature of the variable, empty if it has none
+	Signature  string
+	Start, End *Label
+	Index      int
+}
+
+// InsnList is a doubly linked list of the instructions of a method, which can be edited while iterating over it:
+//
+//	for n := list.First(); n != nil; n = n.Next() { ... }
+//
+// A node belongs to one list at a time, adding a node removes it from its list first. The zero value is an empty list.
+// The offsets of the instructions are only computed when the list is written, e.g. by a ClassWriter.
+type InsnList struct {
+	first, last InsnNode
+	size        int
+}
+
+// Len returns the number of nodes of the list, pseudo instructions included.
+func (l *InsnList) Len() int {
+	return l.size
+}
+
+func (l *InsnList) First() InsnNode {
+	return l.first
+}
+
+func (l *InsnList) Last() InsnNode {
+	return l.last
+}
+
+// Contains tells whether n is a node of l.
+func (l *InsnList) Contains(n InsnNode) bool {
+	return n.links().list == l
+}
+
+// Add appends nodes to the list.
+func (l *InsnList) Add(nodes ...InsnNode) {
+	for _, n := range nodes {
+		l.insert(n, l.last, nil)
+	}
+}
+
+// Insert inserts nodes at the start of the list.
+func (l *InsnList) Insert(nodes ...InsnNode) {
+	next := l.first
+	for _, n := range nodes {
+		l.insert(n, prevOf(next, l), next)
+	}
+}
+
+// InsertBefore inserts nodes before mark, it does nothing if mark isn't a node of l.
+func (l *InsnList) InsertBefore(mark InsnNode, nodes ...InsnNode) {
+	if !l.Contains(mark) {
+		return
+	}
+	for _, n := range nodes {
+		l.insert(n, mark.Prev(), mark)
+	}
+}
+
+// InsertAfter inserts nodes after mark, it does nothing if mark isn't a node of l.
+func (l *InsnList) InsertAfter(mark InsnNode, nodes ...InsnNode) {
+	if !l.Contains(mark) {
+		return
+	}
+	prev := mark
+	for _, n := range nodes {
+		l.insert(n, prev, prev.Next())
+		prev = n
+	}
+}
+
+// Set replaces old with n, it does nothing if old isn't a node of l.
+func (l *InsnList) Set(old, n InsnNode) {
+	if !l.Contains(old) || old == n {
+		return
+	}
+	l.InsertAfter(old, n)
+	l.Remove(old)
+}
+
+// Remove removes n from the list, it does nothing if n isn't a node of l.
+func (l *InsnList) Remove(n InsnNode) {
+	links := n.links()
+	if links.list != l {
+		return
+	}
+	if links.prev == nil {
+		l.first = links.next
+	} else {
+		links.prev.links().next = links.next
+	}
+	if links.next == nil {
+		l.last = links.prev
+	} else {
+		links.next.links().prev = links.prev
+	}
+	*links = insnLinks{}
+	l.size--
+}
+
+// Clear removes all the nodes of the list.
+func (l *InsnList) Clear() {
+	for n := l.first; n != nil; {
+		next := n.Next()
+		*n.links() = insnLinks{}
+		n = next
+	}
+	*l = InsnList{}
+}
+
+// Nodes returns the nodes of the list, in order.
+func (l *InsnList) Nodes() []InsnNode {
+	nodes := make([]InsnNode, 0, l.size)
+	for n := l.first; n != nil; n = n.Next() {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// Accept sends all the nodes of the list to cv.
+func (l *InsnList) Accept(cv CodeVisitor) {
+	for n := l.first; n != nil; n = n.Next() {
+		n.Accept(cv)
+	}
+}
+
+// The node preceding next in l, the last node if next is nil
+func prevOf(next InsnNode, l *InsnList) InsnNode {
+	if next == nil {
+		return l.last
+	}
+	return next.Prev()
+}
+
+// Links n between prev and next, which follow each other in l.
+func (l *InsnList) insert(n InsnNode, prev, next InsnNode) {
+	if n == prev || n == next {
+		return
+	}
+	if list := n.links().list; list != nil {
+		list.Remove(n)
+	}
+	*n.links() = insnLinks{list: l, prev: prev, next: next}
+	if prev == nil {
+		l.first = n
+	} else {
+		prev.links().next = n
+	}
+	if next == nil {
+		l.last = n
+	} else {
+		next.links().prev = n
+	}
+	l.size++
+}
@@ -0,0 +1,152 @@
+package gytes
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func opcodes(list *InsnList) []int {
+	ops := make([]int, 0)
+	for n := list.First(); n != nil; n = n.Next() {
+		if op := n.Opcode(); op >= 0 {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func TestInsnListEditing(t *testing.T) {
+	list := &InsnList{}
+	load, add, ret := &VarInsn{Op: ILOAD, Var: 0}, &Insn{Op: IADD}, &Insn{Op: IRETURN}
+	list.Add(load, ret)
+	list.InsertBefore(ret, &Insn{Op: ICONST_1}, add)
+	assert.Equal(t, []int{ILOAD, ICONST_1, IADD, IRETURN}, opcodes(list))
+	assert.Equal(t, 4, list.Len())
+
+	list.Set(add, &Insn{Op: ISUB})
+	assert.False(t, list.Contains(add))
+	assert.Nil(t, add.Next())
+	list.Insert(&LabelNode{Label: &Label{}})
+	list.InsertAfter(load, &Insn{Op: DUP}, &Insn{Op: IMUL})
+	assert.Equal(t, []int{ILOAD, DUP, IMUL, ICONST_1, ISUB, IRETURN}, opcodes(list))
+	assert.Equal(t, -1, list.First().Opcode())
+	assert.Equal(t, ret, list.Last())
+
+	// Adding a node of another list moves it
+	other := &InsnList{}
+	other.Add(load)
+	assert.Equal(t, 6, list.Len())
+	assert.Equal(t, 1, other.Len())
+	assert.Equal(t, []int{DUP, IMUL, ICONST_1, ISUB, IRETURN}, opcodes(list))
+
+	list.Remove(load)
+	assert.Equal(t, 6, list.Len())
+	list.InsertBefore(load, &Insn{Op: NOP})
+	assert.Equal(t, 6, list.Len())
+
+	nodes := list.Nodes()
+	list.Clear()
+	assert.Equal(t, 0, list.Len())
+	assert.Nil(t, list.First())
+	assert.Nil(t, nodes[1].Prev())
+}
+
+func TestReaderProducesInstructions(t *testing.T) {
+	class, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	main := class.Methods[1]
+	assert.Equal(t, "main", main.Name)
+	assert.Equal(t, []int{GETSTATIC, LDC, INVOKEVIRTUAL, RETURN}, opcodes(main.Instructions))
+
+	nodes := main.Instructions.Nodes()
+	start := nodes[0].(*LabelNode).Label
+	assert.Equal(t, &LineNumberNode{Line: 13, Start: start}, detachedNode(nodes[1]))
+	assert.Equal(t, &FieldInsn{Op: GETSTATIC, Owner: "java/lang/System", Name: "out", Descriptor: "Ljava/io/PrintStream;"}, detachedNode(nodes[2]))
+	assert.Equal(t, "Hello world", nodes[3].(*LdcInsn).Value)
+}
+
+func TestJavaClassWritesEditedInstructions(t *testing.T) {
+	class, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	main := &class.Methods[1]
+	for n := main.Instructions.First(); n != nil; n = n.Next() {
+		if ldc, ok := n.(*LdcInsn); ok {
+			ldc.Value = "Bonjour"
+		}
+		if n.Opcode() == RETURN {
+			// if (args.length == 0) System.out.println("Bonjour"); before returning
+			again := &Label{}
+			main.Instructions.InsertBefore(n,
+				&VarInsn{Op: ALOAD, Var: 0},
+				&Insn{Op: ARRAYLENGTH},
+				&JumpInsn{Op: IFNE, Target: again},
+				&FieldInsn{Op: GETSTATIC, Owner: "java/lang/System", Name: "out", Descriptor: "Ljava/io/PrintStream;"},
+				&LdcInsn{Value: "Bonjour"},
+				&MethodInsn{Op: INVOKEVIRTUAL, Owner: "java/io/PrintStream", Name: "println", Descriptor: "(Ljava/lang/String;)V"},
+				&LabelNode{Label: again},
+				&FrameNode{Frame: Frame{Type: FrameSame}},
+			)
+		}
+	}
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+
+	tracer := &methodTracer{method: "main", code: &codeTracer{}}
+	jumps := &jumpTracer{}
+	tracer.code.Next = jumps
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(out.Bytes(), tracer))
+	assert.Equal(t, []string{
+		"line 13",
+		"getstatic java/lang/System.out:Ljava/io/PrintStream;",
+		"ldc Bonjour",
+		"invokevirtual java/io/PrintStream.println(Ljava/lang/String;)V",
+		"line 14",
+		"aload 0",
+		"arraylength",
+		"getstatic java/lang/System.out:Ljava/io/PrintStream;",
+		"ldc Bonjour",
+		"invokevirtual java/io/PrintStream.println(Ljava/lang/String;)V",
+		"return",
+		"maxs 2 1",
+	}, tracer.code.trace)
+	// The offsets of the labels are recomputed when the list is written
+	assert.Equal(t, []int{21}, jumps.targets)
+}
+
+func TestJavaClassRaisesMaxsOfEditedInstructions(t *testing.T) {
+	class, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	main := &class.Methods[1]
+	assert.Equal(t, uint16(2), main.MaxStack)
+	assert.Equal(t, uint16(1), main.MaxLocals)
+	for n := main.Instructions.First(); n != nil; n = n.Next() {
+		if n.Opcode() == RETURN {
+			// int x = 1 + (2 + 3);
+			main.Instructions.InsertBefore(n,
+				&Insn{Op: ICONST_1}, &Insn{Op: ICONST_2}, &Insn{Op: ICONST_3},
+				&Insn{Op: IADD}, &Insn{Op: IADD}, &VarInsn{Op: ISTORE, Var: 1},
+			)
+		}
+	}
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(3), read.Methods[1].MaxStack)
+	assert.Equal(t, uint16(2), read.Methods[1].MaxLocals)
+	// The maxs of the other methods are kept
+	assert.Equal(t, class.Methods[0].MaxStack, read.Methods[0].MaxStack)
+}
+
+// Returns a copy of the node that is not linked to a list, to compare it with the expected node.
+func detachedNode(n InsnNode) InsnNode {
+	c := reflect.New(reflect.TypeOf(n).Elem())
+	c.Elem().Set(reflect.ValueOf(n).Elem())
+	node := c.Interface().(InsnNode)
+	*node.links() = insnLinks{}
+	return node
+}
@@ -0,0 +1,141 @@
+package gytes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The bootstrap method of the lambda expressions and method references compiled by javac
+var LambdaMetafactory = Handle{
+	Kind:  REF_invokeStatic,
+	Owner: "java/lang/invoke/LambdaMetafactory",
+	Name:  "metafactory",
+	Descriptor: "(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;" +
+		"Ljava/lang/invoke/MethodType;Ljava/lang/invoke/MethodHandle;Ljava/lang/invoke/MethodType;)Ljava/lang/invoke/CallSite;",
+}
+
+// The bootstrap method of the string concatenations compiled by javac since Java 9
+var StringConcatFactory = Handle{
+	Kind:  REF_invokeStatic,
+	Owner: "java/lang/invoke/StringConcatFactory",
+	Name:  "makeConcatWithConstants",
+	Descriptor: "(Ljava/lang/invoke/MethodHandles$Lookup;Ljava/lang/String;Ljava/lang/invoke/MethodType;" +
+		"Ljava/lang/String;[Ljava/lang/Object;)Ljava/lang/invoke/CallSite;",
+}
+
+// The number of argument slots a concatenation can take, a limit of StringConcatFactory
+const maxConcatSlots = 200
+
+// Lambda describes a lambda expression or a method reference: an instance of a functional interface whose
+// abstract method calls the Implementation method.
+type Lambda struct {
+	// The functional interface, and the name and the descriptor of its abstract method,
+	// e.g. java/util/function/Function, apply and (Ljava/lang/Object;)Ljava/lang/Object;
+	Interface  ClassName
+	Method     string
+	Descriptor string
+	// The method called by the lambda, e.g. a method added with JavaClass.NewLambdaMethod. It takes the captured
+	// values followed by the arguments of the abstract method, its receiver coming first
+	Implementation Handle
+	// The types of the values captured by the lambda, they're on the stack before the call site
+	Captured []JType
+}
+
+// LambdaInsn returns the invokedynamic instruction creating the lambda with the LambdaMetafactory, like javac.
+// The abstract method is specialized with the types of the implementation, the primitive types being boxed
+// where the abstract method takes or returns a reference.
+func LambdaInsn(lambda Lambda) (*InvokeDynamicInsn, error) {
+	args, ret, err := SplitMethodDescriptor(lambda.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	impl := lambda.Implementation
+	implArgs, implRet, err := SplitMethodDescriptor(impl.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	switch impl.Kind {
+	case REF_invokeVirtual, REF_invokeInterface, REF_invokeSpecial:
+		implArgs = append([]string{impl.Owner.Descriptor()}, implArgs...)
+	case REF_newInvokeSpecial:
+		implRet = impl.Owner.Descriptor()
+	case REF_invokeStatic:
+	default:
+		return nil, fmt.Errorf("%w: the lambda %s.%s can't be implemented by the field %s", InvalidInstructionError,
+			lambda.Interface, lambda.Method, impl)
+	}
+	if len(implArgs) != len(lambda.Captured)+len(args) {
+		return nil, fmt.Errorf("%w: %s takes %d arguments, not %d captured values and the arguments of %s.%s%s",
+			InvalidInstructionError, impl, len(implArgs), len(lambda.Captured), lambda.Interface, lambda.Method, lambda.Descriptor)
+	}
+
+	var instantiated strings.Builder
+	instantiated.WriteByte('(')
+	for i, arg := range implArgs[len(lambda.Captured):] {
+		instantiated.WriteString(specialize(args[i], arg))
+	}
+	instantiated.WriteByte(')')
+	if ret == JVoid.VMRep {
+		instantiated.WriteString(ret)
+	} else {
+		instantiated.WriteString(specialize(ret, implRet))
+	}
+	return &InvokeDynamicInsn{
+		Name:       lambda.Method,
+		Descriptor: MethodDescriptor(ClassType(lambda.Interface), lambda.Captured...),
+		Bootstrap:  LambdaMetafactory,
+		Arguments:  []interface{}{MethodType(lambda.Descriptor), impl, MethodType(instantiated.String())},
+	}, nil
+}
+
+// Returns the type of a value of the implementation of a lambda, as seen by the abstract method.
+func specialize(abstract, impl string) string {
+	if abstract[0] == 'L' || abstract[0] == '[' {
+		if boxed, ok := boxedTypes[impl]; ok {
+			return boxed
+		}
+	}
+	return impl
+}
+
+var boxedTypes = map[string]string{
+	"Z": "Ljava/lang/Boolean;",
+	"B": "Ljava/lang/Byte;",
+	"S": "Ljava/lang/Short;",
+	"C": "Ljava/lang/Character;",
+	"I": "Ljava/lang/Integer;",
+	"J": "Ljava/lang/Long;",
+	"F": "Ljava/lang/Float;",
+	"D": "Ljava/lang/Double;",
+}
+
+// ConcatInsn returns the invokedynamic instruction replacing the values of types args on the stack by their
+// concatenation, with the makeConcatWithConstants method of the StringConcatFactory like javac.
+// recipe is the text of the result, where each \x01 stands for the next argument, e.g. "x=\x01, y=\x01".
+func ConcatInsn(recipe string, args ...JType) (*InvokeDynamicInsn, error) {
+	if count := strings.Count(recipe, "\x01"); count != len(args) {
+		return nil, fmt.Errorf("%w: the concatenation recipe %q has %d arguments, not %d",
+			InvalidInstructionError, recipe, count, len(args))
+	}
+	if strings.ContainsRune(recipe, '\x02') {
+		return nil, fmt.Errorf("%w: the constants of the concatenation recipe %q must be written in the recipe",
+			InvalidInstructionError, recipe)
+	}
+	slots := 0
+	for _, arg := range args {
+		if arg.VMRep == "" || arg == JVoid {
+			return nil, fmt.Errorf("%w: a concatenation of a %s value", InvalidInstructionError, arg.Name)
+		}
+		slots += arg.Size()
+	}
+	if slots > maxConcatSlots {
+		return nil, fmt.Errorf("%w: a concatenation takes at most %d argument slots, not %d",
+			InvalidInstructionError, maxConcatSlots, slots)
+	}
+	return &InvokeDynamicInsn{
+		Name:       "makeConcatWithConstants",
+		Descriptor: MethodDescriptor(ClassType("java/lang/String"), args...),
+		Bootstrap:  StringConcatFactory,
+		Arguments:  []interface{}{recipe},
+	}, nil
+}
@@ -0,0 +1,158 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLambdas(t *testing.T) {
+	str := ClassType("java/lang/String")
+	class := NewJavaClass("gen.Lambdas").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V11)
+
+	// prefix -> o -> prefix + o
+	concat := class.NewLambdaMethod(str, str, ClassType(ObjectClassName)).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).LoadArg(1).Concat("\x01\x01", str, ClassType(ObjectClassName)).Return(str)
+	})
+	assert.Nil(t, concat.Add())
+	prefixer := Lambda{
+		Interface:      "java/util/function/Function",
+		Method:         "apply",
+		Descriptor:     "(Ljava/lang/Object;)Ljava/lang/Object;",
+		Implementation: concat.Handle(),
+		Captured:       []JType{str},
+	}
+	function := ClassType("java/util/function/Function")
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "prefixer", function, str).Code(func(b *CodeBuilder) {
+		b.LoadArg(0).Lambda(prefixer).Return(function)
+	}).Add())
+
+	// () -> 42
+	answer := class.NewLambdaMethod(JInt).Code(func(b *CodeBuilder) { b.PushInt(42).Return(JInt) })
+	assert.Nil(t, answer.Add())
+	supplier := ClassType("java/util/function/Supplier")
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_STATIC, "answer", supplier).Code(func(b *CodeBuilder) {
+		b.Lambda(Lambda{
+			Interface:      "java/util/function/Supplier",
+			Method:         "get",
+			Descriptor:     "()Ljava/lang/Object;",
+			Implementation: answer.Handle(),
+		}).Return(supplier)
+	}).Add())
+
+	var out bytes.Buffer
+	assert.Nil(t, class.WriteWith(&out, ComputeFrames, nil))
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "lambda$0", read.Methods[0].Name)
+	assert.Equal(t, MethodAccess(ACC_PRIVATE|ACC_STATIC|ACC_SYNTHETIC), read.Methods[0].Modifiers)
+	assert.Equal(t, "lambda$1", read.Methods[2].Name)
+
+	// The call site of the method, out of its list
+	indy := func(method JavaMethod) *InvokeDynamicInsn {
+		for n := method.Instructions.First(); n != nil; n = n.Next() {
+			if insn, ok := n.(*InvokeDynamicInsn); ok {
+				return &InvokeDynamicInsn{Name: insn.Name, Descriptor: insn.Descriptor, Bootstrap: insn.Bootstrap,
+					Arguments: insn.Arguments}
+			}
+		}
+		return nil
+	}
+	assert.Equal(t, &InvokeDynamicInsn{
+		Name:       "makeConcatWithConstants",
+		Descriptor: "(Ljava/lang/String;Ljava/lang/Object;)Ljava/lang/String;",
+		Bootstrap:  StringConcatFactory,
+		Arguments:  []interface{}{"\x01\x01"},
+	}, indy(read.Methods[0]))
+	assert.Equal(t, &InvokeDynamicInsn{
+		Name:       "apply",
+		Descriptor: "(Ljava/lang/String;)Ljava/util/function/Function;",
+		Bootstrap:  LambdaMetafactory,
+		Arguments: []interface{}{
+			MethodType("(Ljava/lang/Object;)Ljava/lang/Object;"),
+			Handle{Kind: REF_invokeStatic, Owner: "gen/Lambdas", Name: "lambda$0",
+				Descriptor: "(Ljava/lang/String;Ljava/lang/Object;)Ljava/lang/String;"},
+			MethodType("(Ljava/lang/Object;)Ljava/lang/String;"),
+		},
+	}, indy(read.Methods[1]))
+	// The primitive result is boxed
+	assert.Equal(t, MethodType("()Ljava/lang/Integer;"), indy(read.Methods[3]).Arguments[2])
+
+	// The version must support invokedynamic
+	old := NewJavaClass("gen.Old").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V6)
+	assert.Nil(t, old.NewMethod(ACC_PUBLIC|ACC_STATIC, "answer", supplier).Code(func(b *CodeBuilder) {
+		b.Lambda(Lambda{
+			Interface:      "java/util/function/Supplier",
+			Method:         "get",
+			Descriptor:     "()Ljava/lang/Object;",
+			Implementation: answer.Handle(),
+		}).Return(supplier)
+	}).Add())
+	assert.True(t, errors.Is(old.ValidateVersion(), UnsupportedVersionError))
+}
+
+func TestLambdaInsn(t *testing.T) {
+	// String::length
+	length := Handle{Kind: REF_invokeVirtual, Owner: "java/lang/String", Name: "length", Descriptor: "()I"}
+	insn, err := LambdaInsn(Lambda{
+		Interface:      "java/util/function/ToIntFunction",
+		Method:         "applyAsInt",
+		Descriptor:     "(Ljava/lang/Object;)I",
+		Implementation: length,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "()Ljava/util/function/ToIntFunction;", insn.Descriptor)
+	assert.Equal(t, MethodType("(Ljava/lang/String;)I"), insn.Arguments[2])
+
+	// ArrayList::new
+	constructor := Handle{Kind: REF_newInvokeSpecial, Owner: "java/util/ArrayList", Name: "<init>", Descriptor: "()V"}
+	insn, err = LambdaInsn(Lambda{
+		Interface:      "java/util/function/Supplier",
+		Method:         "get",
+		Descriptor:     "()Ljava/lang/Object;",
+		Implementation: constructor,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, MethodType("()Ljava/util/ArrayList;"), insn.Arguments[2])
+
+	for _, lambda := range []Lambda{
+		{Interface: "java/lang/Runnable", Method: "run", Descriptor: "()V", Implementation: length},
+		{Interface: "java/lang/Runnable", Method: "run", Descriptor: "()V",
+			Implementation: Handle{Kind: REF_getStatic, Owner: "gen/A", Name: "f", Descriptor: "I"}},
+		{Interface: "java/lang/Runnable", Method: "run", Descriptor: "(", Implementation: length},
+	} {
+		_, err := LambdaInsn(lambda)
+		assert.NotNil(t, err, "%v", lambda)
+	}
+}
+
+func TestConcatInsn(t *testing.T) {
+	insn, err := ConcatInsn("x=\x01, y=\x01", JInt, JLong)
+	assert.Nil(t, err)
+	assert.Equal(t, "(IJ)Ljava/lang/String;", insn.Descriptor)
+
+	many := make([]JType, maxConcatSlots/2+1)
+	recipe := ""
+	for i := range many {
+		many[i], recipe = JLong, recipe+"\x01"
+	}
+	for _, test := range []struct {
+		recipe string
+		args   []JType
+	}{
+		{"\x01", nil},
+		{"\x02", nil},
+		{"\x01", []JType{JVoid}},
+		{"\x01", []JType{{}}},
+		{recipe, many},
+	} {
+		_, err := ConcatInsn(test.recipe, test.args...)
+		assert.True(t, errors.Is(err, InvalidInstructionError), "%v", err)
+	}
+
+	// The error is returned by the code builder
+	method := JavaMethod{Name: "f", Modifiers: ACC_STATIC, Descriptor: "()V"}
+	assert.True(t, errors.Is(NewCodeBuilder(&method).Concat("\x01").Return(JVoid).Build(), InvalidInstructionError))
+}
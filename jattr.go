@@ -7,5 +7,95 @@ package gytes
 //   u4 attribute_length;
 //   u1 info[attribute_length];
 // }
+//
+// Attributes that gytes does not decode are kept as is, Data holds the info bytes. The pool indexes found in
+// the Data of the standard attributes read by a ClassReader, e.g. InnerClasses, are moved to the pool of the
+// class they're written to.
 type JAttribute struct {
+	Name string
+	Data []byte
+	// The value decoded by the AttributeCodec registered for the attribute, if any.
+	// When it is set, it is encoded again by the codec when the class is written, instead of using Data
+	Value interface{}
+	// The pool of the class a standard attribute holding pool indexes was read from, see poolAttributes
+	pool *attributePool
+}
+
+// The structures of a class file that hold attributes
+type AttributeLocation int
+
+const (
+	ClassAttribute AttributeLocation = iota
+	FieldAttribute
+	MethodAttribute
+	CodeAttribute
+	RecordComponentAttribute
+)
+
+func (l AttributeLocation) String() string {
+	return [...]string{"class", "field", "method", "code", "record component"}[l]
+}
+
+// Gives access to the constant pool of the class an attribute is decoded from,
+// the indexes must point to entries of the expected type.
+type ConstantPoolReader interface {
+	Utf8(index uint16) string
+	Class(index uint16) ClassName
+	// Returns a loadable constant, see CodeVisitor.VisitLdcInsn for the possible types
+	Constant(index uint16) interface{}
+}
+
+// Gives access to the constant pool of the class an attribute is encoded into,
+// the entries are added to the pool if it doesn't hold them already.
+type ConstantPoolWriter interface {
+	Utf8(value string) uint16
+	Class(name ClassName) uint16
+	// value is a loadable constant, see CodeVisitor.VisitLdcInsn for the possible types
+	Constant(value interface{}) uint16
+}
+
+// An AttributeCodec converts the content of a custom attribute into a value of the caller's type, and back.
+type AttributeCodec interface {
+	Decode(data []byte, pool ConstantPoolReader) (interface{}, error)
+	Encode(value interface{}, pool ConstantPoolWriter) ([]byte, error)
+}
+
+type attributeKey struct {
+	name     string
+	location AttributeLocation
+}
+
+// AttributeRegistry holds the codecs of the custom attributes, a ClassReader decodes the attributes
+// registered in it, and a class writer encodes them back.
+// The codecs must be registered before the registry is used, it is then safe for concurrent use.
+type AttributeRegistry struct {
+	codecs map[attributeKey]AttributeCodec
+}
+
+func NewAttributeRegistry() *AttributeRegistry {
+	return &AttributeRegistry{codecs: make(map[attributeKey]AttributeCodec)}
+}
+
+// Register sets the codec of the attributes named name found at the given locations.
+func (r *AttributeRegistry) Register(name string, codec AttributeCodec, locations ...AttributeLocation) {
+	for _, location := range locations {
+		r.codecs[attributeKey{name, location}] = codec
+	}
+}
+
+// Codec returns the codec of the attributes named name found at the given location.
+func (r *AttributeRegistry) Codec(name string, location AttributeLocation) (AttributeCodec, bool) {
+	if r == nil {
+		return nil, false
+	}
+	codec, ok := r.codecs[attributeKey{name, location}]
+	return codec, ok
+}
+
+// The registry used by the ClassReaders and class writers that don't have their own
+var DefaultAttributeRegistry = NewAttributeRegistry()
+
+// RegisterAttribute registers a codec in the DefaultAttributeRegistry.
+func RegisterAttribute(name string, codec AttributeCodec, locations ...AttributeLocation) {
+	DefaultAttributeRegistry.Register(name, codec, locations...)
 }
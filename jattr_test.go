@@ -0,0 +1,104 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Decodes attributes holding the index of a CONSTANT_Utf8 entry
+type utf8Codec struct{}
+
+func (utf8Codec) Decode(data []byte, pool ConstantPoolReader) (interface{}, error) {
+	if len(data) != 2 {
+		return nil, errors.New("expected a single index")
+	}
+	return pool.Utf8(readUnsignedShort(data, 0)), nil
+}
+
+func (utf8Codec) Encode(value interface{}, pool ConstantPoolWriter) ([]byte, error) {
+	index := pool.Utf8(value.(string))
+	return []byte{byte(index >> 8), byte(index)}, nil
+}
+
+// Returns Hello.class with its SourceFile attribute renamed to name.
+func helloWithCustomAttribute(t *testing.T, name string) []byte {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	return bytes.Replace(b, []byte("SourceFile"), []byte(name), 1)
+}
+
+func TestCustomAttributeIsDecoded(t *testing.T) {
+	registry := NewAttributeRegistry()
+	registry.Register("SourceFilX", utf8Codec{}, ClassAttribute, FieldAttribute)
+	diagnostics := &Diagnostics{}
+	reader := &ClassReader{Attributes: registry, Diagnostics: diagnostics}
+
+	jclass, err := reader.ReadClassBytes(helloWithCustomAttribute(t, "SourceFilX"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SourceFilX", jclass.Attributes[0].Name)
+	assert.Equal(t, "Hello.java", jclass.Attributes[0].Value)
+	assert.Empty(t, diagnostics.All())
+}
+
+func TestCustomAttributeIsWrittenBackWithTheReaderCodecs(t *testing.T) {
+	registry := NewAttributeRegistry()
+	registry.Register("SourceFilX", utf8Codec{}, ClassAttribute)
+	reader := &ClassReader{Attributes: registry}
+
+	jclass, err := reader.ReadClassBytes(helloWithCustomAttribute(t, "SourceFilX"))
+	assert.Nil(t, err)
+	jclass.Attributes[0].Value = "World.java"
+	var out bytes.Buffer
+	assert.Nil(t, jclass.Write(&out))
+
+	read, err := reader.ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "World.java", read.Attributes[0].Value)
+}
+
+func TestCustomAttributeAtAnotherLocationIsNotDecoded(t *testing.T) {
+	registry := NewAttributeRegistry()
+	registry.Register("SourceFilX", utf8Codec{}, MethodAttribute)
+	diagnostics := &Diagnostics{}
+	reader := &ClassReader{Attributes: registry, Diagnostics: diagnostics}
+
+	jclass, err := reader.ReadClassBytes(helloWithCustomAttribute(t, "SourceFilX"))
+	assert.Nil(t, err)
+	assert.Nil(t, jclass.Attributes[0].Value)
+	assert.Equal(t, 1, diagnostics.Count(UnknownAttribute))
+}
+
+func TestDefaultAttributeRegistry(t *testing.T) {
+	RegisterAttribute("SourceFilY", utf8Codec{}, ClassAttribute)
+	defer delete(DefaultAttributeRegistry.codecs, attributeKey{"SourceFilY", ClassAttribute})
+
+	jclass, err := (&ClassReader{}).ReadClassBytes(helloWithCustomAttribute(t, "SourceFilY"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello.java", jclass.Attributes[0].Value)
+}
+
+type failingCodec struct {
+	utf8Codec
+}
+
+func (failingCodec) Decode(data []byte, pool ConstantPoolReader) (interface{}, error) {
+	return nil, errors.New("unsupported version")
+}
+
+func TestCustomAttributeDecodingErrors(t *testing.T) {
+	registry := NewAttributeRegistry()
+	registry.Register("SourceFilX", failingCodec{}, ClassAttribute)
+	b := helloWithCustomAttribute(t, "SourceFilX")
+
+	_, err := (&ClassReader{Attributes: registry}).ReadClassBytes(b)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Could not decode class attribute SourceFilX")
+
+	diagnostics := &Diagnostics{}
+	jclass, err := (&ClassReader{Attributes: registry, Lenient: true, Diagnostics: diagnostics}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Empty(t, jclass.Attributes)
+	assert.Equal(t, 1, diagnostics.Count(SkippedContent))
+}
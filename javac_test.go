@@ -0,0 +1,127 @@
+package gytes
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var compileJavac = flag.Bool("compile-javac", false, "compile the sources of testdata/src/corpus to testdata/javac, needs a JDK 17 or later")
+
+const javacDir = "testdata/javac"
+
+// The sources compiled for each release, the ones of testdata/src/corpus17 use the features of Java 17
+var javacReleases = map[int][]string{
+	8:  {"testdata/src/corpus"},
+	11: {"testdata/src/corpus"},
+	17: {"testdata/src/corpus", "testdata/src/corpus17"},
+}
+
+// Compiles the corpus with the debug information and the parameter names, as the build tools usually do.
+func compileCorpus(t *testing.T) {
+	for release, dirs := range javacReleases {
+		out := filepath.Join(javacDir, fmt.Sprint(release))
+		assert.Nil(t, os.RemoveAll(out))
+		args := []string{"--release", fmt.Sprint(release), "-g", "-parameters", "-d", out}
+		for _, dir := range dirs {
+			sources, err := filepath.Glob(filepath.Join(dir, "*.java"))
+			assert.Nil(t, err)
+			args = append(args, sources...)
+		}
+		output, err := exec.Command("javac", args...).CombinedOutput()
+		assert.Nil(t, err, "%s", output)
+	}
+}
+
+// Returns the classes compiled for the release, along with their names. The tests are skipped when the
+// corpus was never compiled.
+func javacClasses(t *testing.T, release int) map[string][]byte {
+	if _, err := os.Stat(javacDir); os.IsNotExist(err) {
+		t.Skipf("%s is missing, run go test -run TestJavacClasses -compile-javac with a JDK 17 or later", javacDir)
+	}
+	files, err := filepath.Glob(filepath.Join(javacDir, fmt.Sprint(release), "corpus", "*.class"))
+	assert.Nil(t, err)
+	if len(files) == 0 {
+		t.Fatalf("No classes compiled for release %d, run go test -run TestJavacClasses -compile-javac", release)
+	}
+	classes := make(map[string][]byte)
+	for _, file := range files {
+		classes[filepath.Base(file)] = readTestBytes(t, file)
+	}
+	return classes
+}
+
+func TestJavacClasses(t *testing.T) {
+	if *compileJavac {
+		compileCorpus(t)
+	}
+	for release := range javacReleases {
+		classes := javacClasses(t, release)
+		for _, name := range []string{"Nested$Inner.class", "Nested$1.class", "Nested$1Prefixer.class", "Colors$1.class",
+			"Switches$1.class", "Marker.class"} {
+			assert.Contains(t, classes, name, "release %d", release)
+		}
+		for name, b := range classes {
+			class, err := (&ClassReader{}).ReadClassBytes(b)
+			assert.Nil(t, err, name)
+			assert.Equal(t, ClassVersion(44+release), class.Version(), name)
+		}
+	}
+	classes := javacClasses(t, 17)
+	for _, name := range []string{"Point.class", "Shape.class", "Circle.class"} {
+		assert.Contains(t, classes, name)
+	}
+}
+
+// The classes compiled by javac go through a ClassWriter with a new pool, which must move the indexes of the
+// attributes it doesn't decode, e.g. InnerClasses.
+func TestJavacClassesAreRewritten(t *testing.T) {
+	for release := range javacReleases {
+		for name, b := range javacClasses(t, release) {
+			cw := NewClassWriter()
+			assert.Nil(t, (&ClassReader{}).AcceptBytes(b, cw), name)
+			rewritten, err := cw.Bytes()
+			assert.Nil(t, err, name)
+
+			expected, err := (&ClassReader{}).ReadClassBytes(b)
+			assert.Nil(t, err, name)
+			actual, err := (&ClassReader{}).ReadClassBytes(rewritten)
+			if !assert.Nil(t, err, name) {
+				continue
+			}
+			assert.Equal(t, expected.Name, actual.Name, name)
+			assert.Equal(t, expected.Annotations, actual.Annotations, name)
+			assert.Equal(t, len(expected.Methods), len(actual.Methods), name)
+			for i, method := range expected.Methods {
+				assert.Equal(t, method.Name, actual.Methods[i].Name, name)
+				assert.Equal(t, opcodeNames(method), opcodeNames(actual.Methods[i]), "%s %s", name, method.Name)
+			}
+			assert.Equal(t, innerClassNames(t, b), innerClassNames(t, rewritten), name)
+		}
+	}
+}
+
+// Returns the names of the classes listed in the InnerClasses attribute of the class.
+func innerClassNames(t *testing.T, b []byte) []string {
+	registry := NewAttributeRegistry()
+	registry.Register("InnerClasses", entriesCodec(func(data []byte, pool ConstantPoolReader) []string {
+		var names []string
+		for i := 0; i < int(readUnsignedShort(data, 0)); i++ {
+			names = append(names, string(pool.Class(readUnsignedShort(data, 2+8*i))))
+		}
+		return names
+	}), ClassAttribute)
+	class, err := (&ClassReader{Attributes: registry}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	for _, attr := range class.Attributes {
+		if attr.Name == "InnerClasses" {
+			return attr.Value.([]string)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,6 @@
 package gytes
 
-// TODO: Might not need it, unless we will use it to do the label.
+// The opcodes of a method body, the positions in the body that instructions refer to are Labels.
 type BytesBlock struct {
 	Instructions []*ByteCode
 }
@@ -1,6 +1,9 @@
 package gytes
 
-import "io"
+import (
+	"fmt"
+	"io"
+)
 
 // A Java class file representation.
 // The class representation follows the definition found in the JVM specification
@@ -25,26 +28,63 @@ import "io"
 //   }
 //
 type JavaClass struct {
-	Name         string
+	Name         ClassName
 	PoolCount    uint16
 	CPool        ConstantPool
-	SuperName    string
+	SuperName    ClassName
 	MinorVersion uint16
 	MajorVersion uint16
-	Interfaces   []string
+	Interfaces   []ClassName
 	Fields       []JavaField
 	Methods      []JavaMethod
-	Access       uint16
+	Access       ClassAccess
+	Signature    string
 	SourceName   string
+	// The raw content of the SourceDebugExtension attribute, usually a JSR-45 SMAP
+	SourceDebug string
+	// The content of the NestHost and NestMembers attributes, a class only has one of them
+	NestHost    ClassName
+	NestMembers []ClassName
+	// The content of the PermittedSubclasses attribute of sealed classes
+	PermittedSubclasses []ClassName
+	// The content of the Record attribute, nil if the class is not a record
+	RecordComponents []RecordComponent
+	Annotations      []Annotation
+	// The attributes that are not decoded by gytes
+	Attributes []JAttribute
+	// The bytes of a class read with RoundTrip
+	original *originalClass
+	// The codecs of the custom attributes of a class that was read, used to write it back
+	attributes *AttributeRegistry
+	// The number of names tried by NewLambdaMethod
+	lambdas int
 }
 
+// A component of a record class
+//
+// record_component_info {
+//   u2             name_index;
+//   u2             descriptor_index;
+//   u2             attributes_count;
+//   attribute_info attributes[attributes_count];
+// }
+type RecordComponent struct {
+	Name        string
+	Descriptor  string
+	Signature   string
+	Annotations []Annotation
+	// The attributes that are not decoded by gytes
+	Attributes []JAttribute
+}
+
+// NewJavaClass creates an empty class, name can be given either in its binary or internal form.
 func NewJavaClass(name string) *JavaClass {
 	return &JavaClass{
-		Name:         name,
-		SuperName:    "java.lang.Object",
-		MinorVersion: MIN_VERSION,
+		Name:         NewClassName(name),
+		SuperName:    ObjectClassName,
+		MinorVersion: 0,
 		MajorVersion: MAJ_VERSION,
-		Interfaces:   make([]string, 0),
+		Interfaces:   make([]ClassName, 0),
 		Fields:       make([]JavaField, 0),
 		Methods:      make([]JavaMethod, 0),
 		Access:       0,
@@ -52,30 +92,206 @@ func NewJavaClass(name string) *JavaClass {
 }
 
 func (jc *JavaClass) SuperClass(className string) *JavaClass {
-	jc.SuperName = className
+	jc.SuperName = NewClassName(className)
 	return jc
 }
 
-func (jc *JavaClass) Visibility(accessMask uint16) *JavaClass {
+func (jc *JavaClass) Visibility(accessMask ClassAccess) *JavaClass {
 	jc.Access = accessMask
 	return jc
 }
 
 func (jc *JavaClass) Implements(interfaces []string) *JavaClass {
-	jc.Interfaces = interfaces
+	jc.Interfaces = make([]ClassName, len(interfaces))
+	for i, name := range interfaces {
+		jc.Interfaces[i] = NewClassName(name)
+	}
 	return jc
 }
 
+// AddFields appends fields to the fields of the class, see NewField to add a field checked against the class.
 func (jc *JavaClass) AddFields(fields []JavaField) *JavaClass {
-	jc.Fields = fields
+	jc.Fields = append(jc.Fields, fields...)
 	return jc
 }
 
+// AddMethods appends methods to the methods of the class, see NewMethod to add a method checked against the class.
 func (jc *JavaClass) AddMethods(methods []JavaMethod) *JavaClass {
-	jc.Methods = methods
+	jc.Methods = append(jc.Methods, methods...)
+	return jc
+}
+
+// Version returns the class file version of the class.
+func (jc *JavaClass) Version() ClassVersion {
+	return ClassVersion(jc.MajorVersion)
+}
+
+// IsPreview returns true if the class depends on the preview features of its JDK release.
+func (jc *JavaClass) IsPreview() bool {
+	return jc.MinorVersion == PreviewMinorVersion
+}
+
+// Target sets the class file version the class is written with.
+func (jc *JavaClass) Target(version ClassVersion) *JavaClass {
+	jc.MajorVersion = uint16(version)
+	jc.MinorVersion = 0
+	return jc
+}
+
+// TargetPreview sets the class file version the class is written with, and marks it as depending
+// on the preview features of that release.
+func (jc *JavaClass) TargetPreview(version ClassVersion) *JavaClass {
+	jc.MajorVersion = uint16(version)
+	jc.MinorVersion = PreviewMinorVersion
+	return jc
+}
+
+// Features returns the version dependent features used by the class.
+func (jc *JavaClass) Features() []Feature {
+	used := make(map[Feature]bool)
+	for _, tag := range jc.CPool.Tags {
+		switch tag {
+		case ConstMethodHandle, ConstMethodType:
+			used[FeatureMethodHandles] = true
+		case ConstInvokeDynamic:
+			used[FeatureInvokeDynamic] = true
+		case ConstDynamic:
+			used[FeatureConstantDynamic] = true
+		case ConstModule, ConstPackage:
+			used[FeatureModules] = true
+		}
+	}
+	for _, method := range jc.Methods {
+		for _, block := range method.Body {
+			for _, inst := range block.Instructions {
+				if inst.Name == "invokedynamic" {
+					used[FeatureInvokeDynamic] = true
+				}
+			}
+		}
+		if method.Instructions == nil {
+			continue
+		}
+		for n := method.Instructions.First(); n != nil; n = n.Next() {
+			switch n := n.(type) {
+			case *InvokeDynamicInsn:
+				used[FeatureInvokeDynamic] = true
+			case *LdcInsn:
+				switch n.Value.(type) {
+				case Handle, MethodType:
+					used[FeatureMethodHandles] = true
+				case ConstantDynamic:
+					used[FeatureConstantDynamic] = true
+				}
+			}
+		}
+	}
+	if jc.Access.Has(ACC_MODULE) {
+		used[FeatureModules] = true
+	}
+	if jc.NestHost != "" || len(jc.NestMembers) > 0 {
+		used[FeatureNestMates] = true
+	}
+	if jc.RecordComponents != nil {
+		used[FeatureRecords] = true
+	}
+	if len(jc.PermittedSubclasses) > 0 {
+		used[FeatureSealed] = true
+	}
+	features := make([]Feature, 0, len(used))
+	for f := FeatureInvokeDynamic; f <= FeatureSealed; f++ {
+		if used[f] {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// ValidateVersion checks that the class version is supported, and that all the features
+// used by the class are legal for that version.
+func (jc *JavaClass) ValidateVersion() error {
+	if err := checkVersion(jc.MajorVersion, jc.MinorVersion); err != nil {
+		return err
+	}
+	if jc.IsPreview() && jc.Version() < V12 {
+		return fmt.Errorf("%w: preview features require version %s or later", UnsupportedVersionError, V12)
+	}
+	for _, f := range jc.Features() {
+		if !jc.Version().Supports(f) {
+			return fmt.Errorf("%w: %s require version %s or later, the class targets %s",
+				UnsupportedVersionError, f, f.MinVersion(), jc.Version())
+		}
+	}
+	return nil
+}
+
+// ValidateAccess checks the access flags of the class and of all its members.
+func (jc *JavaClass) ValidateAccess() error {
+	if err := jc.Access.Validate(); err != nil {
+		return err
+	}
+	for _, field := range jc.Fields {
+		if err := field.Modifiers.Validate(jc.Access); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	for _, method := range jc.Methods {
+		if err := method.Modifiers.Validate(jc.Access, method.Name); err != nil {
+			return fmt.Errorf("method %s%s: %w", method.Name, method.Descriptor, err)
+		}
+	}
+	return nil
+}
+
+// SourceMap parses the SMAP stored in the SourceDebugExtension attribute of the class.
+// It returns nil if the class does not carry any debug extension.
+func (jc *JavaClass) SourceMap() (*SourceMap, error) {
+	if jc.SourceDebug == "" {
+		return nil, nil
+	}
+	return ParseSourceMap(jc.SourceDebug)
+}
+
+// SetSourceMap attaches a generated SMAP to the class, it's written as the SourceDebugExtension attribute.
+func (jc *JavaClass) SetSourceMap(smap *SourceMap) *JavaClass {
+	if smap == nil {
+		jc.SourceDebug = ""
+	} else {
+		jc.SourceDebug = smap.String()
+	}
 	return jc
 }
 
-func (jc *JavaClass) Write(writer io.Writer) {
+// Write checks the version of the class and writes it to writer in the class file format.
+// The constant pool of a class that was read is kept, the entries needed by the changes made to the class
+// are added after the original ones. The unchanged parts of a class read with RoundTrip are written as they were read.
+// The custom attributes are encoded with the codecs of the ClassReader the class was read by.
+// The MaxStack and MaxLocals of the methods are raised when their code needs more.
+func (jc *JavaClass) Write(writer io.Writer) error {
+	return jc.WriteWith(writer, 0, nil)
+}
+
+// WriteWith writes the class like Write, computing the maxs or the frames of its methods as options say,
+// e.g. for the code generated with a CodeBuilder. hierarchy is used to compute the frames, see ClassWriter.
+func (jc *JavaClass) WriteWith(writer io.Writer, options WriteOptions, hierarchy ClassHierarchy) error {
+	if err := jc.ValidateVersion(); err != nil {
+		return err
+	}
+	pool, err := NewConstantPoolBuilderFrom(jc.CPool)
+	if err != nil {
+		return err
+	}
+	cw := NewClassWriterWithPool(pool)
+	cw.Options, cw.Hierarchy, cw.original = options, hierarchy, jc.original
+	cw.Attributes, cw.raiseMaxs = jc.attributes, true
+	if err := jc.Accept(cw); err != nil {
+		return err
+	}
+	b, err := cw.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(b)
+	return err
 }
 
@@ -5,7 +5,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
+	"math"
+	"sort"
 )
 
 // JClassReader is the main type to read a classfile into a `JavaClass` type.
@@ -13,139 +14,1317 @@ type JClassReader interface {
 	ReadClass(reader io.Reader) (*JavaClass, error)
 }
 
+// ReadOptions control which parts of a class are decoded by the reader, they can be combined.
+type ReadOptions uint
+
+const (
+	// Don't decode the code of the methods, VisitCode is never called
+	SkipCode ReadOptions = 1 << iota
+	// Don't decode the debug information: the SourceFile, SourceDebugExtension, LineNumberTable,
+	// LocalVariableTable, LocalVariableTypeTable and MethodParameters attributes
+	SkipDebug
+	// Don't decode the StackMapTable of the methods
+	SkipFrames
+	// Visit the stack map frames expanded, i.e. as FrameNew frames holding all the locals and stack values
+	ExpandFrames
+	// Don't decode the annotations, attributes and code of the fields and methods until they are loaded,
+	// see JavaMethod.Load. Only used when reading a JavaClass, visitors always see the whole class
+	LazyMembers
+	// Keep the bytes of the class, so that writing the JavaClass copies the parts that were not changed as they
+	// were read: the header, each field and method, and the class attributes. An unchanged class is written back
+	// byte for byte, along with the parts the other options skip. LazyMembers is ignored, as the members are
+	// compared with the content they were read with
+	RoundTrip
+)
+
 // Component that is responsible of reading a sequence of bytes
 // provides by a io.Reader, and converting it into a class representation
 // in memory according to the spec defined in the JVM spec:
 // https://docs.oracle.com/javase/specs/jvms/se8/html/jvms-4.html#jvms-4.10.2.2
+//
+// A ClassReader only holds its configuration, all the state needed to parse a class is local to
+// each call, so a single reader can be used concurrently by multiple goroutines.
 type ClassReader struct {
-	CurrentIndex int
+	Options ReadOptions
+	// Read class files whose version is not supported instead of failing
+	AllowUnsupportedVersions bool
+	// Skip the malformed attributes, members and method bodies instead of failing, they are reported to Diagnostics
+	// as SkippedContent. Classes whose constant pool, header or layout are malformed still fail to be read
+	Lenient bool
+	// Receives the issues found while reading that don't prevent reading the classes, can be nil
+	Diagnostics DiagnosticHandler
+	// Fail to read classes that go over these limits
+	Limits Limits
+	// The codecs of the custom attributes to decode, the DefaultAttributeRegistry is used if it's nil
+	Attributes *AttributeRegistry
+}
+
+// The state of the parsing of a single class
+type classParser struct {
+	options                  ReadOptions
+	allowUnsupportedVersions bool
+	lenient                  bool
+	diagnostics              DiagnosticHandler
+	limits                   Limits
+	attributes               *AttributeRegistry
+	// The name and access flags of the class, once its header is read
+	name   ClassName
+	access ClassAccess
 	// Contains next entry index in the class's Constant pool
-	PoolItems []int
+	poolItems []int
 	// Cache for the stings found in the ConstantPool
-	PoolStr []string
+	poolStr []string
 	// Pointer to the offset where the class header starts
-	HeadStart int
+	headStart int
+	// The offsets of the entries of the BootstrapMethods attribute, and the content of the attribute
+	bootstrapMethods   []int
+	bootstrapAttribute []byte
+	// Set when the members of the class being read are decoded lazily
+	lazy *lazyClass
+	// Set when a part of the class was skipped in lenient mode
+	skipped bool
+	// The pool of the raw attributes holding pool indexes, created with the first of them
+	attributePool *attributePool
+	// The number of calls to the visitors of the caller that haven't returned, see guardClass
+	visiting int
+}
+
+func (c *ClassReader) newParser() *classParser {
+	attributes := c.Attributes
+	if attributes == nil {
+		attributes = DefaultAttributeRegistry
+	}
+	return &classParser{
+		options:                  c.Options,
+		allowUnsupportedVersions: c.AllowUnsupportedVersions,
+		lenient:                  c.Lenient,
+		diagnostics:              c.Diagnostics,
+		limits:                   c.Limits,
+		attributes:               attributes,
+	}
+}
+
+func (c *classParser) report(kind DiagnosticKind, offset int, format string, args ...interface{}) {
+	if c.diagnostics != nil {
+		c.diagnostics.Handle(Diagnostic{Kind: kind, Class: c.name, Offset: offset, Message: fmt.Sprintf(format, args...)})
+	}
 }
 
+func (c *classParser) checkAttributeLength(name string, length int) error {
+	return checkLimit("length of attribute "+name, length, c.limits.MaxAttributeLength)
+}
+
+// Returns the attribute starting at offset, decoded by its codec if one is registered for it at the given location.
+// The attributes that are neither standard nor registered are reported.
+func (c *classParser) attribute(b []byte, offset int, name string, length int, location AttributeLocation) (JAttribute, error) {
+	attr := JAttribute{Name: name, Data: b[offset+6 : offset+6+length]}
+	codec, ok := c.attributes.Codec(name, location)
+	if !ok {
+		if !standardAttributes[name] {
+			c.report(UnknownAttribute, offset, "%s", name)
+		}
+		if poolAttributes[name] != nil {
+			if c.attributePool == nil {
+				c.attributePool = &attributePool{bytes: b, items: c.poolItems}
+			}
+			attr.pool = c.attributePool
+		}
+		return attr, nil
+	}
+	value, err := codec.Decode(attr.Data, parserPool{c, b})
+	if err != nil {
+		return attr, fmt.Errorf("Could not decode %s attribute %s at offset %d: %w", location, name, offset, err)
+	}
+	attr.Value = value
+	return attr, nil
+}
+
+// The ConstantPoolReader given to the attribute codecs
+type parserPool struct {
+	parser *classParser
+	bytes  []byte
+}
+
+func (p parserPool) Utf8(index uint16) string {
+	return p.parser.readUtf8(p.bytes, index)
+}
+
+func (p parserPool) Class(index uint16) ClassName {
+	return ClassName(p.parser.readStr(p.bytes, p.parser.poolItems[index]))
+}
+
+func (p parserPool) Constant(index uint16) interface{} {
+	return p.parser.readConst(p.bytes, index)
+}
+
+// ReadClass reads the whole class into a JavaClass.
 func (c *ClassReader) ReadClass(reader io.Reader) (*JavaClass, error) {
-	bytes, err := ioutil.ReadAll(reader)
+	bytes, err := c.readAll(reader)
 	if err != nil {
 		return nil, err
 	}
-	magic := readMagic(bytes)
-	if magic != MAGIC {
-		return nil, errors.New(fmt.Sprintf("Invalid class file, expected magic bit found %v", magic))
+	return c.ReadClassBytes(bytes)
+}
+
+// ReadClassBytes reads the class held by bytes into a JavaClass.
+// The bytes are not copied: the data of the undecoded attributes points into them, and so do the members
+// read with LazyMembers until they are loaded, so they must not be modified while the class is in use.
+func (c *ClassReader) ReadClassBytes(bytes []byte) (*JavaClass, error) {
+	collector := newClassCollector()
+	parser := c.newParser()
+	if c.Options&LazyMembers != 0 && c.Options&RoundTrip == 0 {
+		parser.lazy = &lazyClass{parser: parser, bytes: bytes}
+	}
+	if err := parser.accept(bytes, collector); err != nil {
+		return nil, err
+	}
+	jclass := collector.class
+	jclass.attributes = c.Attributes
+	jclass.PoolCount = uint16(len(parser.poolItems))
+	jclass.CPool = ConstantPool{
+		Size:             jclass.PoolCount,
+		Tags:             make([]uint8, jclass.PoolCount),
+		entries:          bytes[10:parser.headStart],
+		bootstrapMethods: parser.bootstrapAttribute,
 	}
-	jclass := &JavaClass{}
-	jclass.MinorVersion = readUnsignedShort(bytes, 4)
-	jclass.MajorVersion = readUnsignedShort(bytes, 6)
-	jclass.PoolCount = readUnsignedShort(bytes, 8)
+	for i, offset := range parser.poolItems {
+		if offset > 0 {
+			jclass.CPool.Tags[i] = bytes[offset-1]
+		}
+	}
+	if c.Options&RoundTrip != 0 {
+		jclass.original = c.original(bytes, parser, jclass)
+	}
+	return jclass, nil
+}
 
-	if err := c.fillPoolItems(bytes, jclass.PoolCount); err != nil {
+// ReadClassAt reads the class held by the first size bytes of reader into a JavaClass,
+// e.g. an entry of a memory mapped jar.
+func (c *ClassReader) ReadClassAt(reader io.ReaderAt, size int64) (*JavaClass, error) {
+	bytes, err := c.readAllAt(reader, size)
+	if err != nil {
 		return nil, err
 	}
+	return c.ReadClassBytes(bytes)
+}
+
+// Accept reads the class and sends its content to the given visitor, without building a JavaClass.
+func (c *ClassReader) Accept(reader io.Reader, visitor ClassVisitor) error {
+	bytes, err := c.readAll(reader)
+	if err != nil {
+		return err
+	}
+	return c.AcceptBytes(bytes, visitor)
+}
+
+// AcceptBytes sends the content of the class held by bytes to the given visitor, whose panics are propagated.
+func (c *ClassReader) AcceptBytes(bytes []byte, visitor ClassVisitor) error {
+	parser := c.newParser()
+	return parser.accept(bytes, guardClass(parser, visitor))
+}
+
+// AcceptAt sends the content of the class held by the first size bytes of reader to the given visitor.
+func (c *ClassReader) AcceptAt(reader io.ReaderAt, size int64, visitor ClassVisitor) error {
+	bytes, err := c.readAllAt(reader, size)
+	if err != nil {
+		return err
+	}
+	return c.AcceptBytes(bytes, visitor)
+}
+
+func (c *ClassReader) readAll(reader io.Reader) ([]byte, error) {
+	if c.Limits.MaxClassSize == 0 {
+		return ioutil.ReadAll(reader)
+	}
+	// Read one more byte than the limit to tell whether the class is over it
+	bytes, err := ioutil.ReadAll(io.LimitReader(reader, int64(c.Limits.MaxClassSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes) > c.Limits.MaxClassSize {
+		return nil, fmt.Errorf("%w: class size is over %d bytes", LimitExceededError, c.Limits.MaxClassSize)
+	}
+	return bytes, nil
+}
+
+func (c *ClassReader) readAllAt(reader io.ReaderAt, size int64) ([]byte, error) {
+	if c.Limits.MaxClassSize > 0 && size > int64(c.Limits.MaxClassSize) {
+		return nil, fmt.Errorf("%w: class size is %d, the limit is %d", LimitExceededError, size, c.Limits.MaxClassSize)
+	}
+	bytes := make([]byte, size)
+	n, err := reader.ReadAt(bytes, 0)
+	// ReadAt may return io.EOF along with the last bytes of the input
+	if n == len(bytes) {
+		return bytes, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+func (c *classParser) accept(bytes []byte, cv ClassVisitor) error {
+	return c.protect(0, "class", func() error {
+		return c.visitClass(bytes, cv)
+	})
+}
 
-	jclass.Access = readUnsignedShort(bytes, c.HeadStart)
-	jclass.Name = c.readClass(bytes, c.HeadStart+2)
-	jclass.SuperName = c.readClass(bytes, c.HeadStart+4)
-	interfaceCount := int(readUnsignedShort(bytes, c.HeadStart+6))
-	jclass.Interfaces = make([]string, interfaceCount)
-	cur := c.HeadStart + 8
-	for i := 0; i < interfaceCount; i++ {
-		jclass.Interfaces[i] = c.readClass(bytes, cur)
-		cur += 2
+func (c *classParser) visitClass(bytes []byte, cv ClassVisitor) error {
+	header, err := c.readHeader(bytes)
+	if err != nil {
+		return err
+	}
+	majorVersion, minorVersion := header.Version, header.MinorVersion
+	access, name, superName, interfaces := header.Access, header.Name, header.SuperName, header.Interfaces
+	fieldsStart := c.headStart + 8 + 2*len(interfaces)
+	// The class attributes come after the fields and the methods, but they are needed
+	// before visiting them, e.g. the bootstrap methods are used by invokedynamic instructions
+	if err := checkLimit("fields count", int(readUnsignedShort(bytes, fieldsStart)), c.limits.MaxMembers); err != nil {
+		return err
 	}
-	f := cur
-	// Skip fields
+	methodsStart := skipMembers(bytes, fieldsStart)
+	if err := checkLimit("methods count", int(readUnsignedShort(bytes, methodsStart)), c.limits.MaxMembers); err != nil {
+		return err
+	}
+	m := skipMembers(bytes, methodsStart)
+
+	var signature, sourceName, sourceDebug string
+	var nestHost ClassName
+	var nestMembers, permittedSubclasses []ClassName
+	visibleAnnotations, invisibleAnnotations, record := 0, 0, 0
+	attributes := make([]JAttribute, 0)
+	c.bootstrapMethods, c.bootstrapAttribute = nil, nil
+	attrCount := int(readUnsignedShort(bytes, m))
+	m += 2
+	for i := 0; i < attrCount; i++ {
+		attrName := c.readStr(bytes, m)
+		attrLen := int(readInt(bytes, m+2))
+		if err := c.checkAttributeLength(attrName, attrLen); err != nil {
+			return err
+		}
+		m += 6
+		_, err := c.skippable(m-6, "attribute "+attrName, func() error {
+			switch attrName {
+			case "SourceFile":
+				if c.options&SkipDebug == 0 {
+					sourceName = c.readStr(bytes, m)
+				}
+			case "SourceDebugExtension":
+				if c.options&SkipDebug == 0 {
+					sourceDebug = string(bytes[m : m+attrLen])
+				}
+			case "Signature":
+				signature = c.readStr(bytes, m)
+			case "Synthetic":
+				access |= ACC_SYNTHETIC
+			case "NestHost":
+				nestHost = c.readClass(bytes, m)
+			case "NestMembers":
+				nestMembers = c.readClasses(bytes, m)
+			case "PermittedSubclasses":
+				permittedSubclasses = c.readClasses(bytes, m)
+			case "Record":
+				record = m
+			case "RuntimeVisibleAnnotations":
+				visibleAnnotations = m
+			case "RuntimeInvisibleAnnotations":
+				invisibleAnnotations = m
+			case "BootstrapMethods":
+				count := int(readUnsignedShort(bytes, m))
+				c.bootstrapAttribute = bytes[m : m+attrLen]
+				c.bootstrapMethods = make([]int, count)
+				b := m + 2
+				for j := 0; j < count; j++ {
+					c.bootstrapMethods[j] = b
+					b += 4 + 2*int(readUnsignedShort(bytes, b+2))
+				}
+			default:
+				attr, err := c.attribute(bytes, m-6, attrName, attrLen, ClassAttribute)
+				if err != nil {
+					return err
+				}
+				attributes = append(attributes, attr)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		m += attrLen
+	}
+
+	cv.Visit(majorVersion, minorVersion, access, name, signature, superName, interfaces)
+	if sourceName != "" || sourceDebug != "" {
+		cv.VisitSource(sourceName, sourceDebug)
+	}
+	if nestHost != "" {
+		cv.VisitNestHost(nestHost)
+	}
+	if visibleAnnotations, err = c.checkAnnotations(bytes, visibleAnnotations, "class annotations"); err != nil {
+		return err
+	}
+	if invisibleAnnotations, err = c.checkAnnotations(bytes, invisibleAnnotations, "class annotations"); err != nil {
+		return err
+	}
+	if err := c.readAnnotations(bytes, visibleAnnotations, true, cv.VisitAnnotation); err != nil {
+		return err
+	}
+	if err := c.readAnnotations(bytes, invisibleAnnotations, false, cv.VisitAnnotation); err != nil {
+		return err
+	}
+	for _, attr := range attributes {
+		cv.VisitAttribute(attr)
+	}
+	for _, member := range nestMembers {
+		cv.VisitNestMember(member)
+	}
+	for _, subclass := range permittedSubclasses {
+		cv.VisitPermittedSubclass(subclass)
+	}
+	if record, err = c.check(record, "attribute Record", func() error {
+		return c.readRecord(bytes, record, func(name, descriptor, signature string) RecordComponentVisitor {
+			return &RecordComponentVisitorAdapter{}
+		})
+	}); err != nil {
+		return err
+	}
+	if record > 0 {
+		if err := c.readRecord(bytes, record, cv.VisitRecordComponent); err != nil {
+			return err
+		}
+	}
+
+	f := fieldsStart
 	fieldsCount := int(readUnsignedShort(bytes, f))
 	f += 2
-	jclass.Fields = make([]JavaField, fieldsCount)
 	for i := 0; i < fieldsCount; i++ {
-		jclass.Fields[i].Modifiers = readUnsignedShort(bytes, f)
-		jclass.Fields[i].Name = c.readStr(bytes, f+2)
-		jclass.Fields[i].Descriptor = c.readStr(bytes, f+4)
-		attrCount := int(readUnsignedShort(bytes, f+6))
-		// Skip access_flags, name, descriptor, and count
-		f += 8
-		// skip attributes
-		for ; attrCount > 0; attrCount-- {
-			attrName := c.readStr(bytes, f)
-			// TODO: handle more attributes
-			if attrName == "Synthetic" {
-				jclass.Fields[i].Modifiers |= ACC_SYNTHETIC
-			}
-			attrLen := int(readInt(bytes, f+2))
-			f += 6 + attrLen
+		field := f
+		_, err := c.skippable(field, "field", func() error {
+			_, err := c.readField(bytes, field, cv)
+			return err
+		})
+		if err != nil {
+			return err
 		}
+		f = memberEnd(bytes, f)
 	}
-	// Skip methods
-	m := f
+	m = methodsStart
 	methCount := int(readUnsignedShort(bytes, m))
-	jclass.Methods = make([]JavaMethod, methCount)
 	m += 2
 	for i := 0; i < methCount; i++ {
-		jclass.Methods[i].Modifiers = readUnsignedShort(bytes, m)
-		jclass.Methods[i].Name = c.readStr(bytes, m+2)
-		jclass.Methods[i].Descriptor = c.readStr(bytes, m+4)
-		attrCount := int(readUnsignedShort(bytes, m+6))
-		m += 8
+		method := m
+		_, err := c.skippable(method, "method", func() error {
+			_, err := c.readMethod(bytes, method, name, cv)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		m = memberEnd(bytes, m)
+	}
+	cv.VisitEnd()
+	return nil
+}
+
+// Reads the components of the Record attribute starting at offset.
+//
+// Record_attribute {
+//   u2                    attribute_name_index;
+//   u4                    attribute_length;
+//   u2                    components_count;
+//   record_component_info components[components_count];
+// }
+func (c *classParser) readRecord(b []byte, offset int, visit func(name, descriptor, signature string) RecordComponentVisitor) error {
+	count := int(readUnsignedShort(b, offset))
+	r := offset + 2
+	for j := 0; j < count; j++ {
+		name := c.readStr(b, r)
+		descriptor := c.readStr(b, r+2)
+		attrCount := int(readUnsignedShort(b, r+4))
+		r += 6
+		var signature string
+		visibleAnnotations, invisibleAnnotations := 0, 0
+		attributes := make([]JAttribute, 0)
 		for ; attrCount > 0; attrCount-- {
-			attrName := c.readStr(bytes, m)
-			attrLen := int(readInt(bytes, m+2))
-			m += 6
-			// TODO: handle more attributes
-			log.Printf("Found attribute %s with length %d\n", attrName, attrLen)
-			if attrName == "Synthetic" {
-				jclass.Methods[i].Modifiers |= ACC_SYNTHETIC
-			} else if attrName == "Code" {
-				jclass.Methods[i].BodyOffset = m
-				jclass.Methods[i].MaxStack = readUnsignedShort(bytes, m)
-				jclass.Methods[i].MaxLocals = readUnsignedShort(bytes, m+2)
-				codeLen := readUnsignedInt(bytes, m+4)
-				body, err := readCode(bytes, m+8, codeLen)
+			attrName := c.readStr(b, r)
+			attrLen := int(readInt(b, r+2))
+			if err := c.checkAttributeLength(attrName, attrLen); err != nil {
+				return err
+			}
+			r += 6
+			switch attrName {
+			case "Signature":
+				signature = c.readStr(b, r)
+			case "RuntimeVisibleAnnotations":
+				visibleAnnotations = r
+			case "RuntimeInvisibleAnnotations":
+				invisibleAnnotations = r
+			default:
+				attr, err := c.attribute(b, r-6, attrName, attrLen, RecordComponentAttribute)
 				if err != nil {
-					return nil, err
+					return err
 				}
-				jclass.Methods[i].Body = body
-				// at := m + 8 + int(codeLen)
-				// exceptionTableLen := readUnsignedInt(bytes, at)
-			} else if attrName == "Exceptions" {
-				exCount := readUnsignedShort(bytes, m)
-				end := m + 2 + 2*int(exCount)
-				jclass.Methods[i].Exceptions = make([]string, 0)
-				for o := m + 2; o < end; o = o + 2 {
-					// The exception should be a fully qualified class name
-					ex := c.readClass(bytes, o)
-					jclass.Methods[i].Exceptions = append(jclass.Methods[i].Exceptions, ex)
+				attributes = append(attributes, attr)
+			}
+			r += attrLen
+		}
+		rv := visit(name, descriptor, signature)
+		if rv == nil {
+			continue
+		}
+		if err := c.readAnnotations(b, visibleAnnotations, true, rv.VisitAnnotation); err != nil {
+			return err
+		}
+		if err := c.readAnnotations(b, invisibleAnnotations, false, rv.VisitAnnotation); err != nil {
+			return err
+		}
+		for _, attr := range attributes {
+			rv.VisitAttribute(attr)
+		}
+		rv.VisitEnd()
+	}
+	return nil
+}
+
+// Returns the offset following the fields or methods starting at offset.
+func skipMembers(b []byte, offset int) int {
+	count := int(readUnsignedShort(b, offset))
+	offset += 2
+	for i := 0; i < count; i++ {
+		offset = memberEnd(b, offset)
+	}
+	return offset
+}
+
+// Returns the offset following the field or method starting at offset.
+func memberEnd(b []byte, offset int) int {
+	return attributesEnd(b, offset+6)
+}
+
+// Returns the offset following the attributes_count and attributes starting at offset.
+func attributesEnd(b []byte, offset int) int {
+	attrCount := int(readUnsignedShort(b, offset))
+	offset += 2
+	for ; attrCount > 0; attrCount-- {
+		offset += 6 + int(readInt(b, offset+2))
+	}
+	return offset
+}
+
+// In lenient mode, drops the annotations of a member that can't be read.
+func (c *classParser) checkMemberAnnotations(b []byte, content *memberAttributes) error {
+	var err error
+	if content.visibleAnnotations, err = c.checkAnnotations(b, content.visibleAnnotations, "annotations of "+content.member); err != nil {
+		return err
+	}
+	content.invisibleAnnotations, err = c.checkAnnotations(b, content.invisibleAnnotations, "annotations of "+content.member)
+	return err
+}
+
+// In lenient mode, returns the offset of the annotations starting at offset if they can be read, 0 otherwise.
+func (c *classParser) checkAnnotations(b []byte, offset int, what string) (int, error) {
+	return c.check(offset, what, func() error {
+		return c.readAnnotations(b, offset, true, func(string, bool) AnnotationVisitor {
+			return &AnnotationVisitorAdapter{}
+		})
+	})
+}
+
+// The offsets of the attributes of a field or a method whose content is visited after the member itself,
+// they are kept around when the member is decoded lazily.
+type memberAttributes struct {
+	// The offset and the name and descriptor of the member, used to report issues
+	offset               int
+	member               string
+	visibleAnnotations   int
+	invisibleAnnotations int
+	code                 int
+	attributes           []JAttribute
+}
+
+// Reads the field starting at f and returns the offset of the next field.
+func (c *classParser) readField(bytes []byte, f int, cv ClassVisitor) (int, error) {
+	access := FieldAccess(readUnsignedShort(bytes, f))
+	if err := access.Validate(c.access); err != nil {
+		c.report(SuspiciousFlags, f, "%v", err)
+	}
+	name := c.readStr(bytes, f+2)
+	descriptor := c.readStr(bytes, f+4)
+	attrCount := int(readUnsignedShort(bytes, f+6))
+	// Skip access_flags, name, descriptor, and count
+	f += 8
+	var signature string
+	var value interface{}
+	content := memberAttributes{offset: f - 8, member: name + " " + descriptor, attributes: make([]JAttribute, 0)}
+	for ; attrCount > 0; attrCount-- {
+		attrName := c.readStr(bytes, f)
+		attrLen := int(readInt(bytes, f+2))
+		if err := c.checkAttributeLength(attrName, attrLen); err != nil {
+			return f, err
+		}
+		f += 6
+		_, err := c.skippable(f-6, "attribute "+attrName+" of "+content.member, func() error {
+			switch attrName {
+			case "Synthetic":
+				access |= ACC_SYNTHETIC
+			case "ConstantValue":
+				value = c.readConst(bytes, readUnsignedShort(bytes, f))
+			case "Signature":
+				signature = c.readStr(bytes, f)
+			case "RuntimeVisibleAnnotations":
+				content.visibleAnnotations = f
+			case "RuntimeInvisibleAnnotations":
+				content.invisibleAnnotations = f
+			default:
+				attr, err := c.attribute(bytes, f-6, attrName, attrLen, FieldAttribute)
+				if err != nil {
+					return err
 				}
+				content.attributes = append(content.attributes, attr)
 			}
-			m += attrLen
+			return nil
+		})
+		if err != nil {
+			return f, err
 		}
+		f += attrLen
 	}
-	attrCount := int(readUnsignedShort(bytes, m))
-	m += 2
-	for i := 0; i < attrCount; i++ {
+	fv := cv.VisitField(access, name, descriptor, signature, value)
+	if fv == nil {
+		return f, nil
+	}
+	if collector, ok := fv.(*fieldCollector); ok && c.lazy != nil {
+		collector.field().lazy = &lazyMember{class: c.lazy, content: content}
+		return f, nil
+	}
+	return f, c.readFieldContent(bytes, content, fv)
+}
+
+func (c *classParser) readFieldContent(bytes []byte, content memberAttributes, fv FieldVisitor) error {
+	if err := c.checkMemberAnnotations(bytes, &content); err != nil {
+		return err
+	}
+	if err := c.readAnnotations(bytes, content.visibleAnnotations, true, fv.VisitAnnotation); err != nil {
+		return err
+	}
+	if err := c.readAnnotations(bytes, content.invisibleAnnotations, false, fv.VisitAnnotation); err != nil {
+		return err
+	}
+	for _, attr := range content.attributes {
+		fv.VisitAttribute(attr)
+	}
+	fv.VisitEnd()
+	return nil
+}
+
+// Reads the method starting at m and returns the offset of the next method.
+func (c *classParser) readMethod(bytes []byte, m int, owner ClassName, cv ClassVisitor) (int, error) {
+	access := MethodAccess(readUnsignedShort(bytes, m))
+	name := c.readStr(bytes, m+2)
+	if err := access.Validate(c.access, name); err != nil {
+		c.report(SuspiciousFlags, m, "%v", err)
+	}
+	descriptor := c.readStr(bytes, m+4)
+	attrCount := int(readUnsignedShort(bytes, m+6))
+	m += 8
+	var signature string
+	var exceptions []ClassName
+	content := memberAttributes{offset: m - 8, member: name + descriptor, attributes: make([]JAttribute, 0)}
+	for ; attrCount > 0; attrCount-- {
 		attrName := c.readStr(bytes, m)
-		// TODO: handle more attributes
-		if attrName == "SourceFile" {
-			jclass.SourceName = c.readStr(bytes, m+6)
-		}
 		attrLen := int(readInt(bytes, m+2))
-		m += 6 + attrLen
+		if err := c.checkAttributeLength(attrName, attrLen); err != nil {
+			return m, err
+		}
+		m += 6
+		_, err := c.skippable(m-6, "attribute "+attrName+" of "+content.member, func() error {
+			switch attrName {
+			case "Synthetic":
+				access |= ACC_SYNTHETIC
+			case "Code":
+				content.code = m
+			case "Exceptions":
+				// The exceptions should be fully qualified class names
+				exceptions = c.readClasses(bytes, m)
+			case "Signature":
+				signature = c.readStr(bytes, m)
+			case "RuntimeVisibleAnnotations":
+				content.visibleAnnotations = m
+			case "RuntimeInvisibleAnnotations":
+				content.invisibleAnnotations = m
+			case "MethodParameters":
+				if c.options&SkipDebug == 0 {
+					attr, err := c.attribute(bytes, m-6, attrName, attrLen, MethodAttribute)
+					if err != nil {
+						return err
+					}
+					content.attributes = append(content.attributes, attr)
+				}
+			default:
+				attr, err := c.attribute(bytes, m-6, attrName, attrLen, MethodAttribute)
+				if err != nil {
+					return err
+				}
+				content.attributes = append(content.attributes, attr)
+			}
+			return nil
+		})
+		if err != nil {
+			return m, err
+		}
+		m += attrLen
+	}
+	mv := cv.VisitMethod(access, name, descriptor, signature, exceptions)
+	if mv == nil {
+		return m, nil
+	}
+	frame := frameContext{owner: owner, access: access, name: name, descriptor: descriptor}
+	if collector, ok := mv.(*methodCollector); ok && c.lazy != nil {
+		method := collector.method()
+		if c.options&SkipCode == 0 {
+			method.BodyOffset = content.code
+		}
+		method.lazy = &lazyMember{class: c.lazy, content: content, frame: frame}
+		return m, nil
+	}
+	return m, c.readMethodContent(bytes, content, frame, mv)
+}
+
+func (c *classParser) readMethodContent(bytes []byte, content memberAttributes, frame frameContext, mv MethodVisitor) error {
+	if err := c.checkMemberAnnotations(bytes, &content); err != nil {
+		return err
+	}
+	if c.options&SkipCode == 0 {
+		var err error
+		if content.code, err = c.check(content.code, "code of "+content.member, func() error {
+			return c.readCode(bytes, content.code, frame, &CodeVisitorAdapter{})
+		}); err != nil {
+			return err
+		}
+	}
+	if err := c.readAnnotations(bytes, content.visibleAnnotations, true, mv.VisitAnnotation); err != nil {
+		return err
+	}
+	if err := c.readAnnotations(bytes, content.invisibleAnnotations, false, mv.VisitAnnotation); err != nil {
+		return err
+	}
+	for _, attr := range content.attributes {
+		mv.VisitAttribute(attr)
+	}
+	if content.code > 0 && c.options&SkipCode == 0 {
+		if codeVisitor := mv.VisitCode(); codeVisitor != nil {
+			if collector, ok := codeVisitor.(*codeCollector); ok {
+				collector.method.BodyOffset = content.code
+			}
+			if err := c.readCode(bytes, content.code, frame, codeVisitor); err != nil {
+				return err
+			}
+		}
+	}
+	mv.VisitEnd()
+	return nil
+}
+
+// Reads the Code attribute starting at offset
+//
+// Code_attribute {
+//   u2 attribute_name_index;
+//   u4 attribute_length;
+//   u2 max_stack;
+//   u2 max_locals;
+//   u4 code_length;
+//   u1 code[code_length];
+//   u2 exception_table_length;
+//   {   u2 start_pc;
+//       u2 end_pc;
+//       u2 handler_pc;
+//       u2 catch_type;
+//   } exception_table[exception_table_length];
+//   u2 attributes_count;
+//   attribute_info attributes[attributes_count];
+// }
+func (c *classParser) readCode(b []byte, offset int, method frameContext, cv CodeVisitor) error {
+	maxStack := int(readUnsignedShort(b, offset))
+	maxLocals := int(readUnsignedShort(b, offset+2))
+	codeLen := int(readUnsignedInt(b, offset+4))
+	if err := checkLimit("code length of "+method.name+method.descriptor, codeLen, c.limits.MaxCodeLength); err != nil {
+		return err
+	}
+	start := offset + 8
+	end := start + codeLen
+	labels := make(map[int]*Label)
+	label := func(offset int) *Label {
+		if l, ok := labels[offset]; ok {
+			return l
+		}
+		l := &Label{Offset: offset}
+		labels[offset] = l
+		return l
+	}
+
+	// Create the labels of all the jump targets, they must be visited before the instructions they mark
+	for p := start; p < end; {
+		op := int(b[p])
+		if op >= len(ByteCodes) {
+			return fmt.Errorf("%w %d at offset %d", UnknownByteCodeError, op, p-start)
+		}
+		pc := p - start
+		target := func(jump int) {
+			if pc+jump < 0 || pc+jump >= codeLen {
+				c.report(FormatIssue, p, "%s in %s%s jumps outside of the code to %d", ByteCodes[op].Name, method.name, method.descriptor, pc+jump)
+			}
+			label(pc + jump)
+		}
+		switch ByteCodes[op].Kind {
+		case KindJumpInsn:
+			if op == GOTO_W || op == JSR_W {
+				target(int(readInt(b, p+1)))
+			} else {
+				target(int(int16(readUnsignedShort(b, p+1))))
+			}
+		case KindTableSwitchInsn:
+			s := p + 1 + (3 - pc%4)
+			target(int(readInt(b, s)))
+			low, high := int(readInt(b, s+4)), int(readInt(b, s+8))
+			for i := 0; i <= high-low; i++ {
+				target(int(readInt(b, s+12+4*i)))
+			}
+		case KindLookupSwitchInsn:
+			s := p + 1 + (3 - pc%4)
+			target(int(readInt(b, s)))
+			pairs := int(readInt(b, s+4))
+			for i := 0; i < pairs; i++ {
+				target(int(readInt(b, s+12+8*i)))
+			}
+		}
+		p += instructionSize(b, p, start)
+	}
+
+	t := end
+	tryCatchCount := int(readUnsignedShort(b, t))
+	t += 2
+	for i := 0; i < tryCatchCount; i++ {
+		label(int(readUnsignedShort(b, t)))
+		label(int(readUnsignedShort(b, t+2)))
+		label(int(readUnsignedShort(b, t+4)))
+		t += 8
+	}
+
+	skipDebug := c.options&SkipDebug != 0
+	stackMap := 0
+	lines := make(map[int][]int)
+	localVariables, localVariableTypes := make([]int, 0), make(map[[2]int]int)
+	attributes := make([]JAttribute, 0)
+	a := t
+	attrCount := int(readUnsignedShort(b, a))
+	a += 2
+	for ; attrCount > 0; attrCount-- {
+		attrName := c.readStr(b, a)
+		attrLen := int(readInt(b, a+2))
+		if err := c.checkAttributeLength(attrName, attrLen); err != nil {
+			return err
+		}
+		a += 6
+		switch {
+		case skipDebug && (attrName == "LineNumberTable" || attrName == "LocalVariableTable" || attrName == "LocalVariableTypeTable"):
+		case attrName == "LineNumberTable":
+			count := int(readUnsignedShort(b, a))
+			for i := 0; i < count; i++ {
+				pc := int(readUnsignedShort(b, a+2+4*i))
+				label(pc)
+				lines[pc] = append(lines[pc], int(readUnsignedShort(b, a+4+4*i)))
+			}
+		case attrName == "LocalVariableTable":
+			count := int(readUnsignedShort(b, a))
+			for i := 0; i < count; i++ {
+				v := a + 2 + 10*i
+				pc := int(readUnsignedShort(b, v))
+				label(pc)
+				label(pc + int(readUnsignedShort(b, v+2)))
+				localVariables = append(localVariables, v)
+			}
+		case attrName == "LocalVariableTypeTable":
+			count := int(readUnsignedShort(b, a))
+			for i := 0; i < count; i++ {
+				v := a + 2 + 10*i
+				key := [2]int{int(readUnsignedShort(b, v)), int(readUnsignedShort(b, v+8))}
+				localVariableTypes[key] = v
+			}
+		case attrName == "StackMapTable":
+			if c.options&SkipFrames == 0 {
+				stackMap = a
+			}
+		default:
+			attr, err := c.attribute(b, a-6, attrName, attrLen, CodeAttribute)
+			if err != nil {
+				return err
+			}
+			attributes = append(attributes, attr)
+		}
+		a += attrLen
+	}
+
+	frames := make(map[int]Frame)
+	if stackMap > 0 {
+		var err error
+		if frames, err = c.readFrames(b, stackMap, method, label); err != nil {
+			return err
+		}
+	}
+
+	t = end + 2
+	for i := 0; i < tryCatchCount; i++ {
+		var typ ClassName
+		if readUnsignedShort(b, t+6) != 0 {
+			typ = c.readClass(b, t+6)
+		}
+		cv.VisitTryCatchBlock(label(int(readUnsignedShort(b, t))), label(int(readUnsignedShort(b, t+2))), label(int(readUnsignedShort(b, t+4))), typ)
+		t += 8
+	}
+
+	for p := start; p < end; {
+		pc := p - start
+		if l, ok := labels[pc]; ok {
+			cv.VisitLabel(l)
+			for _, line := range lines[pc] {
+				cv.VisitLineNumber(line, l)
+			}
+			if frame, ok := frames[pc]; ok {
+				cv.VisitFrame(frame)
+			}
+		}
+		c.visitInstruction(b, p, start, label, cv)
+		p += instructionSize(b, p, start)
+	}
+	if l, ok := labels[codeLen]; ok {
+		cv.VisitLabel(l)
+	}
+
+	for _, v := range localVariables {
+		pc := int(readUnsignedShort(b, v))
+		index := int(readUnsignedShort(b, v+8))
+		var signature string
+		if t, ok := localVariableTypes[[2]int{pc, index}]; ok {
+			signature = c.readStr(b, t+6)
+			delete(localVariableTypes, [2]int{pc, index})
+		}
+		cv.VisitLocalVariable(c.readStr(b, v+4), c.readStr(b, v+6), signature,
+			label(pc), label(pc+int(readUnsignedShort(b, v+2))), index)
+	}
+	orphanTypes := make([]int, 0, len(localVariableTypes))
+	for _, t := range localVariableTypes {
+		orphanTypes = append(orphanTypes, t)
+	}
+	sort.Ints(orphanTypes)
+	for _, t := range orphanTypes {
+		c.report(FormatIssue, t, "LocalVariableTypeTable entry of %s in %s%s has no LocalVariableTable entry",
+			c.readStr(b, t+4), method.name, method.descriptor)
+	}
+	for _, attr := range attributes {
+		cv.VisitAttribute(attr)
+	}
+	cv.VisitMaxs(maxStack, maxLocals)
+	cv.VisitEnd()
+	return nil
+}
+
+// The method whose frames are read, needed to compute the implicit first frame when expanding them
+type frameContext struct {
+	owner      ClassName
+	access     MethodAccess
+	name       string
+	descriptor string
+}
+
+// Reads the StackMapTable attribute starting at offset and returns the frames by bytecode offset.
+//
+// StackMapTable_attribute {
+//   u2              attribute_name_index;
+//   u4              attribute_length;
+//   u2              number_of_entries;
+//   stack_map_frame entries[number_of_entries];
+// }
+func (c *classParser) readFrames(b []byte, offset int, method frameContext, label func(int) *Label) (map[int]Frame, error) {
+	expand := c.options&ExpandFrames != 0
+	var locals []interface{}
+	if expand {
+		var err error
+		if locals, err = initialFrameLocals(method.owner, method.access, method.name, method.descriptor); err != nil {
+			return nil, err
+		}
+	}
+	frames := make(map[int]Frame)
+	count := int(readUnsignedShort(b, offset))
+	s := offset + 2
+	pc := -1
+	for i := 0; i < count; i++ {
+		frameType := int(b[s])
+		s++
+		delta := 0
+		var frame Frame
+		switch {
+		case frameType < 64:
+			delta = frameType
+			frame.Type = FrameSame
+		case frameType < 128:
+			delta = frameType - 64
+			frame.Type = FrameSame1
+			frame.Stack, s = c.readVerificationTypes(b, s, 1, label)
+		case frameType < 247:
+			return nil, fmt.Errorf("Reserved stack map frame type %d", frameType)
+		case frameType == 247:
+			delta = int(readUnsignedShort(b, s))
+			frame.Type = FrameSame1
+			frame.Stack, s = c.readVerificationTypes(b, s+2, 1, label)
+		case frameType < 251:
+			delta = int(readUnsignedShort(b, s))
+			s += 2
+			frame.Type = FrameChop
+			frame.Chopped = 251 - frameType
+		case frameType == 251:
+			delta = int(readUnsignedShort(b, s))
+			s += 2
+			frame.Type = FrameSame
+		case frameType < 255:
+			delta = int(readUnsignedShort(b, s))
+			frame.Type = FrameAppend
+			frame.Locals, s = c.readVerificationTypes(b, s+2, frameType-251, label)
+		default:
+			delta = int(readUnsignedShort(b, s))
+			frame.Type = FrameFull
+			frame.Locals, s = c.readVerificationTypes(b, s+4, int(readUnsignedShort(b, s+2)), label)
+			frame.Stack, s = c.readVerificationTypes(b, s+2, int(readUnsignedShort(b, s)), label)
+		}
+		pc += delta + 1
+		label(pc)
+		if expand {
+			var err error
+			if locals, err = frameLocals(locals, frame); err != nil {
+				return nil, fmt.Errorf("Stack map frame at %d: %v", pc, err)
+			}
+			frame = Frame{Type: FrameNew, Locals: locals, Stack: frame.Stack}
+		}
+		frames[pc] = frame
+	}
+	return frames, nil
+}
+
+// Reads count verification_type_info structures starting at offset, and returns them with the following offset.
+func (c *classParser) readVerificationTypes(b []byte, offset, count int, label func(int) *Label) ([]interface{}, int) {
+	types := make([]interface{}, count)
+	for i := 0; i < count; i++ {
+		tag := b[offset]
+		switch tag {
+		case 7:
+			types[i] = c.readClass(b, offset+1)
+			offset += 3
+		case 8:
+			types[i] = label(int(readUnsignedShort(b, offset+1)))
+			offset += 3
+		default:
+			types[i] = VerificationType(tag)
+			offset++
+		}
+	}
+	return types, offset
+}
+
+// Returns the size in bytes of the instruction at p, including its opcode.
+// start is the offset of the method's code, it's needed to compute the padding of switches.
+func instructionSize(b []byte, p, start int) int {
+	op := int(b[p])
+	switch op {
+	case TABLESWITCH:
+		s := p + 1 + (3 - (p-start)%4)
+		low, high := int(readInt(b, s+4)), int(readInt(b, s+8))
+		return s - p + 12 + 4*(high-low+1)
+	case LOOKUPSWITCH:
+		s := p + 1 + (3 - (p-start)%4)
+		return s - p + 8 + 8*int(readInt(b, s+4))
+	case WIDE:
+		if b[p+1] == IINC {
+			return 6
+		}
+		return 4
+	}
+	return 1 + ByteCodes[op].Size
+}
+
+// Sends the instruction at p to the visitor, using its canonical form.
+func (c *classParser) visitInstruction(b []byte, p, start int, label func(int) *Label, cv CodeVisitor) {
+	op := int(b[p])
+	pc := p - start
+	switch ByteCodes[op].Kind {
+	case KindInsn:
+		cv.VisitInsn(op)
+	case KindIntInsn:
+		switch op {
+		case BIPUSH:
+			cv.VisitIntInsn(op, int(int8(b[p+1])))
+		case SIPUSH:
+			cv.VisitIntInsn(op, int(int16(readUnsignedShort(b, p+1))))
+		default:
+			cv.VisitIntInsn(op, int(b[p+1]))
+		}
+	case KindVarInsn:
+		switch {
+		case op >= ILOAD_0 && op <= ALOAD_3:
+			cv.VisitVarInsn(ILOAD+(op-ILOAD_0)/4, (op-ILOAD_0)%4)
+		case op >= ISTORE_0 && op <= ASTORE_3:
+			cv.VisitVarInsn(ISTORE+(op-ISTORE_0)/4, (op-ISTORE_0)%4)
+		default:
+			cv.VisitVarInsn(op, int(b[p+1]))
+		}
+	case KindTypeInsn:
+		cv.VisitTypeInsn(op, c.readClass(b, p+1))
+	case KindFieldInsn:
+		owner, name, descriptor, _ := c.readMemberRef(b, readUnsignedShort(b, p+1))
+		cv.VisitFieldInsn(op, owner, name, descriptor)
+	case KindMethodInsn:
+		owner, name, descriptor, isInterface := c.readMemberRef(b, readUnsignedShort(b, p+1))
+		cv.VisitMethodInsn(op, owner, name, descriptor, isInterface)
+	case KindInvokeDynamicInsn:
+		indy := c.readDynamic(b, readUnsignedShort(b, p+1))
+		cv.VisitInvokeDynamicInsn(indy.Name, indy.Descriptor, indy.Bootstrap, indy.Arguments)
+	case KindJumpInsn:
+		switch op {
+		case GOTO_W:
+			cv.VisitJumpInsn(GOTO, label(pc+int(readInt(b, p+1))))
+		case JSR_W:
+			cv.VisitJumpInsn(JSR, label(pc+int(readInt(b, p+1))))
+		default:
+			cv.VisitJumpInsn(op, label(pc+int(int16(readUnsignedShort(b, p+1)))))
+		}
+	case KindLdcInsn:
+		if op == LDC {
+			cv.VisitLdcInsn(c.readConst(b, uint16(b[p+1])))
+		} else {
+			cv.VisitLdcInsn(c.readConst(b, readUnsignedShort(b, p+1)))
+		}
+	case KindIincInsn:
+		cv.VisitIincInsn(int(b[p+1]), int(int8(b[p+2])))
+	case KindTableSwitchInsn:
+		s := p + 1 + (3 - pc%4)
+		dflt := label(pc + int(readInt(b, s)))
+		low, high := int(readInt(b, s+4)), int(readInt(b, s+8))
+		labels := make([]*Label, high-low+1)
+		for i := range labels {
+			labels[i] = label(pc + int(readInt(b, s+12+4*i)))
+		}
+		cv.VisitTableSwitchInsn(low, high, dflt, labels)
+	case KindLookupSwitchInsn:
+		s := p + 1 + (3 - pc%4)
+		dflt := label(pc + int(readInt(b, s)))
+		pairs := int(readInt(b, s+4))
+		keys, labels := make([]int, pairs), make([]*Label, pairs)
+		for i := 0; i < pairs; i++ {
+			keys[i] = int(readInt(b, s+8+8*i))
+			labels[i] = label(pc + int(readInt(b, s+12+8*i)))
+		}
+		cv.VisitLookupSwitchInsn(dflt, keys, labels)
+	case KindMultiANewArrayInsn:
+		cv.VisitMultiANewArrayInsn(string(c.readClass(b, p+1)), int(b[p+3]))
+	case KindWide:
+		if int(b[p+1]) == IINC {
+			cv.VisitIincInsn(int(readUnsignedShort(b, p+2)), int(int16(readUnsignedShort(b, p+4))))
+		} else {
+			cv.VisitVarInsn(int(b[p+1]), int(readUnsignedShort(b, p+2)))
+		}
+	}
+}
+
+// Reads the annotations of a RuntimeVisibleAnnotations or RuntimeInvisibleAnnotations attribute starting at offset,
+// an offset of 0 means the attribute is absent.
+func (c *classParser) readAnnotations(b []byte, offset int, visible bool, visit func(string, bool) AnnotationVisitor) error {
+	if offset == 0 {
+		return nil
+	}
+	count := int(readUnsignedShort(b, offset))
+	offset += 2
+	for i := 0; i < count; i++ {
+		av := visit(c.readStr(b, offset), visible)
+		var err error
+		if offset, err = c.readAnnotationValues(b, offset+2, av, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the element value pairs of an annotation, av can be nil in which case the values are skipped.
+// depth is the nesting depth of the values.
+func (c *classParser) readAnnotationValues(b []byte, offset int, av AnnotationVisitor, depth int) (int, error) {
+	if err := checkLimit("annotation nesting depth", depth, c.limits.MaxAnnotationDepth); err != nil {
+		return offset, err
+	}
+	count := int(readUnsignedShort(b, offset))
+	offset += 2
+	for i := 0; i < count; i++ {
+		var err error
+		if offset, err = c.readElementValue(b, offset+2, c.readStr(b, offset), av, depth); err != nil {
+			return offset, err
+		}
+	}
+	if av != nil {
+		av.VisitEnd()
+	}
+	return offset, nil
+}
+
+// Reads an element_value structure and returns the offset following it.
+func (c *classParser) readElementValue(b []byte, offset int, name string, av AnnotationVisitor, depth int) (int, error) {
+	tag := b[offset]
+	offset++
+	switch tag {
+	case 'B', 'C', 'S', 'Z', 'I', 'J', 'F', 'D', 's':
+		if av != nil {
+			value := c.readConst(b, readUnsignedShort(b, offset))
+			switch tag {
+			case 'B':
+				value = int8(value.(int32))
+			case 'C':
+				value = uint16(value.(int32))
+			case 'S':
+				value = int16(value.(int32))
+			case 'Z':
+				value = value.(int32) != 0
+			case 's':
+				value = c.readStr(b, offset)
+			}
+			av.Visit(name, value)
+		}
+		return offset + 2, nil
+	case 'e':
+		if av != nil {
+			av.VisitEnum(name, c.readStr(b, offset), c.readStr(b, offset+2))
+		}
+		return offset + 4, nil
+	case 'c':
+		if av != nil {
+			av.Visit(name, ClassLiteral(c.readStr(b, offset)))
+		}
+		return offset + 2, nil
+	case '@':
+		var nested AnnotationVisitor
+		if av != nil {
+			nested = av.VisitAnnotation(name, c.readStr(b, offset))
+		}
+		return c.readAnnotationValues(b, offset+2, nested, depth+1)
+	case '[':
+		if err := checkLimit("annotation nesting depth", depth+1, c.limits.MaxAnnotationDepth); err != nil {
+			return offset, err
+		}
+		var array AnnotationVisitor
+		if av != nil {
+			array = av.VisitArray(name)
+		}
+		count := int(readUnsignedShort(b, offset))
+		offset += 2
+		for i := 0; i < count; i++ {
+			var err error
+			if offset, err = c.readElementValue(b, offset, "", array, depth+1); err != nil {
+				return offset, err
+			}
+		}
+		if array != nil {
+			array.VisitEnd()
+		}
+		return offset, nil
+	}
+	return offset, fmt.Errorf("Unknown annotation element value tag %c", tag)
+}
+
+// Reads a loadable constant from the pool, see the CodeVisitor.VisitLdcInsn for the possible types.
+func (c *classParser) readConst(b []byte, index uint16) interface{} {
+	offset := c.poolItems[index]
+	switch b[offset-1] {
+	case ConstInteger:
+		return readInt(b, offset)
+	case ConstFloat:
+		return math.Float32frombits(readUnsignedInt(b, offset))
+	case ConstLong:
+		return readLong(b, offset)
+	case ConstDouble:
+		return math.Float64frombits(readUnsignedLong(b, offset))
+	case ConstString:
+		return c.readStr(b, offset)
+	case ConstClass:
+		return ClassName(c.readStr(b, offset))
+	case ConstMethodType:
+		return MethodType(c.readStr(b, offset))
+	case ConstMethodHandle:
+		return c.readHandle(b, index)
+	case ConstDynamic:
+		return c.readDynamic(b, index)
+	}
+	return nil
+}
+
+// Reads a Fieldref, Methodref or InterfaceMethodref entry.
+func (c *classParser) readMemberRef(b []byte, index uint16) (ClassName, string, string, bool) {
+	offset := c.poolItems[index]
+	owner := c.readClass(b, offset)
+	nameAndType := c.poolItems[readUnsignedShort(b, offset+2)]
+	return owner, c.readStr(b, nameAndType), c.readStr(b, nameAndType+2), b[offset-1] == ConstInterfaceMethodref
+}
+
+func (c *classParser) readHandle(b []byte, index uint16) Handle {
+	offset := c.poolItems[index]
+	owner, name, descriptor, isInterface := c.readMemberRef(b, readUnsignedShort(b, offset+1))
+	return Handle{
+		Kind:        int(b[offset]),
+		Owner:       owner,
+		Name:        name,
+		Descriptor:  descriptor,
+		IsInterface: isInterface,
 	}
-	return jclass, nil
 }
 
-func (c *ClassReader) readClass(b []byte, offset int) string {
-	return c.readStr(b, c.PoolItems[readUnsignedShort(b, offset)])
+// Reads a Dynamic or InvokeDynamic entry along with its bootstrap method.
+func (c *classParser) readDynamic(b []byte, index uint16) ConstantDynamic {
+	offset := c.poolItems[index]
+	bootstrap := c.bootstrapMethods[readUnsignedShort(b, offset)]
+	nameAndType := c.poolItems[readUnsignedShort(b, offset+2)]
+	args := make([]interface{}, readUnsignedShort(b, bootstrap+2))
+	for i := range args {
+		args[i] = c.readConst(b, readUnsignedShort(b, bootstrap+4+2*i))
+	}
+	return ConstantDynamic{
+		Name:       c.readStr(b, nameAndType),
+		Descriptor: c.readStr(b, nameAndType+2),
+		Bootstrap:  c.readHandle(b, readUnsignedShort(b, bootstrap)),
+		Arguments:  args,
+	}
+}
+
+func (c *classParser) readClass(b []byte, offset int) ClassName {
+	index := readUnsignedShort(b, offset)
+	if index == 0 {
+		// Only java/lang/Object and module-info have no super class
+		return ""
+	}
+	return ClassName(c.readStr(b, c.poolItems[index]))
 }
 
-func (c *ClassReader) fillPoolItems(b []byte, poolSize uint16) error {
-	c.PoolItems = make([]int, poolSize)
-	c.PoolStr = make([]string, poolSize)
+// Reads a u2 count followed by as many class entries.
+func (c *classParser) readClasses(b []byte, offset int) []ClassName {
+	count := int(readUnsignedShort(b, offset))
+	classes := make([]ClassName, count)
+	for i := 0; i < count; i++ {
+		classes[i] = c.readClass(b, offset+2+2*i)
+	}
+	return classes
+}
+
+func (c *classParser) fillPoolItems(b []byte, poolSize uint16) error {
+	if err := checkLimit("constant pool count", int(poolSize), c.limits.MaxPoolEntries); err != nil {
+		return err
+	}
+	c.poolItems = make([]int, poolSize)
+	c.poolStr = make([]string, poolSize)
 	ptr := 10
 	for i := uint16(1); i < poolSize; i += 1 {
-		c.PoolItems[i] = ptr + 1
+		c.poolItems[i] = ptr + 1
 		curIndex := int(b[ptr])
 		curSize, exists := ConstSizeMap[curIndex]
 		if !exists {
@@ -156,9 +1335,12 @@ func (c *ClassReader) fillPoolItems(b []byte, poolSize uint16) error {
 			}
 		}
 		ptr += curSize
+		if curIndex == ConstLong || curIndex == ConstDouble {
+			// 8 byte constants take two entries in the pool
+			i++
+		}
 	}
-	c.CurrentIndex = ptr
-	c.HeadStart = ptr
+	c.headStart = ptr
 	return nil
 }
 
@@ -212,29 +1394,20 @@ func readUnsignedShort(b []byte, offset int) uint16 {
 	return c
 }
 
-func (c *ClassReader) readStr(b []byte, offset int) string {
-	item := readUnsignedShort(b, offset)
-	index := c.PoolItems[item]
-	length := int(readUnsignedShort(b, index))
-	index += 2
-	// TODO: strings in java are UTF-8 encoded, it should be handled accordingly
-	endIndex := index + length
-	return string(b[index:endIndex])
+// Reads the Utf8 entry whose index is found at offset.
+func (c *classParser) readStr(b []byte, offset int) string {
+	return c.readUtf8(b, readUnsignedShort(b, offset))
 }
 
-// Read the code of the method
-func readCode(b []byte, offset int, length uint32) ([]BytesBlock, error) {
-	blocks := make([]BytesBlock, 0)
-	end := uint32(offset) + length
-	curBlock := NewByteBlock()
-	for i := uint32(offset); i < end; i = i + 1 {
-		// TODO: curBlock may change when dealing with labels or control flow
-		bc, err := curBlock.Add(b[i])
-		if err != nil {
-			return nil, err
-		}
-		i = i + uint32(bc.Size)
+// Reads the CONSTANT_Utf8 entry of the pool at index item.
+func (c *classParser) readUtf8(b []byte, item uint16) string {
+	if str := c.poolStr[item]; str != "" {
+		return str
 	}
-	blocks = append(blocks, curBlock)
-	return blocks, nil
+	index := c.poolItems[item]
+	length := int(readUnsignedShort(b, index))
+	index += 2
+	endIndex := index + length
+	c.poolStr[item] = decodeModifiedUTF8(b[index:endIndex])
+	return c.poolStr[item]
 }
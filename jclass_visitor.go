@@ -0,0 +1,417 @@
+package gytes
+
+import "fmt"
+
+// The visitors in this file build a JavaClass out of the events sent by a ClassReader,
+// they are what ClassReader.ReadClass uses to materialize the whole class.
+// JavaClass.Accept goes the other way and sends the content of a JavaClass to a visitor.
+
+type classCollector struct {
+	class *JavaClass
+}
+
+func newClassCollector() *classCollector {
+	return &classCollector{class: &JavaClass{}}
+}
+
+func (cc *classCollector) Visit(version ClassVersion, minorVersion uint16, access ClassAccess, name ClassName, signature string, superName ClassName, interfaces []ClassName) {
+	cc.class.MajorVersion = uint16(version)
+	cc.class.MinorVersion = minorVersion
+	cc.class.Access = access
+	cc.class.Name = name
+	cc.class.Signature = signature
+	cc.class.SuperName = superName
+	cc.class.Interfaces = interfaces
+	cc.class.Fields = make([]JavaField, 0)
+	cc.class.Methods = make([]JavaMethod, 0)
+}
+
+func (cc *classCollector) VisitSource(source, debug string) {
+	cc.class.SourceName = source
+	cc.class.SourceDebug = debug
+}
+
+func (cc *classCollector) VisitNestHost(host ClassName) {
+	cc.class.NestHost = host
+}
+
+func (cc *classCollector) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return collectAnnotation(descriptor, visible, &cc.class.Annotations)
+}
+
+func (cc *classCollector) VisitAttribute(attr JAttribute) {
+	cc.class.Attributes = append(cc.class.Attributes, attr)
+}
+
+func (cc *classCollector) VisitNestMember(member ClassName) {
+	cc.class.NestMembers = append(cc.class.NestMembers, member)
+}
+
+func (cc *classCollector) VisitPermittedSubclass(subclass ClassName) {
+	cc.class.PermittedSubclasses = append(cc.class.PermittedSubclasses, subclass)
+}
+
+func (cc *classCollector) VisitRecordComponent(name, descriptor, signature string) RecordComponentVisitor {
+	cc.class.RecordComponents = append(cc.class.RecordComponents, RecordComponent{
+		Name:       name,
+		Descriptor: descriptor,
+		Signature:  signature,
+	})
+	return &recordComponentCollector{component: &cc.class.RecordComponents[len(cc.class.RecordComponents)-1]}
+}
+
+func (cc *classCollector) VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor {
+	cc.class.Fields = append(cc.class.Fields, JavaField{
+		Name:       name,
+		Modifiers:  access,
+		Descriptor: descriptor,
+		Signature:  signature,
+		Value:      value,
+	})
+	return newFieldCollector(cc.class, len(cc.class.Fields)-1)
+}
+
+func (cc *classCollector) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	cc.class.Methods = append(cc.class.Methods, JavaMethod{
+		Name:       name,
+		Modifiers:  access,
+		Descriptor: descriptor,
+		Signature:  signature,
+		Exceptions: exceptions,
+	})
+	return newMethodCollector(cc.class, len(cc.class.Methods)-1)
+}
+
+func (cc *classCollector) VisitEnd() {
+}
+
+// The members are looked up on each access, as the slices holding them grow while the class is visited.
+type fieldCollector struct {
+	field func() *JavaField
+}
+
+func newFieldCollector(class *JavaClass, index int) *fieldCollector {
+	return &fieldCollector{field: func() *JavaField { return &class.Fields[index] }}
+}
+
+func (fc *fieldCollector) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return collectAnnotation(descriptor, visible, &fc.field().Annotations)
+}
+
+func (fc *fieldCollector) VisitAttribute(attr JAttribute) {
+	fc.field().Attributes = append(fc.field().Attributes, attr)
+}
+
+func (fc *fieldCollector) VisitEnd() {
+}
+
+// The Record attribute is the only one holding record components, so the slice holding them doesn't grow
+// while a component is visited.
+type recordComponentCollector struct {
+	component *RecordComponent
+}
+
+func (rc *recordComponentCollector) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return collectAnnotation(descriptor, visible, &rc.component.Annotations)
+}
+
+func (rc *recordComponentCollector) VisitAttribute(attr JAttribute) {
+	rc.component.Attributes = append(rc.component.Attributes, attr)
+}
+
+func (rc *recordComponentCollector) VisitEnd() {
+}
+
+type methodCollector struct {
+	method func() *JavaMethod
+}
+
+func newMethodCollector(class *JavaClass, index int) *methodCollector {
+	return &methodCollector{method: func() *JavaMethod { return &class.Methods[index] }}
+}
+
+func (mc *methodCollector) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return collectAnnotation(descriptor, visible, &mc.method().Annotations)
+}
+
+func (mc *methodCollector) VisitAttribute(attr JAttribute) {
+	mc.method().Attributes = append(mc.method().Attributes, attr)
+}
+
+func (mc *methodCollector) VisitCode() CodeVisitor {
+	method := mc.method()
+	method.Instructions = &InsnList{}
+	return &codeCollector{method: method, block: NewByteBlock()}
+}
+
+func (mc *methodCollector) VisitEnd() {
+}
+
+// No method is added to the class while its code is visited, so the method can be referenced directly.
+type codeCollector struct {
+	method *JavaMethod
+	block  BytesBlock
+}
+
+func (cc *codeCollector) add(opcode int, n InsnNode) {
+	// The opcodes are validated by the reader
+	_, _ = cc.block.Add(uint8(opcode))
+	cc.method.Instructions.Add(n)
+}
+
+func (cc *codeCollector) VisitInsn(opcode int) { cc.add(opcode, &Insn{Op: opcode}) }
+func (cc *codeCollector) VisitIntInsn(opcode, operand int) {
+	cc.add(opcode, &IntInsn{Op: opcode, Operand: operand})
+}
+func (cc *codeCollector) VisitVarInsn(opcode, index int) {
+	cc.add(opcode, &VarInsn{Op: opcode, Var: index})
+}
+func (cc *codeCollector) VisitTypeInsn(opcode int, typ ClassName) {
+	cc.add(opcode, &TypeInsn{Op: opcode, Type: typ})
+}
+func (cc *codeCollector) VisitFieldInsn(opcode int, owner ClassName, name, descriptor string) {
+	cc.add(opcode, &FieldInsn{Op: opcode, Owner: owner, Name: name, Descriptor: descriptor})
+}
+func (cc *codeCollector) VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool) {
+	cc.add(opcode, &MethodInsn{Op: opcode, Owner: owner, Name: name, Descriptor: descriptor, Interface: isInterface})
+}
+func (cc *codeCollector) VisitInvokeDynamicInsn(name, descriptor string, bootstrap Handle, arguments []interface{}) {
+	cc.add(INVOKEDYNAMIC, &InvokeDynamicInsn{Name: name, Descriptor: descriptor, Bootstrap: bootstrap, Arguments: arguments})
+}
+func (cc *codeCollector) VisitJumpInsn(opcode int, target *Label) {
+	cc.add(opcode, &JumpInsn{Op: opcode, Target: target})
+}
+func (cc *codeCollector) VisitLabel(label *Label) {
+	cc.method.Instructions.Add(&LabelNode{Label: label})
+}
+func (cc *codeCollector) VisitLdcInsn(value interface{}) {
+	switch value.(type) {
+	case int64, float64:
+		cc.add(LDC2_W, &LdcInsn{Value: value})
+	default:
+		cc.add(LDC, &LdcInsn{Value: value})
+	}
+}
+func (cc *codeCollector) VisitIincInsn(index, increment int) {
+	cc.add(IINC, &IincInsn{Var: index, Increment: increment})
+}
+func (cc *codeCollector) VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label) {
+	cc.add(TABLESWITCH, &TableSwitchInsn{Min: min, Max: max, Default: dflt, Labels: labels})
+}
+func (cc *codeCollector) VisitLookupSwitchInsn(dflt *Label, keys []int, labels []*Label) {
+	cc.add(LOOKUPSWITCH, &LookupSwitchInsn{Default: dflt, Keys: keys, Labels: labels})
+}
+func (cc *codeCollector) VisitMultiANewArrayInsn(descriptor string, dimensions int) {
+	cc.add(MULTIANEWARRAY, &MultiANewArrayInsn{Descriptor: descriptor, Dimensions: dimensions})
+}
+func (cc *codeCollector) VisitTryCatchBlock(start, end, handler *Label, typ ClassName) {
+	cc.method.TryCatchBlocks = append(cc.method.TryCatchBlocks, TryCatchBlock{start, end, handler, typ})
+}
+func (cc *codeCollector) VisitLocalVariable(name, descriptor, signature string, start, end *Label, index int) {
+	cc.method.LocalVariables = append(cc.method.LocalVariables, LocalVariable{name, descriptor, signature, start, end, index})
+}
+func (cc *codeCollector) VisitLineNumber(line int, start *Label) {
+	cc.method.Instructions.Add(&LineNumberNode{Line: line, Start: start})
+}
+func (cc *codeCollector) VisitFrame(frame Frame) {
+	cc.method.Instructions.Add(&FrameNode{Frame: frame})
+}
+func (cc *codeCollector) VisitAttribute(attr JAttribute) {
+	cc.method.CodeAttributes = append(cc.method.CodeAttributes, attr)
+}
+
+func (cc *codeCollector) VisitMaxs(maxStack, maxLocals int) {
+	cc.method.MaxStack = uint16(maxStack)
+	cc.method.MaxLocals = uint16(maxLocals)
+}
+
+func (cc *codeCollector) VisitEnd() {
+	cc.method.Body = []BytesBlock{cc.block}
+}
+
+// Collects the values of an annotation, or of an array value, done is called once all of them are visited.
+type annotationCollector struct {
+	annotation *Annotation
+	array      []interface{}
+	done       func(*annotationCollector)
+}
+
+func collectAnnotation(descriptor string, visible bool, annotations *[]Annotation) AnnotationVisitor {
+	return &annotationCollector{
+		annotation: &Annotation{Descriptor: descriptor, Visible: visible},
+		done: func(ac *annotationCollector) {
+			*annotations = append(*annotations, *ac.annotation)
+		},
+	}
+}
+
+func (ac *annotationCollector) add(name string, value interface{}) {
+	if ac.annotation == nil {
+		ac.array = append(ac.array, value)
+	} else {
+		ac.annotation.Values = append(ac.annotation.Values, AnnotationElement{Name: name, Value: value})
+	}
+}
+
+func (ac *annotationCollector) Visit(name string, value interface{}) {
+	ac.add(name, value)
+}
+
+func (ac *annotationCollector) VisitEnum(name, descriptor, value string) {
+	ac.add(name, EnumConstant{Descriptor: descriptor, Name: value})
+}
+
+func (ac *annotationCollector) VisitAnnotation(name, descriptor string) AnnotationVisitor {
+	nested := &Annotation{Descriptor: descriptor}
+	ac.add(name, nested)
+	return &annotationCollector{annotation: nested}
+}
+
+func (ac *annotationCollector) VisitArray(name string) AnnotationVisitor {
+	return &annotationCollector{
+		array: make([]interface{}, 0),
+		done: func(array *annotationCollector) {
+			ac.add(name, array.array)
+		},
+	}
+}
+
+func (ac *annotationCollector) VisitEnd() {
+	if ac.done != nil {
+		ac.done(ac)
+	}
+}
+
+// Accept sends the content of the class to cv, e.g. to write it with a ClassWriter.
+// The members read with LazyMembers are loaded first.
+// The code of the methods is replayed from their Instructions. The methods without Instructions only hold
+// the opcodes of their Body, so only the bodies made of instructions without operands can be replayed,
+// an error is returned for the others.
+func (jc *JavaClass) Accept(cv ClassVisitor) error {
+	if err := jc.Load(); err != nil {
+		return err
+	}
+	for _, method := range jc.Methods {
+		if method.Instructions != nil {
+			continue
+		}
+		for _, block := range method.Body {
+			for _, inst := range block.Instructions {
+				if inst.Size != 0 {
+					return fmt.Errorf("%w: the body of %s%s holds %s, whose operands are not kept",
+						InvalidInstructionError, method.Name, method.Descriptor, inst.Name)
+				}
+			}
+		}
+	}
+	cv.Visit(jc.Version(), jc.MinorVersion, jc.Access, jc.Name, jc.Signature, jc.SuperName, jc.Interfaces)
+	if jc.SourceName != "" || jc.SourceDebug != "" {
+		cv.VisitSource(jc.SourceName, jc.SourceDebug)
+	}
+	if jc.NestHost != "" {
+		cv.VisitNestHost(jc.NestHost)
+	}
+	acceptAnnotations(jc.Annotations, cv.VisitAnnotation)
+	for _, attr := range jc.Attributes {
+		cv.VisitAttribute(attr)
+	}
+	for _, member := range jc.NestMembers {
+		cv.VisitNestMember(member)
+	}
+	for _, subclass := range jc.PermittedSubclasses {
+		cv.VisitPermittedSubclass(subclass)
+	}
+	for _, component := range jc.RecordComponents {
+		if rv := cv.VisitRecordComponent(component.Name, component.Descriptor, component.Signature); rv != nil {
+			acceptAnnotations(component.Annotations, rv.VisitAnnotation)
+			for _, attr := range component.Attributes {
+				rv.VisitAttribute(attr)
+			}
+			rv.VisitEnd()
+		}
+	}
+	for _, field := range jc.Fields {
+		if fv := cv.VisitField(field.Modifiers, field.Name, field.Descriptor, field.Signature, field.Value); fv != nil {
+			acceptAnnotations(field.Annotations, fv.VisitAnnotation)
+			for _, attr := range field.Attributes {
+				fv.VisitAttribute(attr)
+			}
+			fv.VisitEnd()
+		}
+	}
+	for _, method := range jc.Methods {
+		if mv := cv.VisitMethod(method.Modifiers, method.Name, method.Descriptor, method.Signature, method.Exceptions); mv != nil {
+			acceptAnnotations(method.Annotations, mv.VisitAnnotation)
+			for _, attr := range method.Attributes {
+				mv.VisitAttribute(attr)
+			}
+			if method.Instructions != nil || len(method.Body) > 0 {
+				if code := mv.VisitCode(); code != nil {
+					method.acceptCode(code)
+					for _, attr := range method.CodeAttributes {
+						code.VisitAttribute(attr)
+					}
+					code.VisitMaxs(int(method.MaxStack), int(method.MaxLocals))
+					code.VisitEnd()
+				}
+			}
+			mv.VisitEnd()
+		}
+	}
+	cv.VisitEnd()
+	return nil
+}
+
+// Sends the instructions of the method to code, or the opcodes of its Body if it has no Instructions.
+func (jm *JavaMethod) acceptCode(code CodeVisitor) {
+	if jm.Instructions == nil {
+		for _, block := range jm.Body {
+			for _, inst := range block.Instructions {
+				code.VisitInsn(int(inst.Value))
+			}
+		}
+		return
+	}
+	for _, block := range jm.TryCatchBlocks {
+		code.VisitTryCatchBlock(block.Start, block.End, block.Handler, block.Type)
+	}
+	jm.Instructions.Accept(code)
+	for _, local := range jm.LocalVariables {
+		code.VisitLocalVariable(local.Name, local.Descriptor, local.Signature, local.Start, local.End, local.Index)
+	}
+}
+
+func acceptAnnotations(annotations []Annotation, visit func(descriptor string, visible bool) AnnotationVisitor) {
+	for _, annotation := range annotations {
+		if av := visit(annotation.Descriptor, annotation.Visible); av != nil {
+			acceptAnnotationValues(annotation.Values, av)
+		}
+	}
+}
+
+func acceptAnnotationValues(values []AnnotationElement, av AnnotationVisitor) {
+	for _, element := range values {
+		acceptAnnotationValue(element.Name, element.Value, av)
+	}
+	av.VisitEnd()
+}
+
+func acceptAnnotationValue(name string, value interface{}, av AnnotationVisitor) {
+	switch v := value.(type) {
+	case EnumConstant:
+		av.VisitEnum(name, v.Descriptor, v.Name)
+	case *Annotation:
+		if nested := av.VisitAnnotation(name, v.Descriptor); nested != nil {
+			acceptAnnotationValues(v.Values, nested)
+		}
+	case []interface{}:
+		if array := av.VisitArray(name); array != nil {
+			for _, element := range v {
+				acceptAnnotationValue("", element, array)
+			}
+			array.VisitEnd()
+		}
+	default:
+		av.Visit(name, value)
+	}
+}
@@ -1,14 +1,507 @@
 package gytes
 
-// This is a draft of the low level API that I want for now
+import (
+	"errors"
+	"fmt"
+	"math"
+)
 
-// struct ClassWriter {
-//    bytes ByteVec
-// }
-//
-// func(ClassWriter) WriteOp(opcode int)
-//
-// func(ClassWriter) WriteOp(opcode, operand int)
+// Returned when the content given to a ClassWriter can't be written in a class file
+var InvalidClassContentError = errors.New("Invalid class content")
+
+// WriteOptions control what a ClassWriter computes instead of using the given values, they can be combined.
+type WriteOptions uint
+
+const (
+	// Compute the max_stack and max_locals of the methods from their instructions, the values given
+	// to VisitMaxs are ignored
+	ComputeMaxs WriteOptions = 1 << iota
+	// Compute the stack map frames of the methods of classes of version 50 or later, the frames given
+	// to VisitFrame are ignored. It implies ComputeMaxs, and needs the Hierarchy of the classes used by the code
+	ComputeFrames
+)
+
+// ClassWriter is the low level API to generate a class file, it receives the content of the class as the
+// events of a ClassVisitor, e.g. from a ClassReader or a JavaClass, and emits the class file bytes.
+// The constant pool entries needed by the class are created automatically, including the ones of the standard
+// attributes a ClassReader passes as is, see JAttribute.
 //
-// func(ClassWriter) WriteType(opcode int, typeDesc string)
-//
\ No newline at end of file
+// The visitor methods don't return errors, the first one is kept and returned by Bytes.
+type ClassWriter struct {
+	Options WriteOptions
+	// Used to find the common super classes of the types merged when computing the frames,
+	// the CoreHierarchy is used if it's nil
+	Hierarchy ClassHierarchy
+	// The codecs of the custom attributes, the DefaultAttributeRegistry is used if it's nil
+	Attributes *AttributeRegistry
+
+	pool         *ConstantPoolBuilder
+	version      ClassVersion
+	minorVersion uint16
+	access       ClassAccess
+	name         ClassName
+	superClass   uint16
+	interfaces   []uint16
+	// The names of the super class and of the interfaces, as known to the frames computation
+	superName      ClassName
+	interfaceNames []ClassName
+
+	signature, sourceName, sourceDebug string
+	nestHost                           ClassName
+	nestMembers, permittedSubclasses   []ClassName
+	annotations                        annotationSet
+	attributes                         []JAttribute
+	record                             ByteVector
+	recordComponentCount               int
+	hasRecord                          bool
+
+	fields, methods []*ByteVector
+	// The original bytes of the class being written, set when it was read with RoundTrip
+	original *originalClass
+	// Raise the maxs given to VisitMaxs to the computed ones, for the code edited in a JavaClass
+	raiseMaxs bool
+	err       error
+}
+
+func NewClassWriter() *ClassWriter {
+	return NewClassWriterWithPool(NewConstantPoolBuilder())
+}
+
+// NewClassWriterWithPool creates a writer adding the entries of the class to pool, e.g. to a pool
+// created by NewConstantPoolBuilderFrom to keep the indexes of a class that was read.
+func NewClassWriterWithPool(pool *ConstantPoolBuilder) *ClassWriter {
+	return &ClassWriter{pool: pool}
+}
+
+// Pool returns the constant pool of the class being written.
+func (cw *ClassWriter) Pool() *ConstantPoolBuilder {
+	return cw.pool
+}
+
+func (cw *ClassWriter) setErr(err error) {
+	if cw.err == nil && err != nil {
+		cw.err = err
+	}
+}
+
+func (cw *ClassWriter) Visit(version ClassVersion, minorVersion uint16, access ClassAccess, name ClassName, signature string, superName ClassName, interfaces []ClassName) {
+	cw.version, cw.minorVersion, cw.access, cw.name, cw.signature = version, minorVersion, access, name, signature
+	cw.superName, cw.interfaceNames = superName, interfaces
+	cw.pool.Class(name)
+	if superName != "" {
+		cw.superClass = cw.pool.Class(superName)
+	}
+	cw.interfaces = make([]uint16, len(interfaces))
+	for i, itf := range interfaces {
+		cw.interfaces[i] = cw.pool.Class(itf)
+	}
+}
+
+func (cw *ClassWriter) VisitSource(source, debug string) {
+	cw.sourceName, cw.sourceDebug = source, debug
+}
+
+func (cw *ClassWriter) VisitNestHost(host ClassName) {
+	cw.nestHost = host
+}
+
+func (cw *ClassWriter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return cw.annotations.add(cw, descriptor, visible)
+}
+
+func (cw *ClassWriter) VisitAttribute(attr JAttribute) {
+	cw.attributes = append(cw.attributes, attr)
+}
+
+func (cw *ClassWriter) VisitNestMember(member ClassName) {
+	cw.nestMembers = append(cw.nestMembers, member)
+}
+
+func (cw *ClassWriter) VisitPermittedSubclass(subclass ClassName) {
+	cw.permittedSubclasses = append(cw.permittedSubclasses, subclass)
+}
+
+func (cw *ClassWriter) VisitRecordComponent(name, descriptor, signature string) RecordComponentVisitor {
+	cw.hasRecord = true
+	cw.recordComponentCount++
+	return &recordComponentWriter{memberWriter: newMemberWriter(cw, RecordComponentAttribute), bv: &cw.record,
+		name: name, descriptor: descriptor, signature: signature}
+}
+
+func (cw *ClassWriter) VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor {
+	bv := &ByteVector{}
+	cw.fields = append(cw.fields, bv)
+	return &fieldWriter{memberWriter: newMemberWriter(cw, FieldAttribute), bv: bv,
+		access: access, name: name, descriptor: descriptor, signature: signature, value: value}
+}
+
+func (cw *ClassWriter) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	return cw.NewMethod(access, name, descriptor, signature, exceptions)
+}
+
+// NewMethod adds a method to the class and returns the writer of its content.
+func (cw *ClassWriter) NewMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) *MethodWriter {
+	bv := &ByteVector{}
+	cw.methods = append(cw.methods, bv)
+	return &MethodWriter{memberWriter: newMemberWriter(cw, MethodAttribute), bv: bv,
+		access: access, name: name, descriptor: descriptor, signature: signature, exceptions: exceptions}
+}
+
+func (cw *ClassWriter) VisitEnd() {
+}
+
+// Bytes returns the class file, or the first error that happened while writing it.
+func (cw *ClassWriter) Bytes() ([]byte, error) {
+	parts, err := cw.parts()
+	if err != nil {
+		return nil, err
+	}
+	pool := &ByteVector{}
+	cw.pool.write(pool)
+	poolBytes := pool.Bytes()
+	if cw.original != nil && cw.original.restore(parts) {
+		// The parts only use the entries of the original pool
+		poolBytes = cw.original.pool
+	}
+	bv := &ByteVector{}
+	bv.PutU4(MAGIC).PutU2(cw.minorVersion).PutU2(uint16(cw.version))
+	bv.PutBytes(poolBytes).PutBytes(parts.header)
+	for _, members := range [][][]byte{parts.fields, parts.methods} {
+		bv.PutU2(uint16(len(members)))
+		for _, member := range members {
+			bv.PutBytes(member)
+		}
+	}
+	bv.PutBytes(parts.attributes)
+	return bv.Bytes(), nil
+}
+
+// The parts of a class file following its constant pool
+type classParts struct {
+	// The access flags, the class, its super class and its interfaces
+	header          []byte
+	fields, methods [][]byte
+	// The attributes_count and the attributes of the class
+	attributes []byte
+}
+
+// Writes the parts of the class, they add the entries they use to the pool.
+func (cw *ClassWriter) parts() (*classParts, error) {
+	if cw.err != nil {
+		return nil, cw.err
+	}
+	if cw.name == "" {
+		return nil, fmt.Errorf("%w: the class has no name, Visit must be called first", InvalidClassContentError)
+	}
+	// The attributes are written first, as they add entries to the pool
+	attributes := &ByteVector{}
+	count := attributes.Reserve2()
+	n := 0
+	attribute := func(name string, content func(*ByteVector)) {
+		cw.putAttribute(attributes, name, content)
+		n++
+	}
+	if cw.signature != "" {
+		attribute("Signature", cw.putUtf8(cw.signature))
+	}
+	if cw.sourceName != "" {
+		attribute("SourceFile", cw.putUtf8(cw.sourceName))
+	}
+	if cw.sourceDebug != "" {
+		attribute("SourceDebugExtension", func(bv *ByteVector) { bv.PutBytes([]byte(cw.sourceDebug)) })
+	}
+	if cw.nestHost != "" {
+		attribute("NestHost", func(bv *ByteVector) { bv.PutU2(cw.pool.Class(cw.nestHost)) })
+	}
+	if cw.nestMembers != nil {
+		attribute("NestMembers", cw.putClasses(cw.nestMembers))
+	}
+	if cw.permittedSubclasses != nil {
+		attribute("PermittedSubclasses", cw.putClasses(cw.permittedSubclasses))
+	}
+	if cw.hasRecord {
+		attribute("Record", func(bv *ByteVector) {
+			bv.PutU2(uint16(cw.recordComponentCount)).PutBytes(cw.record.Bytes())
+		})
+	}
+	n += cw.annotations.write(cw, attributes)
+	for _, attr := range cw.attributes {
+		cw.putJAttribute(attributes, attr, ClassAttribute)
+		n++
+	}
+	// Written last as the other attributes can still add bootstrap methods
+	if cw.pool.bootstrapMethodCount > 0 {
+		attribute("BootstrapMethods", func(bv *ByteVector) {
+			bv.PutU2(cw.pool.bootstrapMethodCount).PutBytes(cw.pool.bootstrapMethods.Bytes())
+		})
+	}
+	attributes.PatchU2(count, uint16(n))
+	header := &ByteVector{}
+	header.PutU2(uint16(cw.access)).PutU2(cw.pool.Class(cw.name)).PutU2(cw.superClass)
+	header.PutU2(uint16(len(cw.interfaces)))
+	for _, itf := range cw.interfaces {
+		header.PutU2(itf)
+	}
+	if err := cw.checkCount("fields", len(cw.fields)); err != nil {
+		return nil, err
+	}
+	if err := cw.checkCount("methods", len(cw.methods)); err != nil {
+		return nil, err
+	}
+	if cw.err != nil {
+		return nil, cw.err
+	}
+	if err := cw.pool.Err(); err != nil {
+		return nil, err
+	}
+	parts := &classParts{header: header.Bytes(), attributes: attributes.Bytes()}
+	for _, field := range cw.fields {
+		parts.fields = append(parts.fields, field.Bytes())
+	}
+	for _, method := range cw.methods {
+		parts.methods = append(parts.methods, method.Bytes())
+	}
+	return parts, nil
+}
+
+func (cw *ClassWriter) checkCount(what string, count int) error {
+	if count > math.MaxUint16 {
+		return fmt.Errorf("%w: %d %s, the limit is %d", InvalidClassContentError, count, what, math.MaxUint16)
+	}
+	return nil
+}
+
+// Writes an attribute_info structure, the content of the attribute is written by content.
+func (cw *ClassWriter) putAttribute(bv *ByteVector, name string, content func(*ByteVector)) {
+	bv.PutU2(cw.pool.Utf8(name))
+	length := bv.Reserve4()
+	content(bv)
+	bv.PatchU4(length, uint32(bv.Len()-length-4))
+}
+
+// Writes an attribute given as is, or encoded by its codec if it holds a decoded value. The standard attributes
+// read from another class have their pool indexes replaced, see poolAttributes.
+func (cw *ClassWriter) putJAttribute(bv *ByteVector, attr JAttribute, location AttributeLocation) {
+	data, err := cw.relocate(attr)
+	if err != nil {
+		cw.setErr(err)
+		return
+	}
+	if attr.Value != nil {
+		registry := cw.Attributes
+		if registry == nil {
+			registry = DefaultAttributeRegistry
+		}
+		codec, ok := registry.Codec(attr.Name, location)
+		if !ok {
+			cw.setErr(fmt.Errorf("%w: no codec for the %s attribute %s", InvalidClassContentError, location, attr.Name))
+			return
+		}
+		if data, err = codec.Encode(attr.Value, cw.pool); err != nil {
+			cw.setErr(fmt.Errorf("Could not encode %s attribute %s: %w", location, attr.Name, err))
+			return
+		}
+	}
+	cw.putAttribute(bv, attr.Name, func(bv *ByteVector) { bv.PutBytes(data) })
+}
+
+func (cw *ClassWriter) putUtf8(value string) func(*ByteVector) {
+	return func(bv *ByteVector) { bv.PutU2(cw.pool.Utf8(value)) }
+}
+
+func (cw *ClassWriter) putClasses(classes []ClassName) func(*ByteVector) {
+	return func(bv *ByteVector) {
+		bv.PutU2(uint16(len(classes)))
+		for _, class := range classes {
+			bv.PutU2(cw.pool.Class(class))
+		}
+	}
+}
+
+// The state shared by the writers of the fields, methods and record components
+type memberWriter struct {
+	cw          *ClassWriter
+	location    AttributeLocation
+	annotations annotationSet
+	attributes  []JAttribute
+}
+
+func newMemberWriter(cw *ClassWriter, location AttributeLocation) memberWriter {
+	return memberWriter{cw: cw, location: location}
+}
+
+func (mw *memberWriter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	return mw.annotations.add(mw.cw, descriptor, visible)
+}
+
+func (mw *memberWriter) VisitAttribute(attr JAttribute) {
+	mw.attributes = append(mw.attributes, attr)
+}
+
+// Writes the attributes_count and attributes of a member, the ones written by the member itself come first.
+func (mw *memberWriter) writeAttributes(bv *ByteVector, signature string, own func(attribute func(string, func(*ByteVector)))) {
+	count := bv.Reserve2()
+	n := 0
+	attribute := func(name string, content func(*ByteVector)) {
+		mw.cw.putAttribute(bv, name, content)
+		n++
+	}
+	if own != nil {
+		own(attribute)
+	}
+	if signature != "" {
+		attribute("Signature", mw.cw.putUtf8(signature))
+	}
+	n += mw.annotations.write(mw.cw, bv)
+	for _, attr := range mw.attributes {
+		mw.cw.putJAttribute(bv, attr, mw.location)
+		n++
+	}
+	bv.PatchU2(count, uint16(n))
+}
+
+type fieldWriter struct {
+	memberWriter
+	bv                          *ByteVector
+	access                      FieldAccess
+	name, descriptor, signature string
+	value                       interface{}
+}
+
+func (fw *fieldWriter) VisitEnd() {
+	pool := fw.cw.pool
+	fw.bv.PutU2(uint16(fw.access)).PutU2(pool.Utf8(fw.name)).PutU2(pool.Utf8(fw.descriptor))
+	fw.writeAttributes(fw.bv, fw.signature, func(attribute func(string, func(*ByteVector))) {
+		if fw.value == nil {
+			return
+		}
+		switch fw.value.(type) {
+		case int32, int64, float32, float64, string:
+			attribute("ConstantValue", func(bv *ByteVector) { bv.PutU2(pool.Constant(fw.value)) })
+		default:
+			fw.cw.setErr(fmt.Errorf("%w: constant value %v of field %s has type %T", InvalidConstantError, fw.value, fw.name, fw.value))
+		}
+	})
+}
+
+type recordComponentWriter struct {
+	memberWriter
+	bv                          *ByteVector
+	name, descriptor, signature string
+}
+
+func (rw *recordComponentWriter) VisitEnd() {
+	rw.bv.PutU2(rw.cw.pool.Utf8(rw.name)).PutU2(rw.cw.pool.Utf8(rw.descriptor))
+	rw.writeAttributes(rw.bv, rw.signature, nil)
+}
+
+// The annotations of a class, a member or a record component, in the RuntimeVisibleAnnotations
+// and RuntimeInvisibleAnnotations attributes.
+type annotationSet struct {
+	visible, invisible           ByteVector
+	visibleCount, invisibleCount int
+}
+
+func (as *annotationSet) add(cw *ClassWriter, descriptor string, visible bool) AnnotationVisitor {
+	bv := &as.invisible
+	if visible {
+		bv, as.visibleCount = &as.visible, as.visibleCount+1
+	} else {
+		as.invisibleCount++
+	}
+	bv.PutU2(cw.pool.Utf8(descriptor))
+	return newAnnotationWriter(cw, bv, true)
+}
+
+// Writes the annotation attributes and returns their number.
+func (as *annotationSet) write(cw *ClassWriter, bv *ByteVector) int {
+	n := 0
+	if as.visibleCount > 0 {
+		cw.putAttribute(bv, "RuntimeVisibleAnnotations", func(bv *ByteVector) {
+			bv.PutU2(uint16(as.visibleCount)).PutBytes(as.visible.Bytes())
+		})
+		n++
+	}
+	if as.invisibleCount > 0 {
+		cw.putAttribute(bv, "RuntimeInvisibleAnnotations", func(bv *ByteVector) {
+			bv.PutU2(uint16(as.invisibleCount)).PutBytes(as.invisible.Bytes())
+		})
+		n++
+	}
+	return n
+}
+
+// Writes the element values of an annotation, or of an array value.
+type annotationWriter struct {
+	cw    *ClassWriter
+	bv    *ByteVector
+	named bool
+	// The offset of the number of values
+	count  int
+	values int
+}
+
+func newAnnotationWriter(cw *ClassWriter, bv *ByteVector, named bool) *annotationWriter {
+	return &annotationWriter{cw: cw, bv: bv, named: named, count: bv.Reserve2()}
+}
+
+func (aw *annotationWriter) name(name string) {
+	aw.values++
+	if aw.named {
+		aw.bv.PutU2(aw.cw.pool.Utf8(name))
+	}
+}
+
+func (aw *annotationWriter) Visit(name string, value interface{}) {
+	aw.name(name)
+	pool := aw.cw.pool
+	switch v := value.(type) {
+	case int8:
+		aw.bv.PutU1('B').PutU2(pool.Integer(int32(v)))
+	case uint16:
+		aw.bv.PutU1('C').PutU2(pool.Integer(int32(v)))
+	case int16:
+		aw.bv.PutU1('S').PutU2(pool.Integer(int32(v)))
+	case bool:
+		var b int32
+		if v {
+			b = 1
+		}
+		aw.bv.PutU1('Z').PutU2(pool.Integer(b))
+	case int32:
+		aw.bv.PutU1('I').PutU2(pool.Integer(v))
+	case int64:
+		aw.bv.PutU1('J').PutU2(pool.Long(v))
+	case float32:
+		aw.bv.PutU1('F').PutU2(pool.Float(v))
+	case float64:
+		aw.bv.PutU1('D').PutU2(pool.Double(v))
+	case string:
+		aw.bv.PutU1('s').PutU2(pool.Utf8(v))
+	case ClassLiteral:
+		aw.bv.PutU1('c').PutU2(pool.Utf8(string(v)))
+	default:
+		aw.cw.setErr(fmt.Errorf("%w: annotation value %v of type %T", InvalidConstantError, value, value))
+	}
+}
+
+func (aw *annotationWriter) VisitEnum(name, descriptor, value string) {
+	aw.name(name)
+	aw.bv.PutU1('e').PutU2(aw.cw.pool.Utf8(descriptor)).PutU2(aw.cw.pool.Utf8(value))
+}
+
+func (aw *annotationWriter) VisitAnnotation(name, descriptor string) AnnotationVisitor {
+	aw.name(name)
+	aw.bv.PutU1('@').PutU2(aw.cw.pool.Utf8(descriptor))
+	return newAnnotationWriter(aw.cw, aw.bv, true)
+}
+
+func (aw *annotationWriter) VisitArray(name string) AnnotationVisitor {
+	aw.name(name)
+	aw.bv.PutU1('[')
+	return newAnnotationWriter(aw.cw, aw.bv, false)
+}
+
+func (aw *annotationWriter) VisitEnd() {
+	aw.bv.PatchU2(aw.count, uint16(aw.values))
+}
@@ -0,0 +1,332 @@
+package gytes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func opcodeNames(method JavaMethod) []string {
+	names := make([]string, 0)
+	for _, block := range method.Body {
+		for _, inst := range block.Instructions {
+			names = append(names, inst.Name)
+		}
+	}
+	return names
+}
+
+// Writes a class with a static int abs(int) method and a constant field
+func writeAbsClass(t *testing.T) []byte {
+	cw := NewClassWriter()
+	cw.Visit(V8, 0, ACC_PUBLIC|ACC_SUPER, "gen/Maths", "", ObjectClassName, nil)
+	cw.VisitSource("Maths.java", "")
+	fv := cw.VisitField(ACC_PUBLIC|ACC_STATIC|ACC_FINAL, "LIMIT", "I", "", int32(1000))
+	fv.VisitAnnotation("Ljava/lang/Deprecated;", true).VisitEnd()
+	fv.VisitEnd()
+
+	mw := cw.NewMethod(ACC_PUBLIC|ACC_STATIC, "abs", "(I)I", "", nil)
+	code := mw.Code()
+	positive := &Label{}
+	assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+	assert.Nil(t, code.WriteJump(IFGE, positive))
+	assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+	assert.Nil(t, code.WriteOp(INEG))
+	assert.Nil(t, code.WriteOp(IRETURN))
+	assert.Nil(t, code.WriteLabel(positive))
+	code.VisitFrame(Frame{Type: FrameSame})
+	assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+	assert.Nil(t, code.WriteOp(IRETURN))
+	code.VisitMaxs(1, 1)
+	code.VisitEnd()
+	mw.VisitEnd()
+	cw.VisitEnd()
+
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+	return b
+}
+
+func TestClassWriterWritesReadableClass(t *testing.T) {
+	class, err := (&ClassReader{}).ReadClassBytes(writeAbsClass(t))
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("gen/Maths"), class.Name)
+	assert.Equal(t, ObjectClassName, class.SuperName)
+	assert.Equal(t, "Maths.java", class.SourceName)
+
+	assert.Len(t, class.Fields, 1)
+	assert.Equal(t, "LIMIT", class.Fields[0].Name)
+	assert.Equal(t, int32(1000), class.Fields[0].Value)
+	assert.Equal(t, "Ljava/lang/Deprecated;", class.Fields[0].Annotations[0].Descriptor)
+
+	assert.Len(t, class.Methods, 1)
+	abs := class.Methods[0]
+	assert.Equal(t, "abs", abs.Name)
+	assert.Equal(t, uint16(1), abs.MaxStack)
+	assert.Equal(t, []string{"iload", "ifge", "iload", "ineg", "ireturn", "iload", "ireturn"}, opcodeNames(abs))
+}
+
+func TestClassWriterResolvesLabels(t *testing.T) {
+	cw := NewClassWriter()
+	cw.Visit(V8, 0, ACC_PUBLIC, "gen/Labels", "", ObjectClassName, nil)
+	code := cw.NewMethod(ACC_STATIC, "f", "()V", "", nil).Code()
+	start, end := &Label{}, &Label{}
+	assert.Nil(t, code.WriteLabel(start))
+	assert.Nil(t, code.WriteOp(NOP))
+	assert.Nil(t, code.WriteJump(GOTO, end))
+	assert.Nil(t, code.WriteJump(GOTO, start))
+	assert.Nil(t, code.WriteLabel(end))
+	assert.Nil(t, code.WriteOp(RETURN))
+	assert.Equal(t, 0, start.Offset)
+	assert.Equal(t, 7, end.Offset)
+	assert.NotNil(t, code.WriteLabel(end))
+
+	code.mw.VisitEnd()
+	_, err := cw.Bytes()
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{NOP, GOTO, 0, 6, GOTO, 0xFF, 0xFC, RETURN}, code.code.Bytes())
+}
+
+func TestClassWriterReportsUnwrittenLabels(t *testing.T) {
+	cw := NewClassWriter()
+	cw.Visit(V8, 0, ACC_PUBLIC, "gen/Labels", "", ObjectClassName, nil)
+	mw := cw.NewMethod(ACC_STATIC, "f", "()V", "", nil)
+	mw.VisitCode().VisitJumpInsn(GOTO, &Label{})
+	mw.VisitEnd()
+	_, err := cw.Bytes()
+	assert.True(t, errors.Is(err, InvalidInstructionError))
+}
+
+func TestCodeWriterChecksOperands(t *testing.T) {
+	code := NewClassWriter().NewMethod(ACC_STATIC, "f", "()V", "", nil).Code()
+	assert.True(t, errors.Is(code.WriteOp(ILOAD), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteOp(300), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteIntOp(BIPUSH, 300), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteIntOp(NEWARRAY, 2), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteVarOp(GETFIELD, 1), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteType(GETFIELD, "A"), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteJump(ILOAD, &Label{}), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteTableSwitch(0, 2, &Label{}, []*Label{{}}), InvalidInstructionError))
+	assert.True(t, errors.Is(code.WriteMultiANewArray("[[I", 0), InvalidInstructionError))
+	assert.Equal(t, 0, code.code.Len())
+}
+
+func TestCodeWriterUsesShortestEncodings(t *testing.T) {
+	code := NewClassWriter().NewMethod(ACC_STATIC, "f", "()V", "", nil).Code()
+	assert.Nil(t, code.WriteVarOp(ALOAD, 2))
+	assert.Nil(t, code.WriteVarOp(DSTORE, 3))
+	assert.Nil(t, code.WriteVarOp(ILOAD, 4))
+	assert.Nil(t, code.WriteVarOp(LLOAD, 300))
+	assert.Nil(t, code.WriteIinc(1, -1))
+	assert.Nil(t, code.WriteIinc(1, 200))
+	assert.Equal(t, []byte{
+		ALOAD_0 + 2,
+		DSTORE_0 + 3,
+		ILOAD, 4,
+		WIDE, LLOAD, 1, 44,
+		IINC, 1, 0xFF,
+		WIDE, IINC, 0, 1, 0, 200,
+	}, code.code.Bytes())
+}
+
+func TestClassWriterRewritesReadClass(t *testing.T) {
+	original := readTestBytes(t, "testdata/compiled/Hello.class")
+	cw := NewClassWriter()
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(original, cw))
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	for _, method := range []string{"<init>", "main"} {
+		expected := &methodTracer{method: method, code: &codeTracer{}}
+		assert.Nil(t, (&ClassReader{}).AcceptBytes(original, expected))
+		actual := &methodTracer{method: method, code: &codeTracer{}}
+		assert.Nil(t, (&ClassReader{}).AcceptBytes(b, actual))
+		assert.Equal(t, expected.code.trace, actual.code.trace)
+	}
+
+	expected, err := (&ClassReader{}).ReadClassBytes(original)
+	assert.Nil(t, err)
+	actual, err := (&ClassReader{}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Interfaces, actual.Interfaces)
+	assert.Equal(t, expected.SourceName, actual.SourceName)
+	assert.Equal(t, expected.Fields, actual.Fields)
+}
+
+// Decodes the pool entries referenced by a standard attribute
+type entriesCodec func(data []byte, pool ConstantPoolReader) []string
+
+func (c entriesCodec) Decode(data []byte, pool ConstantPoolReader) (interface{}, error) {
+	return c(data, pool), nil
+}
+
+func (entriesCodec) Encode(value interface{}, pool ConstantPoolWriter) ([]byte, error) {
+	return nil, errors.New("not encoded")
+}
+
+func TestClassWriterMovesReadAttributesToItsPool(t *testing.T) {
+	cw := NewClassWriter()
+	// The entries of the attributes are far from the start of the pool, unlike in a new pool
+	for _, padding := range []string{"a", "b", "c", "d", "e", "f"} {
+		cw.Pool().Utf8(padding)
+	}
+	cw.Visit(V8, 0, ACC_PUBLIC|ACC_INTERFACE|ACC_ABSTRACT, "gen/Outer", "", ObjectClassName, nil)
+	inner, outer, name := cw.Pool().Class("gen/Outer$Inner"), cw.Pool().Class("gen/Outer"), cw.Pool().Utf8("Inner")
+	data := []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, ACC_STATIC}
+	binary.BigEndian.PutUint16(data[2:], inner)
+	binary.BigEndian.PutUint16(data[4:], outer)
+	binary.BigEndian.PutUint16(data[6:], name)
+	cw.VisitAttribute(JAttribute{Name: "InnerClasses", Data: data})
+	mv := cw.VisitMethod(ACC_PUBLIC|ACC_ABSTRACT, "name", "(Ljava/lang/String;)Ljava/lang/String;", "", nil)
+	parameter := cw.Pool().Utf8("prefix")
+	mv.VisitAttribute(JAttribute{Name: "MethodParameters", Data: []byte{1, byte(parameter >> 8), byte(parameter), 0, 0}})
+	value := cw.Pool().Utf8("none")
+	mv.VisitAttribute(JAttribute{Name: "AnnotationDefault", Data: []byte{'s', byte(value >> 8), byte(value)}})
+	mv.VisitEnd()
+	cw.VisitEnd()
+	original, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	copied := NewClassWriter()
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(original, copied))
+	b, err := copied.Bytes()
+	assert.Nil(t, err)
+	assert.Less(t, len(b), len(original))
+
+	registry := NewAttributeRegistry()
+	registry.Register("InnerClasses", entriesCodec(func(data []byte, pool ConstantPoolReader) []string {
+		return []string{string(pool.Class(readUnsignedShort(data, 2))), string(pool.Class(readUnsignedShort(data, 4))),
+			pool.Utf8(readUnsignedShort(data, 6))}
+	}), ClassAttribute)
+	registry.Register("MethodParameters", entriesCodec(func(data []byte, pool ConstantPoolReader) []string {
+		return []string{pool.Utf8(readUnsignedShort(data, 1))}
+	}), MethodAttribute)
+	registry.Register("AnnotationDefault", entriesCodec(func(data []byte, pool ConstantPoolReader) []string {
+		return []string{pool.Utf8(readUnsignedShort(data, 1))}
+	}), MethodAttribute)
+	read, err := (&ClassReader{Attributes: registry}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"gen/Outer$Inner", "gen/Outer", "Inner"}, read.Attributes[0].Value)
+	assert.Equal(t, []string{"prefix"}, read.Methods[0].Attributes[0].Value)
+	assert.Equal(t, []string{"none"}, read.Methods[0].Attributes[1].Value)
+
+	// The attributes of a JavaClass are written back to the pool they were read from
+	jclass, err := (&ClassReader{}).ReadClassBytes(original)
+	assert.Nil(t, err)
+	var out bytes.Buffer
+	assert.Nil(t, jclass.Write(&out))
+	assert.Equal(t, original, out.Bytes())
+}
+
+func TestJavaClassWrite(t *testing.T) {
+	class := NewJavaClass("gen.Shape").Visibility(ACC_PUBLIC | ACC_ABSTRACT).Target(V8)
+	class.AddMethods([]JavaMethod{{
+		Name:        "area",
+		Modifiers:   ACC_PUBLIC | ACC_ABSTRACT,
+		Descriptor:  "()D",
+		Annotations: []Annotation{{Descriptor: "LPure;", Visible: true, Values: []AnnotationElement{{Name: "tags", Value: []interface{}{"a", "b"}}}}},
+	}})
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("gen/Shape"), read.Name)
+	assert.Equal(t, "area", read.Methods[0].Name)
+	assert.Equal(t, class.Methods[0].Annotations, read.Methods[0].Annotations)
+
+	// A Body without Instructions doesn't keep the operands of its instructions
+	class.Methods = []JavaMethod{{Name: "f", Modifiers: ACC_STATIC, Descriptor: "()V", Body: []BytesBlock{NewByteBlock()}}}
+	_, _ = class.Methods[0].Body[0].Add(BIPUSH)
+	assert.True(t, errors.Is(class.Write(&out), InvalidInstructionError))
+}
+
+type jumpTracer struct {
+	CodeVisitorAdapter
+	targets []int
+}
+
+func (t *jumpTracer) VisitJumpInsn(opcode int, target *Label) {
+	t.targets = append(t.targets, target.Offset)
+}
+
+func (t *jumpTracer) VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label) {
+	t.targets = append(t.targets, dflt.Offset)
+	for _, label := range labels {
+		t.targets = append(t.targets, label.Offset)
+	}
+}
+
+func TestClassWriterWidensLongJumps(t *testing.T) {
+	cw := NewClassWriter()
+	cw.Visit(V8, 0, ACC_PUBLIC, "gen/Long", "", ObjectClassName, nil)
+	mw := cw.NewMethod(ACC_STATIC, "f", "(I)V", "", nil)
+	code := mw.Code()
+	start, middle, end := &Label{}, &Label{}, &Label{}
+	assert.Nil(t, code.WriteLabel(start))
+	assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+	assert.Nil(t, code.WriteJump(IFEQ, end))
+	// The instruction following the goto_w of the widened ifeq needs a frame
+	code.VisitFrame(Frame{Type: FrameSame})
+	assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+	assert.Nil(t, code.WriteTableSwitch(0, 0, end, []*Label{middle}))
+	assert.Nil(t, code.WriteLabel(middle))
+	for i := 0; i < 40000; i++ {
+		assert.Nil(t, code.WriteOp(NOP))
+	}
+	assert.Nil(t, code.WriteJump(GOTO, start))
+	assert.Nil(t, code.WriteLabel(end))
+	assert.Nil(t, code.WriteOp(RETURN))
+	code.VisitMaxs(1, 1)
+	mw.VisitEnd()
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	// ifeq becomes ifne over a goto_w, which moves the switch by 5 bytes and changes its padding
+	written := code.code.Bytes()
+	assert.Equal(t, []byte{ILOAD_0, IFNE, 0, 8, GOTO_W}, written[:5])
+	assert.Equal(t, []byte{ILOAD_0, TABLESWITCH, 0, 0}, written[9:13])
+	assert.Equal(t, 28, middle.Offset)
+	assert.Equal(t, 40033, end.Offset)
+	assert.Equal(t, byte(GOTO_W), written[40028])
+
+	tracer := &methodTracer{method: "f"}
+	jumps := &jumpTracer{}
+	tracer.code = &codeTracer{CodeVisitorAdapter: CodeVisitorAdapter{Next: jumps}}
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(b, tracer))
+	assert.Equal(t, []int{9, 40033, 40033, 28, 0}, jumps.targets)
+
+	assert.Equal(t, 9, code.frames[0].offset)
+
+	assert.Equal(t, IFGT, oppositeJump(IFLE))
+	assert.Equal(t, IF_ACMPEQ, oppositeJump(IF_ACMPNE))
+	assert.Equal(t, IFNONNULL, oppositeJump(IFNULL))
+}
+
+func TestClassWriterNeedsFrameToWidenConditionalJumps(t *testing.T) {
+	write := func(version ClassVersion) error {
+		cw := NewClassWriter()
+		cw.Visit(version, 0, ACC_PUBLIC, "gen/Long", "", ObjectClassName, nil)
+		mw := cw.NewMethod(ACC_STATIC, "f", "(I)V", "", nil)
+		code := mw.Code()
+		end := &Label{}
+		assert.Nil(t, code.WriteVarOp(ILOAD, 0))
+		assert.Nil(t, code.WriteJump(IFEQ, end))
+		for i := 0; i < 40000; i++ {
+			assert.Nil(t, code.WriteOp(NOP))
+		}
+		assert.Nil(t, code.WriteLabel(end))
+		assert.Nil(t, code.WriteOp(RETURN))
+		code.VisitMaxs(1, 1)
+		mw.VisitEnd()
+		_, err := cw.Bytes()
+		return err
+	}
+	assert.True(t, errors.Is(write(V6), InvalidInstructionError))
+	// The older classes have no frames
+	assert.Nil(t, write(V5))
+}
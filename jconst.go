@@ -0,0 +1,44 @@
+package gytes
+
+import "fmt"
+
+// The kinds of method handles, see JVMS 4.4.8
+const (
+	REF_getField         = 1
+	REF_getStatic        = 2
+	REF_putField         = 3
+	REF_putStatic        = 4
+	REF_invokeVirtual    = 5
+	REF_invokeStatic     = 6
+	REF_invokeSpecial    = 7
+	REF_newInvokeSpecial = 8
+	REF_invokeInterface  = 9
+)
+
+// A Handle is the value of a CONSTANT_MethodHandle entry, a reference to a field or a method.
+type Handle struct {
+	Kind        int
+	Owner       ClassName
+	Name        string
+	Descriptor  string
+	IsInterface bool
+}
+
+func (h Handle) String() string {
+	return fmt.Sprintf("%s.%s%s (%d)", h.Owner, h.Name, h.Descriptor, h.Kind)
+}
+
+// A MethodType is the value of a CONSTANT_MethodType entry, it holds a method descriptor.
+type MethodType string
+
+// A ConstantDynamic is the value of a CONSTANT_Dynamic entry, a constant computed by
+// its bootstrap method the first time it's loaded.
+type ConstantDynamic struct {
+	Name       string
+	Descriptor string
+	Bootstrap  Handle
+	// The static arguments passed to the bootstrap method, each one of them is a
+	// loadable constant: int32, float32, int64, float64, string, ClassName, MethodType,
+	// Handle or ConstantDynamic
+	Arguments []interface{}
+}
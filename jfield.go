@@ -11,6 +11,20 @@ package gytes
 // }
 type JavaField struct {
 	Name       string
-	Modifiers  uint16
+	Modifiers  FieldAccess
 	Descriptor string
+	Signature  string
+	// The value of the ConstantValue attribute, nil if the field has none
+	Value       interface{}
+	Annotations []Annotation
+	// The attributes that are not decoded by gytes
+	Attributes []JAttribute
+	// The parts left to decode when the member is read with LazyMembers
+	lazy *lazyMember
+}
+
+// TypeName returns the class name of the field's type, if the field holds an object or an array.
+func (jf JavaField) TypeName() (ClassName, bool) {
+	name, err := ClassNameFromDescriptor(jf.Descriptor)
+	return name, err == nil
 }
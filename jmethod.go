@@ -13,19 +13,34 @@ import "fmt"
 // }
 type JavaMethod struct {
 	Name       string
-	Modifiers  uint16
+	Modifiers  MethodAccess
 	Descriptor string
+	Signature  string
 	MaxStack   uint16
 	MaxLocals  uint16
-	Exceptions []string
+	Exceptions []ClassName
 	// The offset in the original class file at which the code of this class starts
 	// This is computed at class read time by finding the Code attribute in the method's attribute list.
 	BodyOffset int
-	Body       []BytesBlock
+	// The opcodes of the code as read, see Instructions to edit the code. The methods read by a ClassReader
+	// have Instructions, so the changes made to their Body are not written
+	Body []BytesBlock
+	// The instructions of the code, with their operands. They're written instead of Body when they're set,
+	// JavaClass.Write raises MaxStack and MaxLocals if they need more
+	Instructions   *InsnList
+	TryCatchBlocks []TryCatchBlock
+	LocalVariables []LocalVariable
+	Annotations    []Annotation
+	// The attributes that are not decoded by gytes
+	Attributes []JAttribute
+	// The attributes of the Code attribute that are not decoded by gytes
+	CodeAttributes []JAttribute
+	// The parts left to decode when the member is read with LazyMembers
+	lazy *lazyMember
 }
 
 func (jm JavaMethod) String() string {
-	return fmt.Sprintf("Modifiers=%d Name=%s Descriptor=%s MaxStack=%d MaxLocals=%d BytesBlock=%v Exceptions=%v", jm.Modifiers,
+	return fmt.Sprintf("Modifiers=%v Name=%s Descriptor=%s MaxStack=%d MaxLocals=%d BytesBlock=%v Exceptions=%v", jm.Modifiers,
 		jm.Name,
 		jm.Descriptor,
 		jm.MaxStack,
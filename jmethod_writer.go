@@ -0,0 +1,809 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Returned when an instruction given to a CodeWriter is not valid, e.g. when its operands don't match its opcode
+var InvalidInstructionError = errors.New("Invalid instruction")
+
+// MethodWriter writes a method of a ClassWriter, the content of the method is given as the events of a MethodVisitor.
+type MethodWriter struct {
+	memberWriter
+	bv                          *ByteVector
+	access                      MethodAccess
+	name, descriptor, signature string
+	exceptions                  []ClassName
+	code                        *CodeWriter
+}
+
+// Code returns the writer of the method's body.
+func (mw *MethodWriter) Code() *CodeWriter {
+	if mw.code == nil {
+		mw.code = &CodeWriter{mw: mw, labels: make(map[*Label]int)}
+	}
+	return mw.code
+}
+
+func (mw *MethodWriter) VisitCode() CodeVisitor {
+	return mw.Code()
+}
+
+func (mw *MethodWriter) VisitEnd() {
+	pool := mw.cw.pool
+	mw.bv.PutU2(uint16(mw.access)).PutU2(pool.Utf8(mw.name)).PutU2(pool.Utf8(mw.descriptor))
+	mw.writeAttributes(mw.bv, mw.signature, func(attribute func(string, func(*ByteVector))) {
+		if mw.code != nil {
+			attribute("Code", func(bv *ByteVector) {
+				mw.cw.setErr(mw.code.write(bv))
+			})
+		}
+		if mw.exceptions != nil {
+			attribute("Exceptions", mw.cw.putClasses(mw.exceptions))
+		}
+	})
+}
+
+// CodeWriter emits the instructions of a method body. The Write methods check the instructions and return
+// their errors, the CodeVisitor methods call them and keep the first error for ClassWriter.Bytes.
+type CodeWriter struct {
+	mw   *MethodWriter
+	code ByteVector
+	// The offsets of the labels that are already written
+	labels     map[*Label]int
+	jumps      []jump
+	handlers   []tryCatchBlock
+	lines      []lineNumber
+	locals     []localVariable
+	frames     []frameAt
+	attributes []JAttribute
+	maxStack   int
+	maxLocals  int
+	// The instructions, as needed to compute the maxs and frames
+	insns []codeInsn
+	// The expanded frames before the reachable instructions, by offset, when the frames are computed
+	states map[int]Frame
+}
+
+// An instruction written by a CodeWriter, with what the analysis of the code needs to know about it
+type codeInsn struct {
+	offset int
+	// The canonical opcode, e.g. ILOAD for iload_0
+	opcode int
+	// The local variable of loads, stores, iinc and ret, the operand of bipush, sipush and newarray,
+	// and the dimensions of multianewarray
+	operand int
+	// The class of type instructions and the owner of field and method instructions
+	owner            ClassName
+	name, descriptor string
+	// The constant loaded by ldc
+	value interface{}
+	// The targets of jumps and switches, the default target of a switch comes first
+	targets []*Label
+}
+
+// A reference to a label from an instruction, patched once all the labels are written
+type jump struct {
+	label *Label
+	// The offset of the instruction and of the jump offset to patch
+	source, at int
+	wide       bool
+}
+
+type tryCatchBlock struct {
+	start, end, handler *Label
+	typ                 ClassName
+}
+
+type lineNumber struct {
+	line  int
+	start *Label
+}
+
+type localVariable struct {
+	name, descriptor, signature string
+	start, end                  *Label
+	index                       int
+}
+
+type frameAt struct {
+	offset int
+	frame  Frame
+}
+
+func (c *CodeWriter) setErr(err error) {
+	c.mw.cw.setErr(err)
+}
+
+// Records an instruction starting at the current position of the code.
+func (c *CodeWriter) add(insn codeInsn) {
+	insn.offset = c.code.Len()
+	c.insns = append(c.insns, insn)
+}
+
+// Returns an error if opcode is not an instruction of the given kind.
+func (c *CodeWriter) checkKind(opcode int, kind OperandKind) error {
+	if opcode < 0 || opcode >= len(ByteCodes) {
+		return fmt.Errorf("%w: unknown opcode %d", InvalidInstructionError, opcode)
+	}
+	if ByteCodes[opcode].Kind != kind {
+		return fmt.Errorf("%w: %s doesn't take these operands", InvalidInstructionError, ByteCodes[opcode].Name)
+	}
+	return nil
+}
+
+func (c *CodeWriter) invalid(opcode int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s %s", InvalidInstructionError, ByteCodes[opcode].Name, fmt.Sprintf(format, args...))
+}
+
+// WriteOp writes an instruction without operands.
+func (c *CodeWriter) WriteOp(opcode int) error {
+	if opcode < 0 || opcode >= len(ByteCodes) {
+		return fmt.Errorf("%w: unknown opcode %d", InvalidInstructionError, opcode)
+	}
+	if ByteCodes[opcode].Size != 0 {
+		return c.invalid(opcode, "takes operands")
+	}
+	switch {
+	case opcode >= ILOAD_0 && opcode <= ALOAD_3:
+		c.add(codeInsn{opcode: ILOAD + (opcode-ILOAD_0)/4, operand: (opcode - ILOAD_0) % 4})
+	case opcode >= ISTORE_0 && opcode <= ASTORE_3:
+		c.add(codeInsn{opcode: ISTORE + (opcode-ISTORE_0)/4, operand: (opcode - ISTORE_0) % 4})
+	default:
+		c.add(codeInsn{opcode: opcode})
+	}
+	c.code.PutU1(uint8(opcode))
+	return nil
+}
+
+// WriteIntOp writes a bipush, sipush or newarray instruction.
+func (c *CodeWriter) WriteIntOp(opcode, operand int) error {
+	if err := c.checkKind(opcode, KindIntInsn); err != nil {
+		return err
+	}
+	insn := codeInsn{opcode: opcode, operand: operand}
+	switch opcode {
+	case BIPUSH:
+		if operand < math.MinInt8 || operand > math.MaxInt8 {
+			return c.invalid(opcode, "operand %d doesn't fit in a byte", operand)
+		}
+		c.add(insn)
+		c.code.PutU1(BIPUSH).PutS1(int8(operand))
+	case SIPUSH:
+		if operand < math.MinInt16 || operand > math.MaxInt16 {
+			return c.invalid(opcode, "operand %d doesn't fit in a short", operand)
+		}
+		c.add(insn)
+		c.code.PutU1(SIPUSH).PutS2(int16(operand))
+	default:
+		if operand < T_BOOLEAN || operand > T_LONG {
+			return c.invalid(opcode, "unknown array type %d", operand)
+		}
+		c.add(insn)
+		c.code.PutU1(NEWARRAY).PutU1(uint8(operand))
+	}
+	return nil
+}
+
+// WriteVarOp writes a load, a store or a ret instruction, using the shortest encoding of the local variable index.
+func (c *CodeWriter) WriteVarOp(opcode, index int) error {
+	if err := c.checkKind(opcode, KindVarInsn); err != nil {
+		return err
+	}
+	if ByteCodes[opcode].Size == 0 {
+		return c.invalid(opcode, "has an implicit index, use WriteOp")
+	}
+	if index < 0 || index > math.MaxUint16 {
+		return c.invalid(opcode, "index %d is out of range", index)
+	}
+	c.add(codeInsn{opcode: opcode, operand: index})
+	switch {
+	case index <= 3 && opcode >= ILOAD && opcode <= ALOAD:
+		c.code.PutU1(uint8(ILOAD_0 + 4*(opcode-ILOAD) + index))
+	case index <= 3 && opcode >= ISTORE && opcode <= ASTORE:
+		c.code.PutU1(uint8(ISTORE_0 + 4*(opcode-ISTORE) + index))
+	case index > math.MaxUint8:
+		c.code.PutU1(WIDE).PutU1(uint8(opcode)).PutU2(uint16(index))
+	default:
+		c.code.PutU1(uint8(opcode)).PutU1(uint8(index))
+	}
+	return nil
+}
+
+// WriteType writes a new, anewarray, checkcast or instanceof instruction.
+func (c *CodeWriter) WriteType(opcode int, typ ClassName) error {
+	if err := c.checkKind(opcode, KindTypeInsn); err != nil {
+		return err
+	}
+	c.add(codeInsn{opcode: opcode, owner: typ})
+	c.code.PutU1(uint8(opcode)).PutU2(c.mw.cw.pool.Class(typ))
+	return nil
+}
+
+// WriteField writes a getstatic, putstatic, getfield or putfield instruction.
+func (c *CodeWriter) WriteField(opcode int, owner ClassName, name, descriptor string) error {
+	if err := c.checkKind(opcode, KindFieldInsn); err != nil {
+		return err
+	}
+	c.add(codeInsn{opcode: opcode, owner: owner, name: name, descriptor: descriptor})
+	c.code.PutU1(uint8(opcode)).PutU2(c.mw.cw.pool.Fieldref(owner, name, descriptor))
+	return nil
+}
+
+// WriteMethod writes an invokevirtual, invokespecial, invokestatic or invokeinterface instruction.
+func (c *CodeWriter) WriteMethod(opcode int, owner ClassName, name, descriptor string, isInterface bool) error {
+	if err := c.checkKind(opcode, KindMethodInsn); err != nil {
+		return err
+	}
+	args, _, err := SplitMethodDescriptor(descriptor)
+	if err != nil {
+		return err
+	}
+	c.add(codeInsn{opcode: opcode, owner: owner, name: name, descriptor: descriptor})
+	c.code.PutU1(uint8(opcode)).PutU2(c.mw.cw.pool.Methodref(owner, name, descriptor, isInterface))
+	if opcode == INVOKEINTERFACE {
+		// The size of the arguments, including the receiver
+		count := 1
+		for _, arg := range args {
+			count += descriptorSize(arg)
+		}
+		c.code.PutU1(uint8(count)).PutU1(0)
+	}
+	return nil
+}
+
+// WriteInvokeDynamic writes an invokedynamic instruction, its bootstrap method is added to the class.
+func (c *CodeWriter) WriteInvokeDynamic(name, descriptor string, bootstrap Handle, arguments []interface{}) error {
+	if _, _, err := SplitMethodDescriptor(descriptor); err != nil {
+		return err
+	}
+	c.add(codeInsn{opcode: INVOKEDYNAMIC, name: name, descriptor: descriptor})
+	c.code.PutU1(INVOKEDYNAMIC).PutU2(c.mw.cw.pool.InvokeDynamic(name, descriptor, bootstrap, arguments)).PutU2(0)
+	return nil
+}
+
+// WriteJump writes a jump to target, target can be written before or after the jump.
+// A jump whose target ends up more than 32 KB away is widened when the method is written: goto and jsr
+// become goto_w and jsr_w, and a conditional jump becomes the opposite condition jumping over a goto_w.
+// The instruction following such a goto_w needs a stack map frame in a class of version 50 or later, so
+// writing the method fails if the frames are not computed and none was visited there.
+func (c *CodeWriter) WriteJump(opcode int, target *Label) error {
+	if err := c.checkKind(opcode, KindJumpInsn); err != nil {
+		return err
+	}
+	c.add(codeInsn{opcode: opcode, targets: []*Label{target}})
+	source := c.code.Len()
+	c.code.PutU1(uint8(opcode))
+	if opcode == GOTO_W || opcode == JSR_W {
+		c.jumps = append(c.jumps, jump{label: target, source: source, at: c.code.Reserve4(), wide: true})
+	} else {
+		c.jumps = append(c.jumps, jump{label: target, source: source, at: c.code.Reserve2()})
+	}
+	return nil
+}
+
+// WriteLabel marks the current position of the code with label, and sets its Offset.
+func (c *CodeWriter) WriteLabel(label *Label) error {
+	if _, ok := c.labels[label]; ok {
+		return fmt.Errorf("%w: label %v is written twice", InvalidInstructionError, label)
+	}
+	label.Offset = c.code.Len()
+	c.labels[label] = label.Offset
+	return nil
+}
+
+// WriteLdc writes the ldc instruction loading value, using ldc_w or ldc2_w when needed.
+func (c *CodeWriter) WriteLdc(value interface{}) error {
+	c.add(codeInsn{opcode: LDC, value: value})
+	index := c.mw.cw.pool.Constant(value)
+	switch {
+	case isWideConstant(value):
+		c.code.PutU1(LDC2_W).PutU2(index)
+	case index > math.MaxUint8:
+		c.code.PutU1(LDC_W).PutU2(index)
+	default:
+		c.code.PutU1(LDC).PutU1(uint8(index))
+	}
+	return nil
+}
+
+// Tells whether value takes two slots on the operand stack.
+func isWideConstant(value interface{}) bool {
+	switch v := value.(type) {
+	case int64, float64:
+		return true
+	case ConstantDynamic:
+		return v.Descriptor == "J" || v.Descriptor == "D"
+	}
+	return false
+}
+
+// WriteIinc writes an iinc instruction, in its wide form if needed.
+func (c *CodeWriter) WriteIinc(index, increment int) error {
+	switch {
+	case index < 0 || index > math.MaxUint16:
+		return c.invalid(IINC, "index %d is out of range", index)
+	case increment < math.MinInt16 || increment > math.MaxInt16:
+		return c.invalid(IINC, "increment %d doesn't fit in a short", increment)
+	}
+	c.add(codeInsn{opcode: IINC, operand: index})
+	if index > math.MaxUint8 || increment < math.MinInt8 || increment > math.MaxInt8 {
+		c.code.PutU1(WIDE).PutU1(IINC).PutU2(uint16(index)).PutS2(int16(increment))
+	} else {
+		c.code.PutU1(IINC).PutU1(uint8(index)).PutS1(int8(increment))
+	}
+	return nil
+}
+
+// WriteTableSwitch writes a tableswitch jumping to labels[i] for the value min+i.
+func (c *CodeWriter) WriteTableSwitch(min, max int, dflt *Label, labels []*Label) error {
+	if max < min || len(labels) != max-min+1 {
+		return c.invalid(TABLESWITCH, "has %d labels for the range [%d, %d]", len(labels), min, max)
+	}
+	c.add(codeInsn{opcode: TABLESWITCH, targets: append([]*Label{dflt}, labels...)})
+	source := c.switchStart(TABLESWITCH)
+	c.switchTarget(source, dflt)
+	c.code.PutS4(int32(min)).PutS4(int32(max))
+	for _, label := range labels {
+		c.switchTarget(source, label)
+	}
+	return nil
+}
+
+// WriteLookupSwitch writes a lookupswitch jumping to labels[i] for the value keys[i], the keys don't need to be sorted.
+func (c *CodeWriter) WriteLookupSwitch(dflt *Label, keys []int, labels []*Label) error {
+	if len(keys) != len(labels) {
+		return c.invalid(LOOKUPSWITCH, "has %d keys and %d labels", len(keys), len(labels))
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+	c.add(codeInsn{opcode: LOOKUPSWITCH, targets: append([]*Label{dflt}, labels...)})
+	source := c.switchStart(LOOKUPSWITCH)
+	c.switchTarget(source, dflt)
+	c.code.PutU4(uint32(len(keys)))
+	for _, i := range order {
+		c.code.PutS4(int32(keys[i]))
+		c.switchTarget(source, labels[i])
+	}
+	return nil
+}
+
+// Writes the opcode of a switch and its padding, and returns the offset of the instruction.
+func (c *CodeWriter) switchStart(opcode int) int {
+	source := c.code.Len()
+	c.code.PutU1(uint8(opcode))
+	for c.code.Len()%4 != 0 {
+		c.code.PutU1(0)
+	}
+	return source
+}
+
+func (c *CodeWriter) switchTarget(source int, label *Label) {
+	c.jumps = append(c.jumps, jump{label: label, source: source, at: c.code.Reserve4(), wide: true})
+}
+
+// WriteMultiANewArray writes a multianewarray instruction creating an array of the given type.
+func (c *CodeWriter) WriteMultiANewArray(descriptor string, dimensions int) error {
+	if dimensions < 1 || dimensions > math.MaxUint8 {
+		return c.invalid(MULTIANEWARRAY, "dimensions %d are out of range", dimensions)
+	}
+	c.add(codeInsn{opcode: MULTIANEWARRAY, operand: dimensions, descriptor: descriptor})
+	c.code.PutU1(MULTIANEWARRAY).PutU2(c.mw.cw.pool.Class(ClassName(descriptor))).PutU1(uint8(dimensions))
+	return nil
+}
+
+func (c *CodeWriter) VisitInsn(opcode int)                    { c.setErr(c.WriteOp(opcode)) }
+func (c *CodeWriter) VisitIntInsn(opcode, operand int)        { c.setErr(c.WriteIntOp(opcode, operand)) }
+func (c *CodeWriter) VisitVarInsn(opcode, index int)          { c.setErr(c.WriteVarOp(opcode, index)) }
+func (c *CodeWriter) VisitTypeInsn(opcode int, typ ClassName) { c.setErr(c.WriteType(opcode, typ)) }
+func (c *CodeWriter) VisitFieldInsn(opcode int, owner ClassName, name, descriptor string) {
+	c.setErr(c.WriteField(opcode, owner, name, descriptor))
+}
+func (c *CodeWriter) VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool) {
+	c.setErr(c.WriteMethod(opcode, owner, name, descriptor, isInterface))
+}
+func (c *CodeWriter) VisitInvokeDynamicInsn(name, descriptor string, bootstrap Handle, arguments []interface{}) {
+	c.setErr(c.WriteInvokeDynamic(name, descriptor, bootstrap, arguments))
+}
+func (c *CodeWriter) VisitJumpInsn(opcode int, target *Label) { c.setErr(c.WriteJump(opcode, target)) }
+func (c *CodeWriter) VisitLabel(label *Label)                 { c.setErr(c.WriteLabel(label)) }
+func (c *CodeWriter) VisitLdcInsn(value interface{})          { c.setErr(c.WriteLdc(value)) }
+func (c *CodeWriter) VisitIincInsn(index, increment int)      { c.setErr(c.WriteIinc(index, increment)) }
+func (c *CodeWriter) VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label) {
+	c.setErr(c.WriteTableSwitch(min, max, dflt, labels))
+}
+func (c *CodeWriter) VisitLookupSwitchInsn(dflt *Label, keys []int, labels []*Label) {
+	c.setErr(c.WriteLookupSwitch(dflt, keys, labels))
+}
+func (c *CodeWriter) VisitMultiANewArrayInsn(descriptor string, dimensions int) {
+	c.setErr(c.WriteMultiANewArray(descriptor, dimensions))
+}
+
+func (c *CodeWriter) VisitTryCatchBlock(start, end, handler *Label, typ ClassName) {
+	c.handlers = append(c.handlers, tryCatchBlock{start, end, handler, typ})
+}
+
+func (c *CodeWriter) VisitLocalVariable(name, descriptor, signature string, start, end *Label, index int) {
+	c.locals = append(c.locals, localVariable{name, descriptor, signature, start, end, index})
+}
+
+func (c *CodeWriter) VisitLineNumber(line int, start *Label) {
+	c.lines = append(c.lines, lineNumber{line, start})
+}
+
+// VisitFrame adds the stack map frame of the current position of the code.
+func (c *CodeWriter) VisitFrame(frame Frame) {
+	c.frames = append(c.frames, frameAt{c.code.Len(), frame})
+}
+
+func (c *CodeWriter) VisitAttribute(attr JAttribute) {
+	c.attributes = append(c.attributes, attr)
+}
+
+func (c *CodeWriter) VisitMaxs(maxStack, maxLocals int) {
+	c.maxStack, c.maxLocals = maxStack, maxLocals
+}
+
+func (c *CodeWriter) VisitEnd() {
+}
+
+// Returns the offset of a label, or an error if it's not written.
+func (c *CodeWriter) offset(label *Label) (int, error) {
+	if offset, ok := c.labels[label]; ok {
+		return offset, nil
+	}
+	return 0, fmt.Errorf("%w: label %p of %s%s is used but never written", InvalidInstructionError, label, c.mw.name, c.mw.descriptor)
+}
+
+// Patches the offsets of the jumps. The jumps whose offset doesn't fit in a short are widened first, which moves
+// the following instructions and can push other jumps out of range, so this is repeated until all of them fit.
+func (c *CodeWriter) resolveJumps() error {
+	for {
+		overflows := make(map[int]bool)
+		for _, j := range c.jumps {
+			target, err := c.offset(j.label)
+			if err != nil {
+				return err
+			}
+			if delta := target - j.source; !j.wide && (delta < math.MinInt16 || delta > math.MaxInt16) {
+				overflows[j.source] = true
+			}
+		}
+		if len(overflows) == 0 {
+			break
+		}
+		if err := c.widenJumps(overflows); err != nil {
+			return err
+		}
+	}
+	for _, j := range c.jumps {
+		delta := c.labels[j.label] - j.source
+		if j.wide {
+			c.code.PatchU4(j.at, uint32(int32(delta)))
+		} else {
+			c.code.PatchU2(j.at, uint16(int16(delta)))
+		}
+	}
+	return nil
+}
+
+// Rewrites the code with the jumps at the given offsets widened: goto and jsr become goto_w and jsr_w,
+// and a conditional jump becomes the opposite condition jumping over a goto_w to the original target.
+// The offsets of the labels, jumps and frames are moved accordingly, and the switches are padded again.
+func (c *CodeWriter) widenJumps(overflows map[int]bool) error {
+	old := c.code.Bytes()
+	jumps := make(map[int][]int)
+	for i, j := range c.jumps {
+		jumps[j.source] = append(jumps[j.source], i)
+	}
+	var code ByteVector
+	// The new offset of each instruction, and of the end of the code
+	moved := make(map[int]int)
+	// The offsets following the widened conditional jumps, which become jump targets
+	var targets []int
+	for pc := 0; pc < len(old); {
+		size := instructionSize(old, pc, 0)
+		op := int(old[pc])
+		moved[pc] = code.Len()
+		switch {
+		case overflows[pc]:
+			j := &c.jumps[jumps[pc][0]]
+			switch op {
+			case GOTO:
+				code.PutU1(GOTO_W)
+			case JSR:
+				code.PutU1(JSR_W)
+			default:
+				if c.states == nil && c.mw.cw.version >= V6 && !c.hasFrame(pc+size) {
+					return c.invalid(op, "at offset %d of %s%s must be widened, which needs a stack map frame at offset %d",
+						pc, c.mw.name, c.mw.descriptor, pc+size)
+				}
+				code.PutU1(uint8(oppositeJump(op))).PutS2(8)
+				code.PutU1(GOTO_W)
+				targets = append(targets, pc+size)
+			}
+			j.source, j.at, j.wide = code.Len()-1, code.Reserve4(), true
+		case op == TABLESWITCH || op == LOOKUPSWITCH:
+			code.PutU1(uint8(op))
+			for code.Len()%4 != 0 {
+				code.PutU1(0)
+			}
+			// The content of the switch, without its opcode and padding
+			content, start := pc+1+(3-pc%4), code.Len()
+			code.PutBytes(old[content : pc+size])
+			for _, i := range jumps[pc] {
+				c.jumps[i].source, c.jumps[i].at = moved[pc], start+c.jumps[i].at-content
+			}
+		default:
+			code.PutBytes(old[pc : pc+size])
+			for _, i := range jumps[pc] {
+				c.jumps[i].source, c.jumps[i].at = moved[pc], moved[pc]+c.jumps[i].at-pc
+			}
+		}
+		pc += size
+	}
+	moved[len(old)] = code.Len()
+	for label, offset := range c.labels {
+		c.labels[label], label.Offset = moved[offset], moved[offset]
+	}
+	for i := range c.frames {
+		c.frames[i].offset = moved[c.frames[i].offset]
+	}
+	if c.states != nil {
+		states := make(map[int]Frame, len(c.states))
+		for offset, frame := range c.states {
+			states[moved[offset]] = frame
+		}
+		c.states = states
+		for _, target := range targets {
+			c.addFrame(moved[target])
+		}
+	}
+	c.code = code
+	return nil
+}
+
+// Tells whether a frame was visited at offset.
+func (c *CodeWriter) hasFrame(offset int) bool {
+	for _, frame := range c.frames {
+		if frame.offset == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the computed frame of the instruction at offset, if it has none yet.
+func (c *CodeWriter) addFrame(offset int) {
+	i := sort.Search(len(c.frames), func(i int) bool { return c.frames[i].offset >= offset })
+	if i < len(c.frames) && c.frames[i].offset == offset {
+		return
+	}
+	c.frames = append(c.frames, frameAt{})
+	copy(c.frames[i+1:], c.frames[i:])
+	c.frames[i] = frameAt{offset, c.states[offset]}
+}
+
+// Returns the conditional jump taken when the one of opcode isn't.
+func oppositeJump(opcode int) int {
+	if opcode == IFNULL || opcode == IFNONNULL {
+		return IFNULL + IFNONNULL - opcode
+	}
+	// The conditions come in pairs, e.g. ifeq and ifne
+	return IFEQ + ((opcode - IFEQ) ^ 1)
+}
+
+// Writes the content of the Code attribute.
+func (c *CodeWriter) write(bv *ByteVector) error {
+	pool := c.mw.cw.pool
+	options := c.mw.cw.Options
+	switch {
+	case options&(ComputeMaxs|ComputeFrames) != 0:
+		if err := c.computeMaxs(); err != nil {
+			return err
+		}
+	case c.mw.cw.raiseMaxs:
+		maxStack, maxLocals := c.maxStack, c.maxLocals
+		if err := c.computeMaxs(); err != nil {
+			return err
+		}
+		if c.maxStack < maxStack {
+			c.maxStack = maxStack
+		}
+		if c.maxLocals < maxLocals {
+			c.maxLocals = maxLocals
+		}
+	}
+	if options&ComputeFrames != 0 {
+		// The classes older than version 50 have no frames
+		c.frames = nil
+		if c.mw.cw.version >= V6 {
+			if err := c.computeFrames(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.resolveJumps(); err != nil {
+		return err
+	}
+	code := c.code.Bytes()
+	if len(code) == 0 || len(code) > math.MaxUint16 {
+		return fmt.Errorf("%w: the code of %s%s is %d bytes long", InvalidInstructionError, c.mw.name, c.mw.descriptor, len(code))
+	}
+	bv.PutU2(uint16(c.maxStack)).PutU2(uint16(c.maxLocals)).PutU4(uint32(len(code))).PutBytes(code)
+
+	bv.PutU2(uint16(len(c.handlers)))
+	for _, h := range c.handlers {
+		start, err := c.offset(h.start)
+		if err != nil {
+			return err
+		}
+		end, err := c.offset(h.end)
+		if err != nil {
+			return err
+		}
+		handler, err := c.offset(h.handler)
+		if err != nil {
+			return err
+		}
+		var typ uint16
+		if h.typ != "" {
+			typ = pool.Class(h.typ)
+		}
+		bv.PutU2(uint16(start)).PutU2(uint16(end)).PutU2(uint16(handler)).PutU2(typ)
+	}
+
+	count := bv.Reserve2()
+	n := 0
+	var err error
+	attribute := func(name string, content func(*ByteVector)) {
+		c.mw.cw.putAttribute(bv, name, content)
+		n++
+	}
+	if len(c.lines) > 0 {
+		attribute("LineNumberTable", func(bv *ByteVector) {
+			bv.PutU2(uint16(len(c.lines)))
+			for _, line := range c.lines {
+				start, e := c.offset(line.start)
+				if e != nil {
+					err = e
+				}
+				bv.PutU2(uint16(start)).PutU2(uint16(line.line))
+			}
+		})
+	}
+	if len(c.locals) > 0 {
+		putLocals := func(bv *ByteVector, typed bool) {
+			count := bv.Reserve2()
+			n := 0
+			for _, local := range c.locals {
+				if typed && local.signature == "" {
+					continue
+				}
+				start, e := c.offset(local.start)
+				if e != nil {
+					err = e
+				}
+				end, e := c.offset(local.end)
+				if e != nil {
+					err = e
+				}
+				descriptor := local.descriptor
+				if typed {
+					descriptor = local.signature
+				}
+				bv.PutU2(uint16(start)).PutU2(uint16(end - start))
+				bv.PutU2(pool.Utf8(local.name)).PutU2(pool.Utf8(descriptor)).PutU2(uint16(local.index))
+				n++
+			}
+			bv.PatchU2(count, uint16(n))
+		}
+		attribute("LocalVariableTable", func(bv *ByteVector) { putLocals(bv, false) })
+		for _, local := range c.locals {
+			if local.signature != "" {
+				attribute("LocalVariableTypeTable", func(bv *ByteVector) { putLocals(bv, true) })
+				break
+			}
+		}
+	}
+	if len(c.frames) > 0 {
+		attribute("StackMapTable", func(bv *ByteVector) {
+			if e := c.putFrames(bv); e != nil {
+				err = e
+			}
+		})
+	}
+	for _, attr := range c.attributes {
+		c.mw.cw.putJAttribute(bv, attr, CodeAttribute)
+		n++
+	}
+	bv.PatchU2(count, uint16(n))
+	return err
+}
+
+// Writes the content of the StackMapTable attribute, see JVMS 4.7.4
+func (c *CodeWriter) putFrames(bv *ByteVector) error {
+	bv.PutU2(uint16(len(c.frames)))
+	locals, err := initialFrameLocals(c.mw.cw.name, c.mw.access, c.mw.name, c.mw.descriptor)
+	if err != nil {
+		return err
+	}
+	previous := -1
+	for _, f := range c.frames {
+		delta := f.offset - previous - 1
+		if delta < 0 {
+			return fmt.Errorf("%w: two stack map frames at offset %d of %s%s", InvalidInstructionError, f.offset, c.mw.name, c.mw.descriptor)
+		}
+		previous = f.offset
+		// The expanded frames are written in their most compact form
+		frame := compressFrame(locals, f.frame)
+		if locals, err = frameLocals(locals, frame); err != nil {
+			return fmt.Errorf("%w: %s at offset %d of %s%s", InvalidInstructionError, err, f.offset, c.mw.name, c.mw.descriptor)
+		}
+		switch frame.Type {
+		case FrameSame:
+			if delta < 64 {
+				bv.PutU1(uint8(delta))
+			} else {
+				bv.PutU1(251).PutU2(uint16(delta))
+			}
+		case FrameSame1:
+			if len(frame.Stack) != 1 {
+				return fmt.Errorf("%w: same_locals_1_stack_item frame with %d stack values", InvalidInstructionError, len(frame.Stack))
+			}
+			if delta < 64 {
+				bv.PutU1(uint8(64 + delta))
+			} else {
+				bv.PutU1(247).PutU2(uint16(delta))
+			}
+			err = c.putVerificationTypes(bv, frame.Stack)
+		case FrameChop:
+			if frame.Chopped < 1 || frame.Chopped > 3 {
+				return fmt.Errorf("%w: chop frame removing %d locals", InvalidInstructionError, frame.Chopped)
+			}
+			bv.PutU1(uint8(251 - frame.Chopped)).PutU2(uint16(delta))
+		case FrameAppend:
+			if len(frame.Locals) < 1 || len(frame.Locals) > 3 {
+				return fmt.Errorf("%w: append frame adding %d locals", InvalidInstructionError, len(frame.Locals))
+			}
+			bv.PutU1(uint8(251 + len(frame.Locals))).PutU2(uint16(delta))
+			err = c.putVerificationTypes(bv, frame.Locals)
+		default:
+			bv.PutU1(255).PutU2(uint16(delta)).PutU2(uint16(len(frame.Locals)))
+			if err = c.putVerificationTypes(bv, frame.Locals); err == nil {
+				bv.PutU2(uint16(len(frame.Stack)))
+				err = c.putVerificationTypes(bv, frame.Stack)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CodeWriter) putVerificationTypes(bv *ByteVector, types []interface{}) error {
+	for _, t := range types {
+		switch v := t.(type) {
+		case VerificationType:
+			bv.PutU1(uint8(v))
+		case ClassName:
+			bv.PutU1(7).PutU2(c.mw.cw.pool.Class(v))
+		case *Label:
+			offset, err := c.offset(v)
+			if err != nil {
+				return err
+			}
+			bv.PutU1(8).PutU2(uint16(offset))
+		default:
+			return fmt.Errorf("%w: %v of type %T is not a verification type", InvalidInstructionError, t, t)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,381 @@
 package gytes
 
-type ConstantPoolEntry struct {
-	Index uint32
-}
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Returned when a value can't be stored in the constant pool
+var InvalidConstantError = errors.New("Invalid constant")
+
+// Returned when a class needs more constant pool entries than a class file can hold
+var ConstantPoolOverflowError = errors.New("Constant pool overflow")
 
 type ConstantPool struct {
 	Size uint16
+	// The tag of every entry in the pool, indexed by the entry's index, unused slots
+	// (index 0 and the slot following a long or a double) have a zero tag
+	Tags []uint8
+	// The constant_pool items and the content of the BootstrapMethods attribute of the class file
+	// the pool was read from, they seed the ConstantPoolBuilder of the class when it's written
+	entries, bootstrapMethods []byte
+}
+
+// ConstantPoolBuilder hands out the indexes of the constant pool entries of a class being written,
+// adding each distinct entry once. It implements ConstantPoolWriter.
+//
+// The methods don't return errors, the first one is kept and returned by Err, the indexes returned
+// after an error are meaningless.
+type ConstantPoolBuilder struct {
+	// The entries, in the format of the constant_pool table
+	entries ByteVector
+	// The index of the next entry
+	count   uint16
+	indexes map[poolKey]uint16
+	// The content of the BootstrapMethods attribute
+	bootstrapMethods     ByteVector
+	bootstrapMethodCount uint16
+	bootstrapIndexes     map[string]uint16
+	// The constant_pool items of the class file the builder was seeded from, if any
+	origin []byte
+	err    error
+}
+
+// The identity of an entry: its tag and its content
+type poolKey struct {
+	tag     uint8
+	a, b, c string
+	value   uint64
+}
+
+func NewConstantPoolBuilder() *ConstantPoolBuilder {
+	return &ConstantPoolBuilder{
+		count:            1,
+		indexes:          make(map[poolKey]uint16),
+		bootstrapIndexes: make(map[string]uint16),
+	}
+}
+
+// NewConstantPoolBuilderFrom creates a builder holding the entries of a pool read by ClassReader at their original
+// indexes, along with the original bootstrap methods, so that writing an unmodified class keeps its indexes.
+// The entries that are not found in the pool are added after the original ones.
+func NewConstantPoolBuilderFrom(pool ConstantPool) (*ConstantPoolBuilder, error) {
+	p := NewConstantPoolBuilder()
+	if pool.entries == nil {
+		return p, nil
+	}
+	err := (&classParser{}).protect(0, "constant pool", func() error {
+		return p.seed(pool)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Adds the entries of a read pool, the keys of the entries are computed from their content.
+func (p *ConstantPoolBuilder) seed(pool ConstantPool) error {
+	b := pool.entries
+	offsets := make([]int, pool.Size)
+	for i, ptr := 1, 0; i < int(pool.Size); i++ {
+		offsets[i] = ptr + 1
+		tag := int(b[ptr])
+		size, ok := ConstSizeMap[tag]
+		if tag == ConstUtf8 {
+			size, ok = 3+int(readUnsignedShort(b, ptr+1)), true
+		}
+		if !ok {
+			return fmt.Errorf("%w: unknown constant pool tag %d", MalformedClassError, tag)
+		}
+		ptr += size
+		if tag == ConstLong || tag == ConstDouble {
+			i++
+		}
+	}
+	utf8 := func(index uint16) string {
+		offset := offsets[index]
+		return decodeModifiedUTF8(b[offset+2 : offset+2+int(readUnsignedShort(b, offset))])
+	}
+	// The content of the entries holding the index of a Utf8 entry
+	indirect := func(index uint16) string {
+		return utf8(readUnsignedShort(b, offsets[index]))
+	}
+	memberRef := func(index uint16) poolKey {
+		offset := offsets[index]
+		nameAndType := offsets[readUnsignedShort(b, offset+2)]
+		return poolKey{
+			tag: b[offset-1],
+			a:   indirect(readUnsignedShort(b, offset)),
+			b:   utf8(readUnsignedShort(b, nameAndType)),
+			c:   utf8(readUnsignedShort(b, nameAndType+2)),
+		}
+	}
+
+	for i, offset := range offsets {
+		if offset == 0 {
+			continue
+		}
+		key := poolKey{tag: b[offset-1]}
+		switch key.tag {
+		case ConstUtf8:
+			key.a = utf8(uint16(i))
+		case ConstClass, ConstString, ConstMethodType, ConstModule, ConstPackage:
+			key.a = indirect(uint16(i))
+		case ConstInteger, ConstFloat:
+			key.value = uint64(readUnsignedInt(b, offset))
+		case ConstLong, ConstDouble:
+			key.value = readUnsignedLong(b, offset)
+		case ConstNameAndType:
+			key.a, key.b = utf8(readUnsignedShort(b, offset)), utf8(readUnsignedShort(b, offset+2))
+		case ConstFieldref, ConstMethodref, ConstInterfaceMethodref:
+			key = memberRef(uint16(i))
+		case ConstMethodHandle:
+			ref := memberRef(readUnsignedShort(b, offset+1))
+			key.a, key.b, key.c, key.value = ref.a, ref.b, ref.c, uint64(b[offset])
+			if ref.tag == ConstInterfaceMethodref {
+				key.value |= 1 << 32
+			}
+		case ConstDynamic, ConstInvokeDynamic:
+			nameAndType := offsets[readUnsignedShort(b, offset+2)]
+			key.a, key.b = utf8(readUnsignedShort(b, nameAndType)), utf8(readUnsignedShort(b, nameAndType+2))
+			key.value = uint64(readUnsignedShort(b, offset))
+		}
+		// The first of two identical entries is the one reused
+		if _, ok := p.indexes[key]; !ok {
+			p.indexes[key] = uint16(i)
+		}
+	}
+	p.entries.PutBytes(b)
+	p.count = pool.Size
+	p.origin = b
+
+	if bootstrap := pool.bootstrapMethods; bootstrap != nil {
+		count := readUnsignedShort(bootstrap, 0)
+		for i, ptr := uint16(0), 2; i < count; i++ {
+			indexes := make([]uint16, 1+readUnsignedShort(bootstrap, ptr+2))
+			indexes[0] = readUnsignedShort(bootstrap, ptr)
+			for j := 1; j < len(indexes); j++ {
+				indexes[j] = readUnsignedShort(bootstrap, ptr+2+2*j)
+			}
+			if _, ok := p.bootstrapIndexes[fmt.Sprint(indexes)]; !ok {
+				p.bootstrapIndexes[fmt.Sprint(indexes)] = i
+			}
+			ptr += 2 + 2*len(indexes)
+		}
+		p.bootstrapMethods.PutBytes(bootstrap[2:])
+		p.bootstrapMethodCount = count
+	}
+	return nil
+}
+
+// Count returns the constant_pool_count of the class, i.e. the number of entries plus one.
+func (p *ConstantPoolBuilder) Count() uint16 {
+	return p.count
+}
+
+// Err returns the first error that happened while adding entries.
+func (p *ConstantPoolBuilder) Err() error {
+	return p.err
+}
+
+func (p *ConstantPoolBuilder) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Returns the index of the entry with the given key, calling put to add it to the pool if it isn't there yet.
+func (p *ConstantPoolBuilder) entry(key poolKey, put func(*ByteVector)) uint16 {
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	slots := 1
+	if key.tag == ConstLong || key.tag == ConstDouble {
+		slots = 2
+	}
+	// constant_pool_count is a u2, so the last usable index is 65534
+	if int(p.count)+slots > math.MaxUint16 {
+		p.setErr(fmt.Errorf("%w: the pool is full with %d entries", ConstantPoolOverflowError, p.count-1))
+		return 0
+	}
+	index := p.count
+	p.entries.PutU1(key.tag)
+	put(&p.entries)
+	// 8 byte constants take two entries in the pool
+	p.count += uint16(slots)
+	p.indexes[key] = index
+	return index
+}
+
+func (p *ConstantPoolBuilder) Utf8(value string) uint16 {
+	return p.entry(poolKey{tag: ConstUtf8, a: value}, func(bv *ByteVector) {
+		if err := bv.PutUTF8(value); err != nil {
+			p.setErr(err)
+		}
+	})
+}
+
+func (p *ConstantPoolBuilder) Class(name ClassName) uint16 {
+	return p.utf8Entry(ConstClass, string(name))
+}
+
+func (p *ConstantPoolBuilder) String(value string) uint16 {
+	return p.utf8Entry(ConstString, value)
+}
+
+func (p *ConstantPoolBuilder) MethodType(descriptor MethodType) uint16 {
+	return p.utf8Entry(ConstMethodType, string(descriptor))
+}
+
+// Adds an entry holding the index of a CONSTANT_Utf8 entry.
+func (p *ConstantPoolBuilder) utf8Entry(tag uint8, value string) uint16 {
+	key := poolKey{tag: tag, a: value}
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	utf8 := p.Utf8(value)
+	return p.entry(key, func(bv *ByteVector) { bv.PutU2(utf8) })
+}
+
+func (p *ConstantPoolBuilder) Integer(value int32) uint16 {
+	return p.entry(poolKey{tag: ConstInteger, value: uint64(uint32(value))}, func(bv *ByteVector) { bv.PutS4(value) })
+}
+
+func (p *ConstantPoolBuilder) Float(value float32) uint16 {
+	bits := math.Float32bits(value)
+	return p.entry(poolKey{tag: ConstFloat, value: uint64(bits)}, func(bv *ByteVector) { bv.PutU4(bits) })
+}
+
+func (p *ConstantPoolBuilder) Long(value int64) uint16 {
+	return p.entry(poolKey{tag: ConstLong, value: uint64(value)}, func(bv *ByteVector) { bv.PutS8(value) })
+}
+
+func (p *ConstantPoolBuilder) Double(value float64) uint16 {
+	bits := math.Float64bits(value)
+	return p.entry(poolKey{tag: ConstDouble, value: bits}, func(bv *ByteVector) { bv.PutU8(bits) })
+}
+
+func (p *ConstantPoolBuilder) NameAndType(name, descriptor string) uint16 {
+	key := poolKey{tag: ConstNameAndType, a: name, b: descriptor}
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	nameIndex, descriptorIndex := p.Utf8(name), p.Utf8(descriptor)
+	return p.entry(key, func(bv *ByteVector) { bv.PutU2(nameIndex).PutU2(descriptorIndex) })
+}
+
+func (p *ConstantPoolBuilder) Fieldref(owner ClassName, name, descriptor string) uint16 {
+	return p.memberRef(ConstFieldref, owner, name, descriptor)
+}
+
+// Methodref adds a CONSTANT_Methodref entry, or a CONSTANT_InterfaceMethodref if the owner is an interface.
+func (p *ConstantPoolBuilder) Methodref(owner ClassName, name, descriptor string, isInterface bool) uint16 {
+	if isInterface {
+		return p.memberRef(ConstInterfaceMethodref, owner, name, descriptor)
+	}
+	return p.memberRef(ConstMethodref, owner, name, descriptor)
+}
+
+func (p *ConstantPoolBuilder) memberRef(tag uint8, owner ClassName, name, descriptor string) uint16 {
+	key := poolKey{tag: tag, a: string(owner), b: name, c: descriptor}
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	class, nameAndType := p.Class(owner), p.NameAndType(name, descriptor)
+	return p.entry(key, func(bv *ByteVector) { bv.PutU2(class).PutU2(nameAndType) })
+}
+
+func (p *ConstantPoolBuilder) MethodHandle(handle Handle) uint16 {
+	key := poolKey{tag: ConstMethodHandle, a: string(handle.Owner), b: handle.Name, c: handle.Descriptor, value: uint64(handle.Kind)}
+	if handle.IsInterface {
+		key.value |= 1 << 32
+	}
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	var reference uint16
+	if handle.Kind <= REF_putStatic {
+		reference = p.Fieldref(handle.Owner, handle.Name, handle.Descriptor)
+	} else {
+		reference = p.Methodref(handle.Owner, handle.Name, handle.Descriptor, handle.IsInterface)
+	}
+	return p.entry(key, func(bv *ByteVector) { bv.PutU1(uint8(handle.Kind)).PutU2(reference) })
+}
+
+// Dynamic adds a CONSTANT_Dynamic entry along with its bootstrap method.
+func (p *ConstantPoolBuilder) Dynamic(constant ConstantDynamic) uint16 {
+	return p.dynamic(ConstDynamic, constant.Name, constant.Descriptor, constant.Bootstrap, constant.Arguments)
+}
+
+// InvokeDynamic adds a CONSTANT_InvokeDynamic entry along with its bootstrap method.
+func (p *ConstantPoolBuilder) InvokeDynamic(name, descriptor string, bootstrap Handle, arguments []interface{}) uint16 {
+	return p.dynamic(ConstInvokeDynamic, name, descriptor, bootstrap, arguments)
+}
+
+func (p *ConstantPoolBuilder) dynamic(tag uint8, name, descriptor string, bootstrap Handle, arguments []interface{}) uint16 {
+	bootstrapIndex := p.BootstrapMethod(bootstrap, arguments)
+	key := poolKey{tag: tag, a: name, b: descriptor, value: uint64(bootstrapIndex)}
+	if index, ok := p.indexes[key]; ok {
+		return index
+	}
+	nameAndType := p.NameAndType(name, descriptor)
+	return p.entry(key, func(bv *ByteVector) { bv.PutU2(bootstrapIndex).PutU2(nameAndType) })
+}
+
+// BootstrapMethod adds an entry to the BootstrapMethods attribute and returns its index in the attribute.
+func (p *ConstantPoolBuilder) BootstrapMethod(handle Handle, arguments []interface{}) uint16 {
+	indexes := make([]uint16, len(arguments)+1)
+	indexes[0] = p.MethodHandle(handle)
+	for i, argument := range arguments {
+		indexes[i+1] = p.Constant(argument)
+	}
+	key := fmt.Sprint(indexes)
+	if index, ok := p.bootstrapIndexes[key]; ok {
+		return index
+	}
+	if p.bootstrapMethodCount == math.MaxUint16 {
+		p.setErr(fmt.Errorf("%w: the BootstrapMethods attribute is full", ConstantPoolOverflowError))
+		return 0
+	}
+	p.bootstrapMethods.PutU2(indexes[0]).PutU2(uint16(len(arguments)))
+	for _, index := range indexes[1:] {
+		p.bootstrapMethods.PutU2(index)
+	}
+	index := p.bootstrapMethodCount
+	p.bootstrapMethodCount++
+	p.bootstrapIndexes[key] = index
+	return index
+}
+
+// Constant adds a loadable constant, one of int32, float32, int64, float64, string, ClassName, MethodType,
+// Handle or ConstantDynamic.
+func (p *ConstantPoolBuilder) Constant(value interface{}) uint16 {
+	switch v := value.(type) {
+	case int32:
+		return p.Integer(v)
+	case float32:
+		return p.Float(v)
+	case int64:
+		return p.Long(v)
+	case float64:
+		return p.Double(v)
+	case string:
+		return p.String(v)
+	case ClassName:
+		return p.Class(v)
+	case MethodType:
+		return p.MethodType(v)
+	case Handle:
+		return p.MethodHandle(v)
+	case ConstantDynamic:
+		return p.Dynamic(v)
+	}
+	p.setErr(fmt.Errorf("%w: %v of type %T", InvalidConstantError, value, value))
+	return 0
+}
+
+// Writes the constant_pool_count and constant_pool items of the class.
+func (p *ConstantPoolBuilder) write(bv *ByteVector) {
+	bv.PutU2(p.count).PutBytes(p.entries.Bytes())
 }
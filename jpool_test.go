@@ -0,0 +1,75 @@
+package gytes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstantPoolBuilderDeduplicatesEntries(t *testing.T) {
+	p := NewConstantPoolBuilder()
+	object := p.Class(ObjectClassName)
+	assert.Equal(t, uint16(2), object)
+	assert.Equal(t, uint16(1), p.Utf8(string(ObjectClassName)))
+	assert.Equal(t, object, p.Class(ObjectClassName))
+
+	long := p.Long(42)
+	assert.Equal(t, long, p.Constant(int64(42)))
+	// Longs and doubles take two slots
+	assert.Equal(t, long+2, p.Integer(42))
+	assert.Equal(t, long+3, p.Float(42))
+	assert.Equal(t, long+4, p.Double(42))
+	assert.Equal(t, long+6, p.Count())
+
+	init := p.Methodref(ObjectClassName, "<init>", "()V", false)
+	assert.Equal(t, init, p.Methodref(ObjectClassName, "<init>", "()V", false))
+	assert.NotEqual(t, init, p.Methodref(ObjectClassName, "<init>", "()V", true))
+	assert.Nil(t, p.Err())
+}
+
+func TestConstantPoolBuilderFailsWhenFull(t *testing.T) {
+	p := NewConstantPoolBuilder()
+	for i := int32(1); i < 65535; i++ {
+		p.Integer(i)
+	}
+	assert.Nil(t, p.Err())
+	assert.Equal(t, uint16(65535), p.Count())
+	assert.Equal(t, uint16(1), p.Integer(1))
+
+	assert.Equal(t, uint16(0), p.Integer(-1))
+	assert.True(t, errors.Is(p.Err(), ConstantPoolOverflowError))
+	assert.Equal(t, uint16(65535), p.Count())
+}
+
+func TestConstantPoolBuilderSeededFromReadClass(t *testing.T) {
+	original := readTestBytes(t, "testdata/compiled/Hello.class")
+	class, err := (&ClassReader{}).ReadClassBytes(original)
+	assert.Nil(t, err)
+	p, err := NewConstantPoolBuilderFrom(class.CPool)
+	assert.Nil(t, err)
+	assert.Equal(t, class.PoolCount, p.Count())
+
+	// The entries of the class are found at their original indexes
+	p.Class("Hello")
+	p.String("Hello world")
+	p.Fieldref("java/lang/System", "out", "Ljava/io/PrintStream;")
+	p.Methodref(ObjectClassName, "<init>", "()V", false)
+	assert.Equal(t, class.PoolCount, p.Count())
+	assert.Equal(t, class.PoolCount, p.Utf8("new entry"))
+
+	// Rewriting the class keeps its pool, followed by the new entry
+	cw := NewClassWriterWithPool(p)
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(original, cw))
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+	poolEnd := 10 + len(class.CPool.entries)
+	assert.Equal(t, original[10:poolEnd], b[10:poolEnd])
+	assert.Equal(t, uint16(class.PoolCount+1), readUnsignedShort(b, 8))
+}
+
+func TestConstantPoolBuilderFromUnreadPool(t *testing.T) {
+	p, err := NewConstantPoolBuilderFrom(NewJavaClass("A").CPool)
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(1), p.Count())
+}
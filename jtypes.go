@@ -1,5 +1,11 @@
 package gytes
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type JType struct {
 	Name  string
 	VMRep string
@@ -27,3 +33,86 @@ var (
 	JAFloat  = JType{"float[]", "[F"}
 	JADouble = JType{"double[]", "[D"}
 )
+
+var InvalidDescriptorError = errors.New("Invalid descriptor")
+
+// ClassType returns the type of the instances of a class or of an array, e.g. ClassType("java/lang/String").
+func ClassType(name ClassName) JType {
+	return JType{name.Canonical(), name.Descriptor()}
+}
+
+// Size returns the number of local variable or operand stack slots taken by a value of the type.
+func (t JType) Size() int {
+	return descriptorSize(t.VMRep)
+}
+
+// IsReference tells whether the values of the type are references to objects or arrays.
+func (t JType) IsReference() bool {
+	return strings.HasPrefix(t.VMRep, "L") || strings.HasPrefix(t.VMRep, "[")
+}
+
+// MethodDescriptor returns the descriptor of a method taking args and returning ret, e.g. (I[J)V
+func MethodDescriptor(ret JType, args ...JType) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, arg := range args {
+		b.WriteString(arg.VMRep)
+	}
+	b.WriteByte(')')
+	b.WriteString(ret.VMRep)
+	return b.String()
+}
+
+// SplitMethodDescriptor returns the descriptors of the arguments and of the return type of a method,
+// e.g. (ILjava/lang/String;[J)V is split into [I Ljava/lang/String; [J] and V
+func SplitMethodDescriptor(descriptor string) ([]string, string, error) {
+	if !strings.HasPrefix(descriptor, "(") {
+		return nil, "", fmt.Errorf("%w: %s", InvalidDescriptorError, descriptor)
+	}
+	args := make([]string, 0)
+	i := 1
+	for i < len(descriptor) && descriptor[i] != ')' {
+		end := fieldDescriptorEnd(descriptor, i)
+		if end < 0 {
+			return nil, "", fmt.Errorf("%w: %s", InvalidDescriptorError, descriptor)
+		}
+		args = append(args, descriptor[i:end])
+		i = end
+	}
+	if i >= len(descriptor) || fieldDescriptorEnd(descriptor, i+1) != len(descriptor) {
+		return nil, "", fmt.Errorf("%w: %s", InvalidDescriptorError, descriptor)
+	}
+	return args, descriptor[i+1:], nil
+}
+
+// Returns the number of slots taken by a value of the given field descriptor, 0 for void.
+func descriptorSize(descriptor string) int {
+	switch descriptor {
+	case "J", "D":
+		return 2
+	case "V":
+		return 0
+	}
+	return 1
+}
+
+// Returns the index following the field descriptor starting at i, or -1 if there is no valid descriptor there.
+func fieldDescriptorEnd(descriptor string, i int) int {
+	for i < len(descriptor) && descriptor[i] == '[' {
+		i++
+	}
+	if i >= len(descriptor) {
+		return -1
+	}
+	switch descriptor[i] {
+	case 'Z', 'B', 'S', 'C', 'I', 'J', 'F', 'D', 'V':
+		return i + 1
+	case 'L':
+		end := strings.IndexByte(descriptor[i:], ';')
+		if end < 0 {
+			return -1
+		}
+		return i + end + 1
+	}
+	return -1
+}
@@ -0,0 +1,17 @@
+package gytes
+
+import "fmt"
+
+// A Label marks a position in the code of a method, it's used as the target of jumps and switches,
+// and to delimit exception handlers, line numbers and local variable ranges.
+// A CodeWriter resolves the jumps to a label once the whole method is written, so a label can be used before
+// the position it marks is written.
+type Label struct {
+	// The bytecode offset of the label, relative to the start of the code.
+	// It's set when the label is written, and moved if jumps preceding it are widened.
+	Offset int
+}
+
+func (l *Label) String() string {
+	return fmt.Sprintf("L%d", l.Offset)
+}
@@ -0,0 +1,97 @@
+package gytes
+
+import "sync"
+
+// The bytes of a class read with LazyMembers, shared by its members until they are all loaded.
+// The parser caches the strings of the constant pool, so the members are loaded one at a time.
+type lazyClass struct {
+	mu     sync.Mutex
+	parser *classParser
+	bytes  []byte
+}
+
+// The parts of a field or a method that are not decoded yet
+type lazyMember struct {
+	class   *lazyClass
+	content memberAttributes
+	frame   frameContext
+}
+
+// Load decodes the annotations and attributes of a field read with LazyMembers,
+// it does nothing if the field is already loaded. The field is left unchanged if it can't be decoded.
+// The members of a class can be loaded concurrently, but a member can't be used while it's loaded.
+func (jf *JavaField) Load() error {
+	lazy := jf.lazy
+	if lazy == nil {
+		return nil
+	}
+	lazy.class.mu.Lock()
+	defer lazy.class.mu.Unlock()
+	if jf.lazy == nil {
+		return nil
+	}
+	loaded := *jf
+	fv := &fieldCollector{field: func() *JavaField { return &loaded }}
+	parser := lazy.class.parser
+	err := parser.protect(lazy.content.offset, "field "+lazy.content.member, func() error {
+		return parser.readFieldContent(lazy.class.bytes, lazy.content, fv)
+	})
+	if err != nil {
+		return err
+	}
+	loaded.lazy = nil
+	*jf = loaded
+	return nil
+}
+
+// Loaded tells whether the annotations and attributes of the field are decoded.
+func (jf *JavaField) Loaded() bool {
+	return jf.lazy == nil
+}
+
+// Load decodes the annotations, attributes and code of a method read with LazyMembers,
+// it does nothing if the method is already loaded. The method is left unchanged if it can't be decoded.
+// The members of a class can be loaded concurrently, but a member can't be used while it's loaded.
+func (jm *JavaMethod) Load() error {
+	lazy := jm.lazy
+	if lazy == nil {
+		return nil
+	}
+	lazy.class.mu.Lock()
+	defer lazy.class.mu.Unlock()
+	if jm.lazy == nil {
+		return nil
+	}
+	loaded := *jm
+	mv := &methodCollector{method: func() *JavaMethod { return &loaded }}
+	parser := lazy.class.parser
+	err := parser.protect(lazy.content.offset, "method "+lazy.content.member, func() error {
+		return parser.readMethodContent(lazy.class.bytes, lazy.content, lazy.frame, mv)
+	})
+	if err != nil {
+		return err
+	}
+	loaded.lazy = nil
+	*jm = loaded
+	return nil
+}
+
+// Loaded tells whether the annotations, attributes and code of the method are decoded.
+func (jm *JavaMethod) Loaded() bool {
+	return jm.lazy == nil
+}
+
+// Load decodes all the fields and methods of a class read with LazyMembers.
+func (jc *JavaClass) Load() error {
+	for i := range jc.Fields {
+		if err := jc.Fields[i].Load(); err != nil {
+			return err
+		}
+	}
+	for i := range jc.Methods {
+		if err := jc.Methods[i].Load(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readTestBytes(t *testing.T, src string) []byte {
+	b, err := ioutil.ReadFile(src)
+	assert.Nil(t, err)
+	return b
+}
+
+func TestReadClassBytesAndReaderAt(t *testing.T) {
+	expected, err := readClass("testdata/compiled/HelloJavaException.class")
+	assert.Nil(t, err)
+	b := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+
+	reader := &ClassReader{}
+	fromBytes, err := reader.ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fromBytes)
+
+	fromReaderAt, err := reader.ReadClassAt(bytes.NewReader(b), int64(len(b)))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fromReaderAt)
+
+	_, err = reader.ReadClassAt(bytes.NewReader(b[:10]), int64(len(b)))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestAcceptBytes(t *testing.T) {
+	scanner := &methodNameScanner{}
+	err := (&ClassReader{}).AcceptBytes(readTestBytes(t, "testdata/compiled/Hello.class"), scanner)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"<init>(Ljava/lang/String;)V", "main([Ljava/lang/String;)V"}, scanner.names)
+}
+
+func TestLazyMembersAreDecodedOnLoad(t *testing.T) {
+	expected, err := readClass("testdata/compiled/HelloJavaException.class")
+	assert.Nil(t, err)
+
+	reader := &ClassReader{Options: LazyMembers}
+	jclass, err := reader.ReadClassBytes(readTestBytes(t, "testdata/compiled/HelloJavaException.class"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(expected.Methods), len(jclass.Methods))
+	for i, method := range jclass.Methods {
+		assert.False(t, method.Loaded())
+		assert.Nil(t, method.Body)
+		assert.Equal(t, expected.Methods[i].Name, method.Name)
+		assert.Equal(t, expected.Methods[i].Exceptions, method.Exceptions)
+		assert.Equal(t, expected.Methods[i].BodyOffset, method.BodyOffset)
+	}
+
+	assert.Nil(t, jclass.Methods[1].Load())
+	assert.True(t, jclass.Methods[1].Loaded())
+	assert.Equal(t, expected.Methods[1], jclass.Methods[1])
+	assert.False(t, jclass.Methods[0].Loaded())
+
+	assert.Nil(t, jclass.Load())
+	assert.Equal(t, expected, jclass)
+}
+
+func TestLazyMembersStayUnloadedWhenLoadFails(t *testing.T) {
+	expected, err := readClass("testdata/compiled/HelloJavaException.class")
+	assert.Nil(t, err)
+	b := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+	jclass, err := (&ClassReader{Options: LazyMembers}).ReadClassBytes(b)
+	assert.Nil(t, err)
+
+	// A code_length going past the end of the class
+	method := &jclass.Methods[1]
+	codeLength := method.lazy.content.code + 4
+	original := append([]byte(nil), b[codeLength:codeLength+4]...)
+	copy(b[codeLength:], []byte{0x7F, 0xFF, 0xFF, 0xFF})
+	for i := 0; i < 2; i++ {
+		assert.True(t, errors.Is(method.Load(), MalformedClassError))
+		assert.False(t, method.Loaded())
+	}
+	assert.NotNil(t, jclass.Write(ioutil.Discard))
+
+	copy(b[codeLength:], original)
+	assert.Nil(t, method.Load())
+	assert.Equal(t, expected.Methods[1], *method)
+}
+
+func TestLazyMembersCanBeLoadedConcurrently(t *testing.T) {
+	expected, err := readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	jclass, err := (&ClassReader{Options: LazyMembers}).ReadClassBytes(readTestBytes(t, "testdata/compiled/Hello.class"))
+	assert.Nil(t, err)
+	var wg sync.WaitGroup
+	for i := range jclass.Methods {
+		wg.Add(1)
+		go func(method *JavaMethod) {
+			defer wg.Done()
+			assert.Nil(t, method.Load())
+		}(&jclass.Methods[i])
+	}
+	wg.Wait()
+	assert.Equal(t, expected.Methods, jclass.Methods)
+}
@@ -0,0 +1,44 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when a class file goes over one of the Limits of the ClassReader reading it
+var LimitExceededError = errors.New("Class file limit exceeded")
+
+// Limits bound the resources a ClassReader spends on a class, to read untrusted class files safely.
+// A zero value means no limit.
+type Limits struct {
+	// The size of the class file in bytes
+	MaxClassSize int
+	// The number of entries of the constant pool
+	MaxPoolEntries int
+	// The number of fields, and the number of methods, of a class
+	MaxMembers int
+	// The length in bytes of any attribute
+	MaxAttributeLength int
+	// The length in bytes of the code of a method
+	MaxCodeLength int
+	// The nesting depth of annotation values, nested annotations and arrays each adding a level
+	MaxAnnotationDepth int
+}
+
+// Limits suitable to read class files coming from untrusted sources, they are far above what compilers emit.
+var DefaultLimits = Limits{
+	MaxClassSize:       16 << 20,
+	MaxPoolEntries:     65535,
+	MaxMembers:         65535,
+	MaxAttributeLength: 8 << 20,
+	MaxCodeLength:      65535,
+	MaxAnnotationDepth: 64,
+}
+
+// Returns a LimitExceededError if value is over limit.
+func checkLimit(what string, value, limit int) error {
+	if limit > 0 && value > limit {
+		return fmt.Errorf("%w: %s is %d, the limit is %d", LimitExceededError, what, value, limit)
+	}
+	return nil
+}
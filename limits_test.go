@@ -0,0 +1,59 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultLimitsAllowRegularClasses(t *testing.T) {
+	reader := &ClassReader{Limits: DefaultLimits}
+	for _, src := range []string{"testdata/compiled/Hello.class", "testdata/compiled/HelloJavaException.class"} {
+		_, err := reader.ReadClass(readClassFile(src))
+		assert.Nil(t, err)
+	}
+}
+
+func TestLimitsAreEnforced(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+	tests := []struct {
+		limits  Limits
+		message string
+	}{
+		{Limits{MaxClassSize: 100}, "class size"},
+		{Limits{MaxPoolEntries: 10}, "constant pool count is"},
+		{Limits{MaxMembers: 1}, "methods count is 2, the limit is 1"},
+		{Limits{MaxAttributeLength: 20}, "length of attribute Code is"},
+		{Limits{MaxCodeLength: 4}, "code length of <init>()V is 5, the limit is 4"},
+	}
+	for _, test := range tests {
+		for _, lenient := range []bool{false, true} {
+			reader := &ClassReader{Limits: test.limits, Lenient: lenient}
+			for _, read := range []func() (*JavaClass, error){
+				func() (*JavaClass, error) { return reader.ReadClass(bytes.NewReader(b)) },
+				func() (*JavaClass, error) { return reader.ReadClassBytes(b) },
+				func() (*JavaClass, error) { return reader.ReadClassAt(bytes.NewReader(b), int64(len(b))) },
+			} {
+				_, err := read()
+				assert.True(t, errors.Is(err, LimitExceededError), test.message)
+				assert.True(t, err != nil && strings.Contains(err.Error(), test.message), "%v", err)
+			}
+		}
+	}
+}
+
+func TestAnnotationDepthLimit(t *testing.T) {
+	// Three nested arrays holding an enum value
+	value := []byte{'[', 0, 1, '[', 0, 1, '[', 0, 1, 'e', 0, 0, 0, 0}
+	parser := &classParser{limits: Limits{MaxAnnotationDepth: 4}}
+	end, err := parser.readElementValue(value, 0, "value", nil, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, len(value), end)
+
+	parser.limits.MaxAnnotationDepth = 3
+	_, err = parser.readElementValue(value, 0, "value", nil, 1)
+	assert.True(t, errors.Is(err, LimitExceededError))
+}
@@ -0,0 +1,302 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Returned when a field or a method given to a builder can't be added to its class
+var InvalidMemberError = errors.New("Invalid member")
+
+const deprecatedDescriptor = "Ljava/lang/Deprecated;"
+
+// FieldBuilder describes a field to add to a class, see JavaClass.NewField.
+type FieldBuilder struct {
+	class      *JavaClass
+	field      JavaField
+	deprecated bool
+}
+
+// NewField starts describing a field of the class, it's added to the class by FieldBuilder.Add.
+func (jc *JavaClass) NewField(access FieldAccess, name string, typ JType) *FieldBuilder {
+	return &FieldBuilder{class: jc, field: JavaField{Name: name, Modifiers: access, Descriptor: typ.VMRep}}
+}
+
+// Signature sets the generic signature of the field, e.g. Ljava/util/List<Ljava/lang/String;>;
+func (b *FieldBuilder) Signature(signature string) *FieldBuilder {
+	b.field.Signature = signature
+	return b
+}
+
+// Value sets the ConstantValue of a static field: an int32 for the int, short, char, byte and boolean fields,
+// an int64, float32 or float64 for the other primitive fields, and a string for the String fields.
+func (b *FieldBuilder) Value(value interface{}) *FieldBuilder {
+	b.field.Value = value
+	return b
+}
+
+func (b *FieldBuilder) Annotate(annotation Annotation) *FieldBuilder {
+	b.field.Annotations = append(b.field.Annotations, annotation)
+	return b
+}
+
+// Deprecated marks the field as deprecated, with the Deprecated attribute and the @Deprecated annotation like javac.
+func (b *FieldBuilder) Deprecated() *FieldBuilder {
+	b.deprecated = true
+	return b
+}
+
+// Add checks the field and appends it to the fields of the class.
+func (b *FieldBuilder) Add() error {
+	field := b.field
+	if b.deprecated {
+		field.Annotations, field.Attributes = deprecate(field.Annotations, field.Attributes)
+	}
+	if err := b.check(field); err != nil {
+		return fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	b.class.Fields = append(b.class.Fields, field)
+	return nil
+}
+
+func (b *FieldBuilder) check(field JavaField) error {
+	if !validMemberName(field.Name, false) {
+		return fmt.Errorf("%w: invalid name", InvalidMemberError)
+	}
+	if field.Descriptor == "" || field.Descriptor == JVoid.VMRep {
+		return fmt.Errorf("%w: a field can't be void", InvalidMemberError)
+	}
+	if err := field.Modifiers.Validate(b.class.Access); err != nil {
+		return err
+	}
+	for _, other := range b.class.Fields {
+		if other.Name == field.Name && other.Descriptor == field.Descriptor {
+			return fmt.Errorf("%w: the class already has a field %s %s", InvalidMemberError, field.Name, field.Descriptor)
+		}
+	}
+	if field.Value != nil {
+		if !field.Modifiers.Has(ACC_STATIC) {
+			return fmt.Errorf("%w: only static fields have a constant value", InvalidMemberError)
+		}
+		if !constantFits(field.Value, field.Descriptor) {
+			return fmt.Errorf("%w: constant value %v of type %T for a field of type %s",
+				InvalidConstantError, field.Value, field.Value, field.Descriptor)
+		}
+	}
+	return checkAnnotations(field.Annotations)
+}
+
+// MethodBuilder describes a method to add to a class, see JavaClass.NewMethod.
+//
+// The methods don't return errors, the first one is kept and returned by Add.
+type MethodBuilder struct {
+	class      *JavaClass
+	method     JavaMethod
+	hasCode    bool
+	deprecated bool
+	err        error
+}
+
+// NewMethod starts describing a method of the class returning ret and taking args, it's added to the class
+// by MethodBuilder.Add.
+func (jc *JavaClass) NewMethod(access MethodAccess, name string, ret JType, args ...JType) *MethodBuilder {
+	return &MethodBuilder{class: jc, method: JavaMethod{Name: name, Modifiers: access, Descriptor: MethodDescriptor(ret, args...)}}
+}
+
+// NewLambdaMethod starts describing a private static synthetic method implementing a lambda, named lambda$0,
+// lambda$1 and so on. It's the Implementation of the Lambda, see MethodBuilder.Handle.
+func (jc *JavaClass) NewLambdaMethod(ret JType, args ...JType) *MethodBuilder {
+	name := ""
+	for name == "" || jc.hasMethod(name) {
+		name = fmt.Sprintf("lambda$%d", jc.lambdas)
+		jc.lambdas++
+	}
+	return jc.NewMethod(ACC_PRIVATE|ACC_STATIC|ACC_SYNTHETIC, name, ret, args...)
+}
+
+func (jc *JavaClass) hasMethod(name string) bool {
+	for _, method := range jc.Methods {
+		if method.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle returns the method handle calling the method, e.g. the Implementation of a Lambda.
+func (b *MethodBuilder) Handle() Handle {
+	handle := Handle{Owner: b.class.Name, Name: b.method.Name, Descriptor: b.method.Descriptor,
+		IsInterface: b.class.Access.Has(ACC_INTERFACE)}
+	switch access := b.method.Modifiers; {
+	case access.Has(ACC_STATIC):
+		handle.Kind = REF_invokeStatic
+	case b.method.Name == "<init>":
+		handle.Kind = REF_newInvokeSpecial
+	case access.Has(ACC_PRIVATE):
+		handle.Kind = REF_invokeSpecial
+	case handle.IsInterface:
+		handle.Kind = REF_invokeInterface
+	default:
+		handle.Kind = REF_invokeVirtual
+	}
+	return handle
+}
+
+func (b *MethodBuilder) setErr(err error) {
+	if b.err == nil && err != nil {
+		b.err = err
+	}
+}
+
+// Signature sets the generic signature of the method, e.g. <T:Ljava/lang/Object;>(TT;)TT;
+func (b *MethodBuilder) Signature(signature string) *MethodBuilder {
+	b.method.Signature = signature
+	return b
+}
+
+// Throws adds exceptions to the checked exceptions thrown by the method, stored in its Exceptions attribute.
+func (b *MethodBuilder) Throws(exceptions ...ClassName) *MethodBuilder {
+	b.method.Exceptions = append(b.method.Exceptions, exceptions...)
+	return b
+}
+
+func (b *MethodBuilder) Annotate(annotation Annotation) *MethodBuilder {
+	b.method.Annotations = append(b.method.Annotations, annotation)
+	return b
+}
+
+// Deprecated marks the method as deprecated, with the Deprecated attribute and the @Deprecated annotation like javac.
+func (b *MethodBuilder) Deprecated() *MethodBuilder {
+	b.deprecated = true
+	return b
+}
+
+// Code generates the code of the method with body, it replaces the code generated by the previous calls.
+// The errors of the CodeBuilder are returned by Add.
+func (b *MethodBuilder) Code(body func(code *CodeBuilder)) *MethodBuilder {
+	code := NewCodeBuilder(&b.method)
+	body(code)
+	b.setErr(code.Build())
+	b.hasCode = true
+	return b
+}
+
+// Add checks the method and appends it to the methods of the class.
+func (b *MethodBuilder) Add() error {
+	method := b.method
+	if b.deprecated {
+		method.Annotations, method.Attributes = deprecate(method.Annotations, method.Attributes)
+	}
+	err := b.err
+	if err == nil {
+		err = b.check(method)
+	}
+	if err != nil {
+		return fmt.Errorf("method %s%s: %w", method.Name, method.Descriptor, err)
+	}
+	b.class.Methods = append(b.class.Methods, method)
+	return nil
+}
+
+func (b *MethodBuilder) check(method JavaMethod) error {
+	args, ret, err := SplitMethodDescriptor(method.Descriptor)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if arg == JVoid.VMRep {
+			return fmt.Errorf("%w: an argument can't be void", InvalidMemberError)
+		}
+	}
+	switch method.Name {
+	case "<clinit>":
+		if method.Descriptor != "()V" {
+			return fmt.Errorf("%w: a class initializer takes no arguments and returns void", InvalidMemberError)
+		}
+	case "<init>":
+		if ret != JVoid.VMRep || b.class.Access.Has(ACC_INTERFACE) {
+			return fmt.Errorf("%w: a constructor returns void and is declared by a class", InvalidMemberError)
+		}
+	default:
+		if !validMemberName(method.Name, true) {
+			return fmt.Errorf("%w: invalid name", InvalidMemberError)
+		}
+	}
+	if err := method.Modifiers.Validate(b.class.Access, method.Name); err != nil {
+		return err
+	}
+	if withoutCode := method.Modifiers&(ACC_ABSTRACT|ACC_NATIVE) != 0; withoutCode == b.hasCode {
+		return fmt.Errorf("%w: only the methods that are neither abstract nor native have code", InvalidMemberError)
+	}
+	for _, other := range b.class.Methods {
+		if other.Name == method.Name && other.Descriptor == method.Descriptor {
+			return fmt.Errorf("%w: the class already has a method %s%s", InvalidMemberError, method.Name, method.Descriptor)
+		}
+	}
+	for _, exception := range method.Exceptions {
+		if exception == "" || exception.IsArray() {
+			return fmt.Errorf("%w: invalid exception class %q", InvalidMemberError, exception)
+		}
+	}
+	return checkAnnotations(method.Annotations)
+}
+
+// Adds the Deprecated attribute and the @Deprecated annotation, unless they're already there.
+func deprecate(annotations []Annotation, attributes []JAttribute) ([]Annotation, []JAttribute) {
+	annotated, attributed := false, false
+	for _, annotation := range annotations {
+		annotated = annotated || annotation.Descriptor == deprecatedDescriptor
+	}
+	for _, attr := range attributes {
+		attributed = attributed || attr.Name == "Deprecated"
+	}
+	if !annotated {
+		annotations = append(annotations, Annotation{Descriptor: deprecatedDescriptor, Visible: true})
+	}
+	if !attributed {
+		attributes = append(attributes, JAttribute{Name: "Deprecated", Data: []byte{}})
+	}
+	return annotations, attributes
+}
+
+// Checks that the annotations are annotations of classes, each of them used once.
+func checkAnnotations(annotations []Annotation) error {
+	seen := make(map[string]bool)
+	for _, annotation := range annotations {
+		if name, err := ClassNameFromDescriptor(annotation.Descriptor); err != nil || name.IsArray() {
+			return fmt.Errorf("%w: invalid annotation descriptor %q", InvalidMemberError, annotation.Descriptor)
+		}
+		if seen[annotation.Descriptor] {
+			return fmt.Errorf("%w: %s is used more than once", InvalidMemberError, annotation.Descriptor)
+		}
+		seen[annotation.Descriptor] = true
+	}
+	return nil
+}
+
+// Tells whether value can be the ConstantValue of a field with the given descriptor.
+func constantFits(value interface{}, descriptor string) bool {
+	switch value.(type) {
+	case int32:
+		return len(descriptor) == 1 && strings.Contains("ISCBZ", descriptor)
+	case int64:
+		return descriptor == "J"
+	case float32:
+		return descriptor == "F"
+	case float64:
+		return descriptor == "D"
+	case string:
+		return descriptor == "Ljava/lang/String;"
+	}
+	return false
+}
+
+// Tells whether name is a valid unqualified name of a field or of a method other than <init> and <clinit> (JVMS 4.2.2).
+func validMemberName(name string, method bool) bool {
+	invalid := ".;[/"
+	if method {
+		invalid += "<>"
+	}
+	return name != "" && !strings.ContainsAny(name, invalid)
+}
@@ -0,0 +1,101 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemberBuilders(t *testing.T) {
+	class := NewJavaClass("gen.Members").Visibility(ACC_PUBLIC | ACC_SUPER).Target(V8)
+	assert.Nil(t, class.NewField(ACC_PUBLIC|ACC_STATIC|ACC_FINAL, "MAX", JInt).Value(int32(10)).Add())
+	assert.Nil(t, class.NewField(ACC_PRIVATE, "names", ClassType("java/util/List")).
+		Signature("Ljava/util/List<Ljava/lang/String;>;").Deprecated().Add())
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "<init>", JVoid).Code(func(b *CodeBuilder) {
+		b.LoadThis().InvokeSpecial(ObjectClassName, "<init>", "()V").Return(JVoid)
+	}).Add())
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC, "load", JVoid, ClassType("java/lang/String")).
+		Throws("java/io/IOException").
+		Annotate(Annotation{Descriptor: "Lgen/Traced;", Values: []AnnotationElement{{Name: "level", Value: int32(2)}}}).
+		Deprecated().
+		Code(func(b *CodeBuilder) {
+			b.Construct("java/io/IOException", "(Ljava/lang/String;)V", func(b *CodeBuilder) { b.LoadArg(0) }).Throw()
+		}).Add())
+	assert.Len(t, class.Fields, 2)
+	assert.Len(t, class.Methods, 2)
+
+	var out bytes.Buffer
+	assert.Nil(t, class.WriteWith(&out, ComputeFrames, nil))
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+
+	assert.Equal(t, int32(10), read.Fields[0].Value)
+	names := read.Fields[1]
+	assert.Equal(t, "Ljava/util/List;", names.Descriptor)
+	assert.Equal(t, "Ljava/util/List<Ljava/lang/String;>;", names.Signature)
+	assert.Equal(t, []Annotation{{Descriptor: deprecatedDescriptor, Visible: true}}, names.Annotations)
+	assert.Equal(t, "Deprecated", names.Attributes[0].Name)
+
+	load := read.Methods[1]
+	assert.Equal(t, "(Ljava/lang/String;)V", load.Descriptor)
+	assert.Equal(t, []ClassName{"java/io/IOException"}, load.Exceptions)
+	// The visible annotations are written first
+	assert.Len(t, load.Annotations, 2)
+	assert.Equal(t, deprecatedDescriptor, load.Annotations[0].Descriptor)
+	assert.Equal(t, "Lgen/Traced;", load.Annotations[1].Descriptor)
+	assert.False(t, load.Annotations[1].Visible)
+	assert.Equal(t, []string{"new", "dup", "aload", "invokespecial", "athrow"}, opcodeNames(load))
+	assert.Equal(t, uint16(2), load.MaxLocals)
+}
+
+func TestMemberBuildersCheckMembers(t *testing.T) {
+	class := NewJavaClass("gen.Checked").Visibility(ACC_PUBLIC | ACC_SUPER)
+	assert.Nil(t, class.NewField(ACC_PRIVATE, "count", JInt).Add())
+	assert.Nil(t, class.NewMethod(ACC_PUBLIC|ACC_NATIVE, "run", JVoid).Add())
+	itf := NewJavaClass("gen.Service").Visibility(ACC_PUBLIC | ACC_INTERFACE | ACC_ABSTRACT)
+	body := func(b *CodeBuilder) { b.Return(JVoid) }
+
+	for _, test := range []struct {
+		add func() error
+		err error
+	}{
+		{class.NewField(ACC_PRIVATE, "count", JInt).Add, InvalidMemberError},
+		{class.NewField(ACC_PRIVATE, "a.b", JInt).Add, InvalidMemberError},
+		{class.NewField(ACC_PRIVATE, "v", JVoid).Add, InvalidMemberError},
+		{class.NewField(ACC_PRIVATE|ACC_FINAL|ACC_VOLATILE, "f", JInt).Add, InvalidAccessFlagsError},
+		{class.NewField(ACC_PRIVATE, "x", JInt).Value(int32(1)).Add, InvalidMemberError},
+		{class.NewField(ACC_STATIC, "x", JLong).Value(int32(1)).Add, InvalidConstantError},
+		{class.NewField(ACC_STATIC, "s", ClassType("java/lang/String")).Value(1.0).Add, InvalidConstantError},
+		{class.NewField(ACC_PRIVATE, "x", JInt).Annotate(Annotation{Descriptor: "I"}).Add, InvalidMemberError},
+		{class.NewField(ACC_PRIVATE, "x", JInt).Annotate(Annotation{Descriptor: deprecatedDescriptor}).
+			Annotate(Annotation{Descriptor: deprecatedDescriptor}).Add, InvalidMemberError},
+		{itf.NewField(ACC_PRIVATE, "x", JInt).Add, InvalidAccessFlagsError},
+
+		{class.NewMethod(ACC_PUBLIC|ACC_NATIVE, "run", JVoid).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC, "<run>", JVoid).Code(body).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC, "run", JVoid, JVoid).Code(body).Add, InvalidMemberError},
+		{class.NewMethod(ACC_STATIC, "<clinit>", JVoid, JInt).Code(body).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC, "<init>", JInt).Code(body).Add, InvalidMemberError},
+		{itf.NewMethod(ACC_PUBLIC, "<init>", JVoid).Code(body).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC|ACC_STATIC, "<init>", JVoid).Code(body).Add, InvalidAccessFlagsError},
+		{class.NewMethod(ACC_PUBLIC, "stop", JVoid).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC|ACC_ABSTRACT, "stop", JVoid).Code(body).Add, InvalidMemberError},
+		{class.NewMethod(ACC_PUBLIC, "stop", JVoid).Code(body).Throws("[Ljava/lang/Exception;").Add, InvalidMemberError},
+		{class.NewMethod(ACC_STATIC, "stop", JVoid).Code(func(b *CodeBuilder) { b.LoadThis() }).Add, InvalidInstructionError},
+	} {
+		err := test.add()
+		assert.True(t, errors.Is(err, test.err), "%v", err)
+	}
+	assert.Len(t, class.Fields, 1)
+	assert.Len(t, class.Methods, 1)
+	assert.Len(t, itf.Fields, 0)
+
+	// The plain members are appended
+	class.AddFields([]JavaField{{Name: "other", Descriptor: "J"}}).AddMethods([]JavaMethod{{Name: "other", Descriptor: "()V"}})
+	assert.Equal(t, "count", class.Fields[0].Name)
+	assert.Len(t, class.Fields, 2)
+	assert.Equal(t, "run", class.Methods[0].Name)
+	assert.Len(t, class.Methods, 2)
+}
@@ -2,9 +2,12 @@ package gytes
 
 import (
 	"bufio"
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -25,30 +28,30 @@ func TestCanReadJavaClass(t *testing.T) {
 		MinorVersion: 0,
 		MajorVersion: 52,
 		SuperName:    "java/lang/Object",
-		Access:       uint16(ACC_PUBLIC | ACC_SUPER),
-		Interfaces:   []string{"java/io/Serializable"},
+		Access:       ClassAccess(ACC_PUBLIC | ACC_SUPER),
+		Interfaces:   []ClassName{"java/io/Serializable"},
 		SourceName:   "Hello.java",
 		Fields: []JavaField{
 			{
 				Name:       "MAGIC",
-				Modifiers:  uint16(ACC_PUBLIC | ACC_STATIC | ACC_FINAL),
+				Modifiers:  FieldAccess(ACC_PUBLIC | ACC_STATIC | ACC_FINAL),
 				Descriptor: "I",
 			},
 			{
 				Name:       "message",
-				Modifiers:  uint16(ACC_PRIVATE),
+				Modifiers:  FieldAccess(ACC_PRIVATE),
 				Descriptor: "Ljava/lang/String;",
 			},
 		},
 		Methods: []JavaMethod{
 			{
 				Name:       "<init>",
-				Modifiers:  uint16(ACC_PUBLIC),
+				Modifiers:  MethodAccess(ACC_PUBLIC),
 				Descriptor: "(Ljava/lang/String;)V",
 			},
 			{
 				Name:       "main",
-				Modifiers:  uint16(ACC_PUBLIC | ACC_STATIC),
+				Modifiers:  MethodAccess(ACC_PUBLIC | ACC_STATIC),
 				Descriptor: "([Ljava/lang/String;)V",
 			},
 		},
@@ -67,8 +70,8 @@ func TestCanReadJavaClassWithException(t *testing.T) {
 		MinorVersion: 0,
 		MajorVersion: 52,
 		SuperName:    "java/lang/Object",
-		Access:       uint16(ACC_PUBLIC | ACC_SUPER),
-		Interfaces:   []string{},
+		Access:       ClassAccess(ACC_PUBLIC | ACC_SUPER),
+		Interfaces:   []ClassName{},
 		SourceName:   "HelloJavaException.java",
 		Fields:       []JavaField{},
 		Methods: []JavaMethod{
@@ -81,7 +84,7 @@ func TestCanReadJavaClassWithException(t *testing.T) {
 				Name:       "methodWithException",
 				Modifiers:  0,
 				Descriptor: "()V",
-				Exceptions: []string{"java/lang/Exception"},
+				Exceptions: []ClassName{"java/lang/Exception"},
 			},
 		},
 	}
@@ -89,6 +92,26 @@ func TestCanReadJavaClassWithException(t *testing.T) {
 	AssertClass(t, expected, jclass)
 }
 
+func TestCanReadPoolWithLongAndDoubleEntries(t *testing.T) {
+	b := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0, 0, 0, 52, 0, 9}
+	b = append(b, ConstUtf8, 0, 1, 'A')
+	b = append(b, ConstClass, 0, 1)
+	// #3 and #5 take two entries
+	b = append(b, ConstLong, 0, 0, 0, 0, 0, 0, 0, 1)
+	b = append(b, ConstDouble, 0x3F, 0xF0, 0, 0, 0, 0, 0, 0)
+	b = append(b, ConstUtf8, 0, 16)
+	b = append(b, "java/lang/Object"...)
+	b = append(b, ConstClass, 0, 7)
+	b = append(b, 0, 0x21, 0, 2, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0)
+
+	jclass, err := (&ClassReader{}).ReadClass(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("A"), jclass.Name)
+	assert.Equal(t, ClassName("java/lang/Object"), jclass.SuperName)
+	assert.Equal(t, []uint8{0, ConstUtf8, ConstClass, ConstLong, 0, ConstDouble, 0, ConstUtf8, ConstClass},
+		jclass.CPool.Tags)
+}
+
 func readClass(src string) (*JavaClass, error) {
 	reader := readClassFile(src)
 	classReader := &ClassReader{}
@@ -133,3 +156,38 @@ func AssertMethod(t *testing.T, expected, got *JavaMethod) {
 func AssertCode(t *testing.T, expected, got []BytesBlock) {
 	// TODO
 }
+
+func TestClassReaderCanBeSharedAcrossGoroutines(t *testing.T) {
+	reader := &ClassReader{}
+	classes := []string{"testdata/compiled/Hello.class", "testdata/compiled/HelloJavaException.class"}
+	names := make(chan ClassName, 50)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(names); i++ {
+		wg.Add(1)
+		go func(src string) {
+			defer wg.Done()
+			jclass, err := reader.ReadClass(readClassFile(src))
+			if assert.Nil(t, err) {
+				names <- jclass.Name
+			}
+		}(classes[i%2])
+	}
+	wg.Wait()
+	close(names)
+	counts := make(map[ClassName]int)
+	for name := range names {
+		counts[name]++
+	}
+	assert.Equal(t, map[ClassName]int{"Hello": 25, "HelloJavaException": 25}, counts)
+}
+
+func TestClassReaderCanBeReusedAfterAnError(t *testing.T) {
+	reader := &ClassReader{}
+	_, err := reader.ReadClass(strings.NewReader("not a class file"))
+	assert.NotNil(t, err)
+
+	jclass, err := reader.ReadClass(readClassFile("testdata/compiled/HelloJavaException.class"))
+	assert.Nil(t, err)
+	assert.Equal(t, ClassName("HelloJavaException"), jclass.Name)
+	assert.Equal(t, []ClassName{"java/lang/Exception"}, jclass.Methods[1].Exceptions)
+}
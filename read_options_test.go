@@ -0,0 +1,75 @@
+package gytes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipCodeDoesNotDecodeMethodBodies(t *testing.T) {
+	jclass, err := (&ClassReader{Options: SkipCode}).ReadClass(readClassFile("testdata/compiled/Hello.class"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jclass.Methods))
+	for _, method := range jclass.Methods {
+		assert.Nil(t, method.Body)
+		assert.Equal(t, uint16(0), method.MaxStack)
+	}
+	assert.Equal(t, "Hello.java", jclass.SourceName)
+}
+
+func TestSkipDebugDropsDebugInformation(t *testing.T) {
+	tracer := &methodTracer{method: "main", code: &codeTracer{}}
+	err := (&ClassReader{Options: SkipDebug}).Accept(readClassFile("testdata/compiled/Hello.class"), tracer)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"getstatic java/lang/System.out:Ljava/io/PrintStream;",
+		"ldc Hello world",
+		"invokevirtual java/io/PrintStream.println(Ljava/lang/String;)V",
+		"return",
+		"maxs 2 1",
+	}, tracer.code.trace)
+
+	jclass, err := (&ClassReader{Options: SkipDebug}).ReadClass(readClassFile("testdata/compiled/Hello.class"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", jclass.SourceName)
+}
+
+// A StackMapTable with the frames of a static (I)V method:
+// same_locals_1_stack_item at 3, append at 6, chop at 7 and full at 9
+var stackMapTable = []byte{
+	0, 4,
+	64 + 3, 1,
+	252, 0, 2, 2,
+	250, 0, 0,
+	255, 0, 1, 0, 1, 4, 0, 1, 8, 0, 0,
+}
+
+func readTestFrames(t *testing.T, options ReadOptions) (map[int]Frame, map[int]*Label) {
+	labels := make(map[int]*Label)
+	label := func(offset int) *Label {
+		if _, ok := labels[offset]; !ok {
+			labels[offset] = &Label{Offset: offset}
+		}
+		return labels[offset]
+	}
+	method := frameContext{owner: "Frames", access: ACC_STATIC, name: "run", descriptor: "(I)V"}
+	frames, err := (&classParser{options: options}).readFrames(stackMapTable, 0, method, label)
+	assert.Nil(t, err)
+	return frames, labels
+}
+
+func TestCanReadCompressedFrames(t *testing.T) {
+	frames, labels := readTestFrames(t, 0)
+	assert.Equal(t, Frame{Type: FrameSame1, Stack: []interface{}{IntegerType}}, frames[3])
+	assert.Equal(t, Frame{Type: FrameAppend, Locals: []interface{}{FloatType}}, frames[6])
+	assert.Equal(t, Frame{Type: FrameChop, Chopped: 1}, frames[7])
+	assert.Equal(t, Frame{Type: FrameFull, Locals: []interface{}{LongType}, Stack: []interface{}{labels[0]}}, frames[9])
+}
+
+func TestCanReadExpandedFrames(t *testing.T) {
+	frames, labels := readTestFrames(t, ExpandFrames)
+	assert.Equal(t, Frame{Type: FrameNew, Locals: []interface{}{IntegerType}, Stack: []interface{}{IntegerType}}, frames[3])
+	assert.Equal(t, Frame{Type: FrameNew, Locals: []interface{}{IntegerType, FloatType}}, frames[6])
+	assert.Equal(t, Frame{Type: FrameNew, Locals: []interface{}{IntegerType}}, frames[7])
+	assert.Equal(t, Frame{Type: FrameNew, Locals: []interface{}{LongType}, Stack: []interface{}{labels[0]}}, frames[9])
+}
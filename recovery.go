@@ -0,0 +1,59 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// Returned when the content of a class file is inconsistent, e.g. when an offset or an index points outside of it
+var MalformedClassError = errors.New("Malformed class file")
+
+// Runs f which reads the part of the class starting at offset, turning the runtime errors caused by
+// malformed content into MalformedClassError errors. Other panics, and all the panics raised by the visitors
+// of the caller, are propagated.
+func (c *classParser) protect(offset int, what string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(runtime.Error)
+			if !ok || c.visiting > 0 {
+				panic(r)
+			}
+			err = fmt.Errorf("%w: %s at offset %d: %v", MalformedClassError, what, offset, re)
+		}
+	}()
+	return f()
+}
+
+// Runs f like protect, in lenient mode its failure is reported and false is returned instead of the error,
+// so that the caller skips the part f reads.
+func (c *classParser) skippable(offset int, what string, f func() error) (bool, error) {
+	err := c.protect(offset, what, f)
+	if err == nil {
+		return true, nil
+	}
+	// Going over the limits is never recovered, the limits are meant to stop reading untrusted classes early
+	if !c.lenient || errors.Is(err, LimitExceededError) {
+		return false, err
+	}
+	c.skipped = true
+	if errors.Is(err, MalformedClassError) {
+		c.report(SkippedContent, offset, "%v", err)
+	} else {
+		c.report(SkippedContent, offset, "%s: %v", what, err)
+	}
+	return false, nil
+}
+
+// In lenient mode, reads the part of the class starting at offset without visiting it, and returns the offset
+// to visit it from, or 0 if it must be skipped. As the visitors can't take back what they received, this is done
+// before visiting anything.
+func (c *classParser) check(offset int, what string, dryRun func() error) (int, error) {
+	if !c.lenient || offset == 0 {
+		return offset, nil
+	}
+	if ok, err := c.skippable(offset, what, dryRun); !ok {
+		return 0, err
+	}
+	return offset, nil
+}
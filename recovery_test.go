@@ -0,0 +1,116 @@
+package gytes
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the offset of the first method attribute with the given name in the class file.
+func methodAttributeOffset(t *testing.T, b []byte, name string) int {
+	parser := &classParser{}
+	assert.Nil(t, parser.fillPoolItems(b, readUnsignedShort(b, 8)))
+	m := skipMembers(b, parser.headStart+8+2*int(readUnsignedShort(b, parser.headStart+6)))
+	count := int(readUnsignedShort(b, m))
+	m += 2
+	for i := 0; i < count; i++ {
+		a := m + 8
+		for j := 0; j < int(readUnsignedShort(b, m+6)); j++ {
+			if parser.readStr(b, a) == name {
+				return a
+			}
+			a += 6 + int(readInt(b, a+2))
+		}
+		m = memberEnd(b, m)
+	}
+	t.Fatalf("No method attribute %s", name)
+	return 0
+}
+
+func TestTruncatedClassIsMalformed(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	for _, lenient := range []bool{false, true} {
+		_, err := (&ClassReader{Lenient: lenient}).ReadClassBytes(b[:len(b)/2])
+		assert.True(t, errors.Is(err, MalformedClassError))
+	}
+}
+
+func TestMalformedCodeIsSkippedInLenientMode(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+	code := methodAttributeOffset(t, b, "Code")
+	// The code_length of the first method now goes past the end of the class
+	binary.BigEndian.PutUint32(b[code+10:], 0x7FFF0000)
+
+	_, err := (&ClassReader{}).ReadClassBytes(b)
+	assert.True(t, errors.Is(err, MalformedClassError))
+
+	diagnostics := &Diagnostics{}
+	jclass, err := (&ClassReader{Lenient: true, Diagnostics: diagnostics}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jclass.Methods))
+	assert.Nil(t, jclass.Methods[0].Body)
+	assert.NotNil(t, jclass.Methods[1].Body)
+	assert.Equal(t, 1, diagnostics.Count(SkippedContent))
+	d := diagnostics.All()[0]
+	assert.Equal(t, code+6, d.Offset)
+	assert.True(t, strings.Contains(d.Message, "code of <init>"), d.Message)
+}
+
+func TestMalformedAttributeIsSkippedInLenientMode(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/HelloJavaException.class")
+	exceptions := methodAttributeOffset(t, b, "Exceptions")
+	// The first exception now points outside of the constant pool
+	binary.BigEndian.PutUint16(b[exceptions+8:], 0xFFFF)
+
+	_, err := (&ClassReader{}).ReadClassBytes(b)
+	assert.True(t, errors.Is(err, MalformedClassError))
+
+	diagnostics := &Diagnostics{}
+	jclass, err := (&ClassReader{Lenient: true, Diagnostics: diagnostics}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	assert.Nil(t, jclass.Methods[1].Exceptions)
+	assert.NotNil(t, jclass.Methods[1].Body)
+	assert.Equal(t, []int{exceptions}, []int{diagnostics.All()[0].Offset})
+	assert.True(t, strings.Contains(diagnostics.All()[0].Message, "attribute Exceptions of methodWithException()V"))
+}
+
+// Visits the code of the methods with a visitor that dereferences a nil pointer
+type panickingVisitor struct {
+	ClassVisitorAdapter
+}
+
+type panickingMethodVisitor struct {
+	MethodVisitorAdapter
+}
+
+type panickingCodeVisitor struct {
+	CodeVisitorAdapter
+	labels map[int]*Label
+}
+
+func (v *panickingVisitor) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	return &panickingMethodVisitor{}
+}
+
+func (v *panickingMethodVisitor) VisitCode() CodeVisitor {
+	return &panickingCodeVisitor{}
+}
+
+func (v *panickingCodeVisitor) VisitInsn(opcode int) {
+	_ = *v.labels[opcode]
+}
+
+func TestVisitorPanicsArePropagated(t *testing.T) {
+	b := readTestBytes(t, "testdata/compiled/Hello.class")
+	for _, lenient := range []bool{false, true} {
+		diagnostics := &Diagnostics{}
+		reader := &ClassReader{Lenient: lenient, Diagnostics: diagnostics}
+		assert.PanicsWithError(t, "runtime error: invalid memory address or nil pointer dereference", func() {
+			_ = reader.AcceptBytes(b, &panickingVisitor{})
+		})
+		assert.Equal(t, 0, diagnostics.Count(SkippedContent))
+	}
+}
@@ -0,0 +1,221 @@
+package gytes
+
+import "fmt"
+
+// The constant pool of a class file, as read by the classParser
+type attributePool struct {
+	bytes []byte
+	items []int
+}
+
+// The standard attributes that are given to the visitors as is although they hold pool indexes, with the
+// function returning the offsets of these indexes in their content. Written to a pool that isn't the one
+// they were read from, e.g. by a ClassWriter receiving the events of a ClassReader, their entries are
+// added to the pool and their indexes are replaced.
+var poolAttributes = map[string]func(data []byte) ([]int, error){
+	"InnerClasses": func(data []byte) ([]int, error) {
+		var indexes []int
+		for i, p := 0, 2; i < int(readUnsignedShort(data, 0)); i, p = i+1, p+8 {
+			indexes = append(indexes, p, p+2, p+4)
+		}
+		return indexes, nil
+	},
+	"EnclosingMethod": func(data []byte) ([]int, error) {
+		return []int{0, 2}, nil
+	},
+	"MethodParameters": func(data []byte) ([]int, error) {
+		var indexes []int
+		for i, p := 0, 1; i < int(data[0]); i, p = i+1, p+4 {
+			indexes = append(indexes, p)
+		}
+		return indexes, nil
+	},
+	"AnnotationDefault": func(data []byte) ([]int, error) {
+		var indexes []int
+		_, err := elementValueIndexes(data, 0, &indexes)
+		return indexes, err
+	},
+	"RuntimeVisibleParameterAnnotations":   parameterAnnotationIndexes,
+	"RuntimeInvisibleParameterAnnotations": parameterAnnotationIndexes,
+	"RuntimeVisibleTypeAnnotations":        typeAnnotationIndexes,
+	"RuntimeInvisibleTypeAnnotations":      typeAnnotationIndexes,
+	"Module": func(data []byte) ([]int, error) {
+		// name, flags and version, then requires (module, flags, version)
+		indexes := []int{0, 4}
+		p := 6
+		for i, n := 0, int(readUnsignedShort(data, p)); i < n; i++ {
+			indexes = append(indexes, p+2, p+6)
+			p += 6
+		}
+		p += 2
+		// exports and opens: a package, flags and the modules they're restricted to
+		for k := 0; k < 2; k++ {
+			for i, n := 0, int(readUnsignedShort(data, p)); i < n; i++ {
+				indexes = append(indexes, p+2)
+				p += 6
+				for j := 0; j < int(readUnsignedShort(data, p)); j++ {
+					indexes = append(indexes, p+2+2*j)
+				}
+				p += 2 * int(readUnsignedShort(data, p))
+			}
+			p += 2
+		}
+		// uses, then provides: a service and its implementations
+		for i, n := 0, int(readUnsignedShort(data, p)); i < n; i++ {
+			indexes = append(indexes, p+2+2*i)
+		}
+		p += 2 + 2*int(readUnsignedShort(data, p))
+		for i, n := 0, int(readUnsignedShort(data, p)); i < n; i++ {
+			indexes = append(indexes, p+2)
+			p += 4
+			for j := 0; j < int(readUnsignedShort(data, p)); j++ {
+				indexes = append(indexes, p+2+2*j)
+			}
+			p += 2 * int(readUnsignedShort(data, p))
+		}
+		return indexes, nil
+	},
+	"ModulePackages": func(data []byte) ([]int, error) {
+		var indexes []int
+		for i := 0; i < int(readUnsignedShort(data, 0)); i++ {
+			indexes = append(indexes, 2+2*i)
+		}
+		return indexes, nil
+	},
+	"ModuleMainClass": func(data []byte) ([]int, error) {
+		return []int{0}, nil
+	},
+}
+
+func parameterAnnotationIndexes(data []byte) ([]int, error) {
+	var indexes []int
+	p := 1
+	for i := 0; i < int(data[0]); i++ {
+		count := int(readUnsignedShort(data, p))
+		p += 2
+		for j := 0; j < count; j++ {
+			var err error
+			if p, err = annotationIndexes(data, p, &indexes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return indexes, nil
+}
+
+func typeAnnotationIndexes(data []byte) ([]int, error) {
+	var indexes []int
+	p := 2
+	for i := 0; i < int(readUnsignedShort(data, 0)); i++ {
+		// The target_info depends on the target_type, see JVMS 4.7.20.1
+		switch target := data[p]; {
+		case target == 0x00 || target == 0x01 || target == 0x16:
+			p += 2
+		case target == 0x13 || target == 0x14 || target == 0x15:
+			p++
+		case target == 0x40 || target == 0x41:
+			p += 3 + 6*int(readUnsignedShort(data, p+1))
+		case target >= 0x47 && target <= 0x4B:
+			p += 4
+		case target == 0x10 || target == 0x11 || target == 0x12 || target == 0x17 || target == 0x42 ||
+			target >= 0x43 && target <= 0x46:
+			p += 3
+		default:
+			return nil, fmt.Errorf("%w: unknown type annotation target %#x", MalformedClassError, target)
+		}
+		// type_path
+		p += 1 + 2*int(data[p])
+		var err error
+		if p, err = annotationIndexes(data, p, &indexes); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+// Adds the offsets of the indexes of the annotation at p, and returns the offset following it.
+func annotationIndexes(data []byte, p int, indexes *[]int) (int, error) {
+	*indexes = append(*indexes, p)
+	count := int(readUnsignedShort(data, p+2))
+	p += 4
+	for i := 0; i < count; i++ {
+		*indexes = append(*indexes, p)
+		var err error
+		if p, err = elementValueIndexes(data, p+2, indexes); err != nil {
+			return 0, err
+		}
+	}
+	return p, nil
+}
+
+// Adds the offsets of the indexes of the element_value at p, and returns the offset following it.
+func elementValueIndexes(data []byte, p int, indexes *[]int) (int, error) {
+	switch tag := data[p]; tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's', 'c':
+		*indexes = append(*indexes, p+1)
+		return p + 3, nil
+	case 'e':
+		*indexes = append(*indexes, p+1, p+3)
+		return p + 5, nil
+	case '@':
+		return annotationIndexes(data, p+1, indexes)
+	case '[':
+		count := int(readUnsignedShort(data, p+1))
+		p += 3
+		for i := 0; i < count; i++ {
+			var err error
+			if p, err = elementValueIndexes(data, p, indexes); err != nil {
+				return 0, err
+			}
+		}
+		return p, nil
+	default:
+		return 0, fmt.Errorf("%w: unknown annotation element value tag %c", MalformedClassError, tag)
+	}
+}
+
+// Returns the content of a raw attribute to write it into the pool of the class, its indexes are replaced
+// by the ones of the same entries in the pool unless the pool was seeded from the one it was read from.
+func (cw *ClassWriter) relocate(attr JAttribute) ([]byte, error) {
+	source := attr.pool
+	if source == nil || len(source.bytes) > 10 && len(cw.pool.origin) > 0 && &source.bytes[10] == &cw.pool.origin[0] {
+		return attr.Data, nil
+	}
+	data := append([]byte{}, attr.Data...)
+	parser := &classParser{poolItems: source.items, poolStr: make([]string, len(source.items))}
+	err := parser.protect(0, "attribute "+attr.Name, func() error {
+		b := source.bytes
+		indexes, err := poolAttributes[attr.Name](data)
+		if err != nil {
+			return err
+		}
+		for _, p := range indexes {
+			index := readUnsignedShort(data, p)
+			if index == 0 {
+				// The optional entries, e.g. the outer class of a local class
+				continue
+			}
+			offset := source.items[index]
+			if offset == 0 {
+				return fmt.Errorf("%w: invalid pool index %d in attribute %s", MalformedClassError, index, attr.Name)
+			}
+			switch tag := b[offset-1]; tag {
+			case ConstUtf8:
+				index = cw.pool.Utf8(parser.readUtf8(b, index))
+			case ConstClass:
+				index = cw.pool.Class(ClassName(parser.readStr(b, offset)))
+			case ConstModule, ConstPackage:
+				index = cw.pool.utf8Entry(tag, parser.readStr(b, offset))
+			case ConstNameAndType:
+				index = cw.pool.NameAndType(parser.readStr(b, offset), parser.readStr(b, offset+2))
+			case ConstInteger, ConstFloat, ConstLong, ConstDouble, ConstString:
+				index = cw.pool.Constant(parser.readConst(b, index))
+			default:
+				return fmt.Errorf("%w: unexpected pool entry of tag %d in attribute %s", MalformedClassError, tag, attr.Name)
+			}
+			data[p], data[p+1] = byte(index>>8), byte(index)
+		}
+		return nil
+	})
+	return data, err
+}
@@ -0,0 +1,44 @@
+package gytes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolIndexesOfStandardAttributes(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		data    []byte
+		indexes []int
+	}{
+		{"InnerClasses", []byte{0, 2, 0, 1, 0, 2, 0, 3, 0, 8, 0, 4, 0, 0, 0, 0, 0, 0}, []int{2, 4, 6, 10, 12, 14}},
+		{"MethodParameters", []byte{2, 0, 1, 0, 0, 0, 0, 0, 16}, []int{1, 5}},
+		{"AnnotationDefault", []byte{'[', 0, 2, 'e', 0, 1, 0, 2, '@', 0, 3, 0, 1, 0, 4, 'c', 0, 5}, []int{4, 6, 9, 13, 16}},
+		{"RuntimeVisibleParameterAnnotations", []byte{2, 0, 0, 0, 1, 0, 1, 0, 0}, []int{5}},
+		{"RuntimeVisibleTypeAnnotations", []byte{
+			0, 2,
+			0x10, 0xFF, 0xFF, 1, 0, 0, 0, 1, 0, 1, 0, 2, 'I', 0, 3,
+			0x40, 0, 1, 0, 0, 0, 5, 0, 1, 0, 0, 4, 0, 0,
+		}, []int{8, 12, 15, 27}},
+		{"Module", []byte{
+			0, 1, 0, 0, 0, 0,
+			0, 1, 0, 2, 0, 0, 0, 3,
+			0, 1, 0, 4, 0, 0, 0, 1, 0, 5,
+			0, 0,
+			0, 1, 0, 6,
+			0, 1, 0, 7, 0, 2, 0, 8, 0, 9,
+		}, []int{0, 4, 8, 12, 16, 22, 28, 32, 36, 38}},
+		{"ModulePackages", []byte{0, 2, 0, 1, 0, 2}, []int{2, 4}},
+	} {
+		indexes, err := poolAttributes[test.name](test.data)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.indexes, indexes, test.name)
+	}
+
+	_, err := poolAttributes["AnnotationDefault"]([]byte{'x', 0, 1})
+	assert.True(t, errors.Is(err, MalformedClassError))
+	_, err = poolAttributes["RuntimeInvisibleTypeAnnotations"]([]byte{0, 1, 0x30, 0, 0, 1, 0, 0})
+	assert.True(t, errors.Is(err, MalformedClassError))
+}
@@ -0,0 +1,107 @@
+package gytes
+
+import "crypto/sha256"
+
+type partDigest [sha256.Size]byte
+
+// The bytes of a class read with RoundTrip. Its parts are kept by the digest of their content as written by
+// a ClassWriter right after reading them: a part written with the same digest is unchanged, and is replaced
+// by its original bytes.
+type originalClass struct {
+	// The constant_pool_count and constant_pool of the class
+	pool               []byte
+	header, attributes map[partDigest][]byte
+	fields, methods    map[partDigest][]byte
+}
+
+// Replaces the unchanged parts of a class being written by their original bytes,
+// and tells whether all of them were.
+func (o *originalClass) restore(parts *classParts) bool {
+	restored := true
+	original := func(written []byte, originals map[partDigest][]byte) []byte {
+		if b, ok := originals[sha256.Sum256(written)]; ok {
+			return b
+		}
+		restored = false
+		return written
+	}
+	parts.header = original(parts.header, o.header)
+	for i, field := range parts.fields {
+		parts.fields[i] = original(field, o.fields)
+	}
+	for i, method := range parts.methods {
+		parts.methods[i] = original(method, o.methods)
+	}
+	parts.attributes = original(parts.attributes, o.attributes)
+	return restored
+}
+
+// Keeps the bytes jclass was read from by parser, it returns nil if the parts of jclass can't be matched with
+// them, i.e. if some of them were skipped, or if jclass can't be written.
+func (c *ClassReader) original(bytes []byte, parser *classParser, jclass *JavaClass) *originalClass {
+	if parser.skipped {
+		return nil
+	}
+	pool, err := NewConstantPoolBuilderFrom(jclass.CPool)
+	if err != nil {
+		return nil
+	}
+	cw := NewClassWriterWithPool(pool)
+	cw.Attributes, cw.raiseMaxs = c.Attributes, true
+	defer keepLabelOffsets(jclass)()
+	if err := jclass.Accept(cw); err != nil {
+		return nil
+	}
+	parts, err := cw.parts()
+	if err != nil {
+		return nil
+	}
+
+	fieldsStart := parser.headStart + 8 + 2*int(readUnsignedShort(bytes, parser.headStart+6))
+	methodsStart := skipMembers(bytes, fieldsStart)
+	attributesStart := skipMembers(bytes, methodsStart)
+	if int(readUnsignedShort(bytes, fieldsStart)) != len(parts.fields) ||
+		int(readUnsignedShort(bytes, methodsStart)) != len(parts.methods) {
+		return nil
+	}
+	return &originalClass{
+		pool:       bytes[8:parser.headStart],
+		header:     map[partDigest][]byte{sha256.Sum256(parts.header): bytes[parser.headStart:fieldsStart]},
+		attributes: map[partDigest][]byte{sha256.Sum256(parts.attributes): bytes[attributesStart:attributesEnd(bytes, attributesStart)]},
+		fields:     membersByDigest(bytes, fieldsStart, parts.fields),
+		methods:    membersByDigest(bytes, methodsStart, parts.methods),
+	}
+}
+
+// Maps the digests of the written members to the bytes of the members starting at offset.
+func membersByDigest(b []byte, offset int, written [][]byte) map[partDigest][]byte {
+	members := make(map[partDigest][]byte, len(written))
+	offset += 2
+	for _, member := range written {
+		end := memberEnd(b, offset)
+		members[sha256.Sum256(member)] = b[offset:end]
+		offset = end
+	}
+	return members
+}
+
+// Writing a class moves the labels of its code to the offsets they are written at, the returned function
+// moves them back to their current offsets.
+func keepLabelOffsets(jclass *JavaClass) func() {
+	offsets := make(map[*Label]int)
+	for _, method := range jclass.Methods {
+		if method.Instructions == nil {
+			continue
+		}
+		for n := method.Instructions.First(); n != nil; n = n.Next() {
+			if node, ok := n.(*LabelNode); ok {
+				offsets[node.Label] = node.Label.Offset
+			}
+		}
+	}
+	return func() {
+		for label, offset := range offsets {
+			label.Offset = offset
+		}
+	}
+}
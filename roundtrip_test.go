@@ -0,0 +1,181 @@
+package gytes
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTrip(t *testing.T, b []byte, options ReadOptions) []byte {
+	class, err := (&ClassReader{Options: options}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+	return out.Bytes()
+}
+
+// A class that the writer encodes differently, written as:
+//
+//	public class gen.Raw {                     // with a Synthetic attribute instead of the ACC_SYNTHETIC flag
+//	  @Deprecated                              // attribute written before the Code attribute
+//	  public static int f(int);
+//	    0: iload 0                             // instead of iload_0
+//	    2: ifeq 9
+//	    5: ldc_w #13                           // instead of ldc, and a duplicate of the entry #12
+//	    8: ireturn
+//	    9: iconst_0                            // full_frame instead of same_frame
+//	   10: ireturn
+//	}
+//
+// The StackMapTable precedes the LineNumberTable, whose entries are not sorted.
+func nonCanonicalClass() []byte {
+	pool := &ByteVector{}
+	for _, utf8 := range []string{"gen/Raw", "java/lang/Object", "f", "(I)I", "Code", "Deprecated", "Synthetic",
+		"StackMapTable", "LineNumberTable"} {
+		pool.PutU1(ConstUtf8).PutU2(uint16(len(utf8))).PutBytes([]byte(utf8))
+	}
+	// #10 to #13
+	pool.PutU1(ConstClass).PutU2(1).PutU1(ConstClass).PutU2(2)
+	pool.PutU1(ConstInteger).PutU4(7).PutU1(ConstInteger).PutU4(7)
+
+	code := []byte{ILOAD, 0, IFEQ, 0, 7, LDC_W, 0, 13, IRETURN, ICONST_0, IRETURN}
+	stackMapTable := []byte{0, 1, 255, 0, 9, 0, 1, 1, 0, 0}
+	lineNumberTable := []byte{0, 2, 0, 9, 0, 3, 0, 0, 0, 2}
+	attributes := &ByteVector{}
+	attributes.PutU2(2)
+	attributes.PutU2(8).PutU4(uint32(len(stackMapTable))).PutBytes(stackMapTable)
+	attributes.PutU2(9).PutU4(uint32(len(lineNumberTable))).PutBytes(lineNumberTable)
+
+	bv := &ByteVector{}
+	bv.PutU4(MAGIC).PutU2(0).PutU2(uint16(V8)).PutU2(14).PutBytes(pool.Bytes())
+	bv.PutU2(uint16(ACC_PUBLIC | ACC_SUPER)).PutU2(10).PutU2(11).PutU2(0)
+	bv.PutU2(0)
+	bv.PutU2(1).PutU2(uint16(ACC_PUBLIC | ACC_STATIC)).PutU2(3).PutU2(4).PutU2(2)
+	bv.PutU2(6).PutU4(0)
+	bv.PutU2(5).PutU4(uint32(10 + len(code) + attributes.Len()))
+	bv.PutU2(2).PutU2(1).PutU4(uint32(len(code))).PutBytes(code).PutU2(0).PutBytes(attributes.Bytes())
+	bv.PutU2(1).PutU2(7).PutU4(0)
+	return bv.Bytes()
+}
+
+func TestRoundTripOfCompiledClasses(t *testing.T) {
+	for _, src := range []string{"testdata/compiled/Hello.class", "testdata/compiled/HelloJavaException.class"} {
+		b := readTestBytes(t, src)
+		assert.Equal(t, b, roundTrip(t, b, RoundTrip), src)
+		// The parts that are not decoded are kept too
+		assert.Equal(t, b, roundTrip(t, b, RoundTrip|SkipCode|SkipDebug|LazyMembers), src)
+	}
+}
+
+func TestRoundTripKeepsOriginalEncodings(t *testing.T) {
+	b := nonCanonicalClass()
+	assert.NotEqual(t, b, roundTrip(t, b, 0))
+	assert.Equal(t, b, roundTrip(t, b, RoundTrip))
+
+	// The labels keep the offsets they were read at
+	class, err := (&ClassReader{Options: RoundTrip}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	labels := make([]int, 0)
+	for n := class.Methods[0].Instructions.First(); n != nil; n = n.Next() {
+		if node, ok := n.(*LabelNode); ok {
+			labels = append(labels, node.Label.Offset)
+		}
+	}
+	assert.Equal(t, []int{0, 9}, labels)
+}
+
+func TestRoundTripWritesChangedParts(t *testing.T) {
+	b := nonCanonicalClass()
+	class, err := (&ClassReader{Options: RoundTrip}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	class.Methods[0].MaxStack = 3
+	var out bytes.Buffer
+	assert.Nil(t, class.Write(&out))
+
+	// The method is written again, the rest of the class is kept
+	read, err := (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(3), read.Methods[0].MaxStack)
+	assert.Equal(t, []string{"iload", "ifeq", "ldc", "ireturn", "iconst_0", "ireturn"}, opcodeNames(read.Methods[0]))
+	assert.Equal(t, "Deprecated", read.Methods[0].Attributes[0].Name)
+	assert.True(t, bytes.HasSuffix(out.Bytes(), []byte{0, 1, 0, 7, 0, 0, 0, 0}))
+	assert.Less(t, out.Len(), len(b))
+
+	// Reordered members are still found
+	hello := readTestBytes(t, "testdata/compiled/Hello.class")
+	class, err = (&ClassReader{Options: RoundTrip}).ReadClassBytes(hello)
+	assert.Nil(t, err)
+	class.Methods[0], class.Methods[1] = class.Methods[1], class.Methods[0]
+	out.Reset()
+	assert.Nil(t, class.Write(&out))
+	assert.Len(t, out.Bytes(), len(hello))
+	assert.NotEqual(t, hello, out.Bytes())
+	read, err = (&ClassReader{}).ReadClassBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "main", read.Methods[0].Name)
+}
+
+// Round trips the classes of testdata, and of the directories and jars listed in GYTES_CORPUS, e.g. the jars
+// of a JDK or of a local Maven repository.
+func TestRoundTripCorpus(t *testing.T) {
+	roots := []string{"testdata/compiled", javacDir, generatedDir}
+	if corpus := os.Getenv("GYTES_CORPUS"); corpus != "" {
+		roots = append(roots, filepath.SplitList(corpus)...)
+	}
+	count := 0
+	check := func(name string, b []byte) {
+		count++
+		if !bytes.Equal(b, roundTrip(t, b, RoundTrip)) {
+			t.Errorf("%s is not written back as it was read", name)
+		}
+	}
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) && root == javacDir {
+			// See TestJavacClasses
+			t.Logf("%s is missing", javacDir)
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case strings.HasSuffix(path, ".class"):
+				b, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				check(path, b)
+			case strings.HasSuffix(path, ".jar"):
+				jar, err := zip.OpenReader(path)
+				if err != nil {
+					return err
+				}
+				defer jar.Close()
+				for _, f := range jar.File {
+					if !strings.HasSuffix(f.Name, ".class") {
+						continue
+					}
+					r, err := f.Open()
+					if err != nil {
+						return err
+					}
+					b, err := ioutil.ReadAll(r)
+					r.Close()
+					if err != nil {
+						return err
+					}
+					check(path+"!"+f.Name, b)
+				}
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+	t.Logf("%d classes written back", count)
+}
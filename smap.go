@@ -0,0 +1,317 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A JSR-45 source map (SMAP), as stored in the SourceDebugExtension attribute
+// by JSP compilers, Kotlin inline functions and other JVM languages.
+//
+//	SMAP
+//	<output file name>
+//	<default stratum name>
+//	*S <stratum name>
+//	*F
+//	+ 1 <file name>
+//	<file path>
+//	*L
+//	<input start line>#<file id>,<repeat count>:<output start line>,<output increment>
+//	*E
+//
+// Source: https://jcp.org/en/jsr/detail?id=45
+type SourceMap struct {
+	// The name of the file the class was generated into, usually the .java or .kt file name.
+	OutputFile     string
+	DefaultStratum string
+	Strata         []*Stratum
+}
+
+// A Stratum is a named view of the source lines, e.g. the "JSP" or "Kotlin" stratum.
+type Stratum struct {
+	Name  string
+	Files []SourceMapFile
+	Lines []LineMapping
+}
+
+// A single entry of the file section of a stratum.
+type SourceMapFile struct {
+	ID   int
+	Name string
+	// The optional path of the source file, relative to the source root.
+	Path string
+}
+
+// A single entry of the line section of a stratum, the input lines
+// [InputStartLine, InputStartLine+RepeatCount) are mapped to the output lines starting at
+// OutputStartLine, each input line covering OutputLineIncrement output lines.
+type LineMapping struct {
+	InputStartLine      int
+	FileID              int
+	RepeatCount         int
+	OutputStartLine     int
+	OutputLineIncrement int
+}
+
+var InvalidSourceMapError = errors.New("Invalid SMAP")
+
+func NewSourceMap(outputFile, defaultStratum string) *SourceMap {
+	return &SourceMap{
+		OutputFile:     outputFile,
+		DefaultStratum: defaultStratum,
+		Strata:         make([]*Stratum, 0),
+	}
+}
+
+// AddStratum adds a new empty stratum to the source map and returns it.
+func (sm *SourceMap) AddStratum(name string) *Stratum {
+	st := &Stratum{Name: name}
+	sm.Strata = append(sm.Strata, st)
+	return st
+}
+
+// Stratum returns the stratum with the given name, or nil if it does not exist.
+func (sm *SourceMap) Stratum(name string) *Stratum {
+	for _, st := range sm.Strata {
+		if st.Name == name {
+			return st
+		}
+	}
+	return nil
+}
+
+// MapLine translates a line of the generated class (as found in the LineNumberTable)
+// into a line of the original source using the default stratum.
+func (sm *SourceMap) MapLine(outputLine int) (SourceMapFile, int, bool) {
+	st := sm.Stratum(sm.DefaultStratum)
+	if st == nil {
+		return SourceMapFile{}, 0, false
+	}
+	return st.MapLine(outputLine)
+}
+
+// AddFile registers a source file in the stratum and returns its id.
+func (st *Stratum) AddFile(name, path string) int {
+	id := 1
+	for _, f := range st.Files {
+		if f.ID >= id {
+			id = f.ID + 1
+		}
+	}
+	st.Files = append(st.Files, SourceMapFile{ID: id, Name: name, Path: path})
+	return id
+}
+
+// AddLines maps repeatCount lines starting at inputStartLine in the file fileID to the output
+// lines starting at outputStartLine.
+func (st *Stratum) AddLines(inputStartLine, fileID, repeatCount, outputStartLine, outputLineIncrement int) {
+	st.Lines = append(st.Lines, LineMapping{
+		InputStartLine:      inputStartLine,
+		FileID:              fileID,
+		RepeatCount:         repeatCount,
+		OutputStartLine:     outputStartLine,
+		OutputLineIncrement: outputLineIncrement,
+	})
+}
+
+// File returns the file with the given id.
+func (st *Stratum) File(id int) (SourceMapFile, bool) {
+	for _, f := range st.Files {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return SourceMapFile{}, false
+}
+
+// MapLine translates an output line into the original file and line of this stratum.
+func (st *Stratum) MapLine(outputLine int) (SourceMapFile, int, bool) {
+	for _, l := range st.Lines {
+		if outputLine < l.OutputStartLine {
+			continue
+		}
+		delta := outputLine - l.OutputStartLine
+		var i int
+		if l.OutputLineIncrement == 0 {
+			if delta != 0 {
+				continue
+			}
+			i = 0
+		} else {
+			i = delta / l.OutputLineIncrement
+		}
+		if i >= l.RepeatCount {
+			continue
+		}
+		f, ok := st.File(l.FileID)
+		if !ok {
+			return SourceMapFile{}, 0, false
+		}
+		return f, l.InputStartLine + i, true
+	}
+	return SourceMapFile{}, 0, false
+}
+
+// String generates the textual SMAP representation, suitable for the SourceDebugExtension attribute.
+func (sm *SourceMap) String() string {
+	var sb strings.Builder
+	sb.WriteString("SMAP\n")
+	sb.WriteString(sm.OutputFile)
+	sb.WriteByte('\n')
+	sb.WriteString(sm.DefaultStratum)
+	sb.WriteByte('\n')
+	for _, st := range sm.Strata {
+		fmt.Fprintf(&sb, "*S %s\n", st.Name)
+		sb.WriteString("*F\n")
+		for _, f := range st.Files {
+			if f.Path != "" {
+				fmt.Fprintf(&sb, "+ %d %s\n%s\n", f.ID, f.Name, f.Path)
+			} else {
+				fmt.Fprintf(&sb, "%d %s\n", f.ID, f.Name)
+			}
+		}
+		sb.WriteString("*L\n")
+		fileID := 0
+		for _, l := range st.Lines {
+			sb.WriteString(strconv.Itoa(l.InputStartLine))
+			if l.FileID != fileID {
+				fmt.Fprintf(&sb, "#%d", l.FileID)
+				fileID = l.FileID
+			}
+			if l.RepeatCount != 1 {
+				fmt.Fprintf(&sb, ",%d", l.RepeatCount)
+			}
+			fmt.Fprintf(&sb, ":%d", l.OutputStartLine)
+			if l.OutputLineIncrement != 1 {
+				fmt.Fprintf(&sb, ",%d", l.OutputLineIncrement)
+			}
+			sb.WriteByte('\n')
+		}
+	}
+	sb.WriteString("*E\n")
+	return sb.String()
+}
+
+// ParseSourceMap parses the textual SMAP representation found in a SourceDebugExtension attribute.
+// Vendor sections are ignored, and embedded source maps (*O / *C) are skipped.
+func ParseSourceMap(smap string) (*SourceMap, error) {
+	lines := strings.Split(smap, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	if len(lines) < 3 || strings.TrimSpace(lines[0]) != "SMAP" {
+		return nil, fmt.Errorf("%w: missing SMAP header", InvalidSourceMapError)
+	}
+	sm := NewSourceMap(strings.TrimSpace(lines[1]), strings.TrimSpace(lines[2]))
+	var st *Stratum
+	section := ""
+	fileID := 0
+	embedded := 0
+	for i := 3; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "*") {
+			if len(line) < 2 {
+				return nil, fmt.Errorf("%w: empty section marker at line %d", InvalidSourceMapError, i+1)
+			}
+			section = line[:2]
+			switch section {
+			case "*O":
+				embedded++
+			case "*C":
+				embedded--
+			case "*S":
+				if embedded > 0 {
+					continue
+				}
+				st = sm.AddStratum(strings.TrimSpace(line[2:]))
+				fileID = 0
+			case "*F", "*L", "*V", "*E":
+			default:
+				return nil, fmt.Errorf("%w: unknown section %s at line %d", InvalidSourceMapError, line, i+1)
+			}
+			continue
+		}
+		if embedded > 0 {
+			continue
+		}
+		switch section {
+		case "*F":
+			if st == nil {
+				return nil, fmt.Errorf("%w: file section outside of a stratum at line %d", InvalidSourceMapError, i+1)
+			}
+			withPath := strings.HasPrefix(line, "+")
+			if withPath {
+				line = strings.TrimSpace(line[1:])
+			}
+			parts := strings.SplitN(line, " ", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("%w: malformed file entry at line %d", InvalidSourceMapError, i+1)
+			}
+			id, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("%w: malformed file id at line %d", InvalidSourceMapError, i+1)
+			}
+			f := SourceMapFile{ID: id, Name: strings.TrimSpace(parts[1])}
+			if withPath {
+				if i+1 >= len(lines) {
+					return nil, fmt.Errorf("%w: missing file path at line %d", InvalidSourceMapError, i+2)
+				}
+				i++
+				f.Path = strings.TrimSpace(lines[i])
+			}
+			st.Files = append(st.Files, f)
+		case "*L":
+			if st == nil {
+				return nil, fmt.Errorf("%w: line section outside of a stratum at line %d", InvalidSourceMapError, i+1)
+			}
+			l, err := parseLineMapping(line, fileID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v at line %d", InvalidSourceMapError, err, i+1)
+			}
+			fileID = l.FileID
+			st.Lines = append(st.Lines, l)
+		}
+	}
+	return sm, nil
+}
+
+// Parses InputStartLine [ "#" LineFileID ] [ "," RepeatCount ] ":" OutputStartLine [ "," OutputLineIncrement ]
+func parseLineMapping(line string, fileID int) (LineMapping, error) {
+	l := LineMapping{FileID: fileID, RepeatCount: 1, OutputLineIncrement: 1}
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return l, errors.New("missing ':' in line entry")
+	}
+	input, output := line[:colon], line[colon+1:]
+	var err error
+	if comma := strings.IndexByte(input, ','); comma >= 0 {
+		if l.RepeatCount, err = strconv.Atoi(input[comma+1:]); err != nil {
+			return l, err
+		}
+		input = input[:comma]
+	}
+	if hash := strings.IndexByte(input, '#'); hash >= 0 {
+		if l.FileID, err = strconv.Atoi(input[hash+1:]); err != nil {
+			return l, err
+		}
+		input = input[:hash]
+	}
+	if l.InputStartLine, err = strconv.Atoi(input); err != nil {
+		return l, err
+	}
+	if comma := strings.IndexByte(output, ','); comma >= 0 {
+		if l.OutputLineIncrement, err = strconv.Atoi(output[comma+1:]); err != nil {
+			return l, err
+		}
+		output = output[:comma]
+	}
+	if l.OutputStartLine, err = strconv.Atoi(output); err != nil {
+		return l, err
+	}
+	return l, nil
+}
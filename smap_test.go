@@ -0,0 +1,111 @@
+package gytes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const kotlinSmap = `SMAP
+Main.kt
+Kotlin
+*S Kotlin
+*F
++ 1 Main.kt
+MainKt
++ 2 Util.kt
+UtilKt
+*L
+1#1,12:1
+5#2,3:13,2
+*E
+*S KotlinDebug
+*F
++ 1 Main.kt
+MainKt
+*L
+7#1:13
+*E
+`
+
+func TestCanParseSourceMap(t *testing.T) {
+	smap, err := ParseSourceMap(kotlinSmap)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Main.kt", smap.OutputFile)
+	assert.Equal(t, "Kotlin", smap.DefaultStratum)
+	assert.Equal(t, 2, len(smap.Strata))
+
+	kotlin := smap.Stratum("Kotlin")
+	assert.Equal(t, []SourceMapFile{
+		{ID: 1, Name: "Main.kt", Path: "MainKt"},
+		{ID: 2, Name: "Util.kt", Path: "UtilKt"},
+	}, kotlin.Files)
+	assert.Equal(t, []LineMapping{
+		{InputStartLine: 1, FileID: 1, RepeatCount: 12, OutputStartLine: 1, OutputLineIncrement: 1},
+		{InputStartLine: 5, FileID: 2, RepeatCount: 3, OutputStartLine: 13, OutputLineIncrement: 2},
+	}, kotlin.Lines)
+
+	debug := smap.Stratum("KotlinDebug")
+	assert.Equal(t, 1, debug.Lines[0].FileID)
+	assert.Nil(t, smap.Stratum("JSP"))
+}
+
+func TestCanParseSourceMapWithLongLines(t *testing.T) {
+	// A vendor section longer than the lines read by a bufio.Scanner, followed by a stratum
+	vendor := "*V\n" + strings.Repeat("x", 100_000) + "\n"
+	smap, err := ParseSourceMap(strings.Replace(kotlinSmap, "*S KotlinDebug", vendor+"*S KotlinDebug", 1))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(smap.Strata))
+	assert.Equal(t, 1, smap.Stratum("KotlinDebug").Lines[0].FileID)
+}
+
+func TestCanMapLinesThroughSourceMap(t *testing.T) {
+	smap, err := ParseSourceMap(kotlinSmap)
+	assert.Nil(t, err)
+
+	file, line, ok := smap.MapLine(4)
+	assert.True(t, ok)
+	assert.Equal(t, "Main.kt", file.Name)
+	assert.Equal(t, 4, line)
+
+	// Each input line of Util.kt covers two output lines
+	file, line, ok = smap.MapLine(16)
+	assert.True(t, ok)
+	assert.Equal(t, "Util.kt", file.Name)
+	assert.Equal(t, 6, line)
+
+	_, _, ok = smap.MapLine(100)
+	assert.False(t, ok)
+}
+
+func TestCanGenerateSourceMap(t *testing.T) {
+	smap := NewSourceMap("index_jsp.java", "JSP")
+	jsp := smap.AddStratum("JSP")
+	index := jsp.AddFile("index.jsp", "")
+	header := jsp.AddFile("header.jsp", "WEB-INF/header.jsp")
+	jsp.AddLines(1, index, 5, 60, 1)
+	jsp.AddLines(1, header, 1, 70, 3)
+
+	expected := "SMAP\nindex_jsp.java\nJSP\n*S JSP\n*F\n1 index.jsp\n+ 2 header.jsp\nWEB-INF/header.jsp\n*L\n1#1,5:60\n1#2:70,3\n*E\n"
+	assert.Equal(t, expected, smap.String())
+
+	parsed, err := ParseSourceMap(smap.String())
+	assert.Nil(t, err)
+	assert.Equal(t, smap, parsed)
+
+	jclass := NewJavaClass("index_jsp").SetSourceMap(smap)
+	got, err := jclass.SourceMap()
+	assert.Nil(t, err)
+	assert.Equal(t, smap, got)
+}
+
+func TestRejectsInvalidSourceMap(t *testing.T) {
+	_, err := ParseSourceMap("Hello.java\nJava\n")
+	assert.True(t, errors.Is(err, InvalidSourceMapError))
+
+	_, err = ParseSourceMap("SMAP\nA.java\nJSP\n*S JSP\n*L\n1#1\n*E\n")
+	assert.True(t, errors.Is(err, InvalidSourceMapError))
+}
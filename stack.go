@@ -0,0 +1,206 @@
+package gytes
+
+import "fmt"
+
+// Returns the change of the operand stack size caused by an instruction, in slots.
+func stackEffect(insn *codeInsn) int {
+	switch op := insn.opcode; op {
+	case NOP, LALOAD, DALOAD, SWAP, INEG, LNEG, FNEG, DNEG, IINC, I2F, L2D, F2I, D2L, I2B, I2C, I2S,
+		GOTO, GOTO_W, RET, RETURN, NEWARRAY, ANEWARRAY, ARRAYLENGTH, CHECKCAST, INSTANCEOF:
+		return 0
+	case ACONST_NULL, ICONST_M1, ICONST_0, ICONST_1, ICONST_2, ICONST_3, ICONST_4, ICONST_5,
+		FCONST_0, FCONST_1, FCONST_2, BIPUSH, SIPUSH, ILOAD, FLOAD, ALOAD, DUP, DUP_X1, DUP_X2,
+		I2L, I2D, F2L, F2D, NEW, JSR, JSR_W:
+		return 1
+	case LCONST_0, LCONST_1, DCONST_0, DCONST_1, LLOAD, DLOAD, DUP2, DUP2_X1, DUP2_X2:
+		return 2
+	case IALOAD, FALOAD, AALOAD, BALOAD, CALOAD, SALOAD, ISTORE, FSTORE, ASTORE, POP,
+		IADD, FADD, ISUB, FSUB, IMUL, FMUL, IDIV, FDIV, IREM, FREM, ISHL, LSHL, ISHR, LSHR, IUSHR, LUSHR,
+		IAND, IOR, IXOR, L2I, L2F, D2I, D2F, FCMPL, FCMPG, IFEQ, IFNE, IFLT, IFGE, IFGT, IFLE, IFNULL, IFNONNULL,
+		TABLESWITCH, LOOKUPSWITCH, IRETURN, FRETURN, ARETURN, ATHROW, MONITORENTER, MONITOREXIT:
+		return -1
+	case LSTORE, DSTORE, POP2, LADD, DADD, LSUB, DSUB, LMUL, DMUL, LDIV, DDIV, LREM, DREM, LAND, LOR, LXOR,
+		IF_ICMPEQ, IF_ICMPNE, IF_ICMPLT, IF_ICMPGE, IF_ICMPGT, IF_ICMPLE, IF_ACMPEQ, IF_ACMPNE, LRETURN, DRETURN:
+		return -2
+	case IASTORE, FASTORE, AASTORE, BASTORE, CASTORE, SASTORE, LCMP, DCMPL, DCMPG:
+		return -3
+	case LASTORE, DASTORE:
+		return -4
+	case LDC:
+		if isWideConstant(insn.value) {
+			return 2
+		}
+		return 1
+	case GETSTATIC:
+		return descriptorSize(insn.descriptor)
+	case PUTSTATIC:
+		return -descriptorSize(insn.descriptor)
+	case GETFIELD:
+		return descriptorSize(insn.descriptor) - 1
+	case PUTFIELD:
+		return -descriptorSize(insn.descriptor) - 1
+	case INVOKEVIRTUAL, INVOKESPECIAL, INVOKESTATIC, INVOKEINTERFACE, INVOKEDYNAMIC:
+		// The descriptors are checked when the instructions are written
+		args, ret, _ := SplitMethodDescriptor(insn.descriptor)
+		effect := descriptorSize(ret)
+		for _, arg := range args {
+			effect -= descriptorSize(arg)
+		}
+		if op != INVOKESTATIC && op != INVOKEDYNAMIC {
+			// The receiver
+			effect--
+		}
+		return effect
+	case MULTIANEWARRAY:
+		return 1 - insn.operand
+	}
+	return 0
+}
+
+// Returns the index following the last local variable used by an instruction, 0 if it doesn't use any.
+func localsEnd(insn *codeInsn) int {
+	switch insn.opcode {
+	case LLOAD, DLOAD, LSTORE, DSTORE:
+		return insn.operand + 2
+	case ILOAD, FLOAD, ALOAD, ISTORE, FSTORE, ASTORE, IINC, RET:
+		return insn.operand + 1
+	}
+	return 0
+}
+
+// Tells whether the instruction following insn can be executed after it.
+func fallsThrough(insn *codeInsn) bool {
+	switch insn.opcode {
+	case GOTO, GOTO_W, TABLESWITCH, LOOKUPSWITCH, RET, ATHROW,
+		IRETURN, LRETURN, FRETURN, DRETURN, ARETURN, RETURN:
+		return false
+	}
+	return true
+}
+
+// Returns the size of the arguments of a method, including the receiver of instance methods.
+func argumentsSize(access MethodAccess, descriptor string) (int, error) {
+	args, _, err := SplitMethodDescriptor(descriptor)
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	if !access.Has(ACC_STATIC) {
+		size++
+	}
+	for _, arg := range args {
+		size += descriptorSize(arg)
+	}
+	return size, nil
+}
+
+// Returns the index of the instruction starting at each label, or len(c.insns) for the labels ending the code.
+func (c *CodeWriter) labelTargets() map[*Label]int {
+	starts := make(map[int]int, len(c.insns))
+	for i := len(c.insns) - 1; i >= 0; i-- {
+		starts[c.insns[i].offset] = i
+	}
+	targets := make(map[*Label]int, len(c.labels))
+	for label, offset := range c.labels {
+		i, ok := starts[offset]
+		if !ok {
+			i = len(c.insns)
+		}
+		targets[label] = i
+	}
+	return targets
+}
+
+// Computes the max_stack and max_locals of the method, see ComputeMaxs. The stack sizes are propagated
+// from the first instruction along the jumps, switches and exception handlers, the stack holding only
+// the exception at the start of a handler.
+func (c *CodeWriter) computeMaxs() error {
+	maxLocals, err := argumentsSize(c.mw.access, c.mw.descriptor)
+	if err != nil {
+		return err
+	}
+	for i := range c.insns {
+		if end := localsEnd(&c.insns[i]); end > maxLocals {
+			maxLocals = end
+		}
+	}
+
+	targets := c.labelTargets()
+	index := func(label *Label) (int, error) {
+		if _, err := c.offset(label); err != nil {
+			return 0, err
+		}
+		return targets[label], nil
+	}
+	type handler struct{ start, end, handler int }
+	handlers := make([]handler, len(c.handlers))
+	for i, h := range c.handlers {
+		var err error
+		if handlers[i].start, err = index(h.start); err != nil {
+			return err
+		}
+		if handlers[i].end, err = index(h.end); err != nil {
+			return err
+		}
+		if handlers[i].handler, err = index(h.handler); err != nil {
+			return err
+		}
+	}
+
+	// The stack size before each instruction, -1 for the instructions not reached yet
+	sizes := make([]int, len(c.insns))
+	for i := range sizes {
+		sizes[i] = -1
+	}
+	var work []int
+	reach := func(i, size int) {
+		if i < len(sizes) && sizes[i] < 0 {
+			sizes[i] = size
+			work = append(work, i)
+		}
+	}
+	reach(0, 0)
+	maxStack := 0
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		insn := &c.insns[i]
+		after := sizes[i] + stackEffect(insn)
+		if after < 0 {
+			return fmt.Errorf("%w: the stack of %s%s underflows at offset %d", InvalidInstructionError, c.mw.name, c.mw.descriptor, insn.offset)
+		}
+		if after > maxStack {
+			maxStack = after
+		}
+		for _, h := range handlers {
+			if i >= h.start && i < h.end {
+				reach(h.handler, 1)
+				if maxStack < 1 {
+					maxStack = 1
+				}
+			}
+		}
+		if insn.opcode == JSR || insn.opcode == JSR_W {
+			// The subroutine starts with the return address on the stack, and returns to the next instruction
+			target, err := index(insn.targets[0])
+			if err != nil {
+				return err
+			}
+			reach(target, after)
+			reach(i+1, sizes[i])
+			continue
+		}
+		for _, label := range insn.targets {
+			target, err := index(label)
+			if err != nil {
+				return err
+			}
+			reach(target, after)
+		}
+		if fallsThrough(insn) {
+			reach(i+1, after)
+		}
+	}
+	c.maxStack, c.maxLocals = maxStack, maxLocals
+	return nil
+}
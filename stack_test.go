@@ -0,0 +1,127 @@
+package gytes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeMaxsOfReadClass(t *testing.T) {
+	original := readTestBytes(t, "testdata/compiled/Hello.class")
+	cw := NewClassWriter()
+	cw.Options = ComputeMaxs
+	assert.Nil(t, (&ClassReader{}).AcceptBytes(original, &maxsEraser{ClassVisitorAdapter{Next: cw}}))
+	b, err := cw.Bytes()
+	assert.Nil(t, err)
+
+	expected, err := (&ClassReader{}).ReadClassBytes(original)
+	assert.Nil(t, err)
+	actual, err := (&ClassReader{}).ReadClassBytes(b)
+	assert.Nil(t, err)
+	for i, method := range expected.Methods {
+		assert.Equal(t, method.MaxStack, actual.Methods[i].MaxStack, method.Name)
+		assert.Equal(t, method.MaxLocals, actual.Methods[i].MaxLocals, method.Name)
+	}
+}
+
+// Replaces the maxs of the methods with zeros
+type maxsEraser struct {
+	ClassVisitorAdapter
+}
+
+func (e *maxsEraser) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	return &maxsEraserMethod{MethodVisitorAdapter{Next: e.ClassVisitorAdapter.VisitMethod(access, name, descriptor, signature, exceptions)}}
+}
+
+type maxsEraserMethod struct {
+	MethodVisitorAdapter
+}
+
+func (m *maxsEraserMethod) VisitCode() CodeVisitor {
+	return &maxsEraserCode{CodeVisitorAdapter{Next: m.MethodVisitorAdapter.VisitCode()}}
+}
+
+type maxsEraserCode struct {
+	CodeVisitorAdapter
+}
+
+func (c *maxsEraserCode) VisitMaxs(maxStack, maxLocals int) {
+	c.CodeVisitorAdapter.VisitMaxs(0, 0)
+}
+
+func computedMaxs(t *testing.T, access MethodAccess, descriptor string, write func(c *CodeWriter)) (int, int, error) {
+	cw := NewClassWriter()
+	cw.Options = ComputeMaxs
+	cw.Visit(V8, 0, ACC_PUBLIC, "gen/Maxs", "", ObjectClassName, nil)
+	mw := cw.NewMethod(access, "f", descriptor, "", nil)
+	write(mw.Code())
+	mw.VisitEnd()
+	_, err := cw.Bytes()
+	return mw.Code().maxStack, mw.Code().maxLocals, err
+}
+
+func TestComputeMaxsWithWideValues(t *testing.T) {
+	// static long f(long a, int b) { return a + b; }
+	maxStack, maxLocals, err := computedMaxs(t, ACC_STATIC, "(JI)J", func(c *CodeWriter) {
+		c.VisitVarInsn(LLOAD, 0)
+		c.VisitVarInsn(ILOAD, 2)
+		c.VisitInsn(I2L)
+		c.VisitInsn(LADD)
+		c.VisitInsn(LRETURN)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, maxStack)
+	assert.Equal(t, 3, maxLocals)
+}
+
+func TestComputeMaxsAcrossBranchesAndHandlers(t *testing.T) {
+	// double f(int i) { try { return i > 0 ? Math.pow(i, 2) : this.g(null, null, 0L); } catch (Exception e) { double d = 0; return d; } }
+	maxStack, maxLocals, err := computedMaxs(t, ACC_PUBLIC, "(I)D", func(c *CodeWriter) {
+		start, end, handler, negative := &Label{}, &Label{}, &Label{}, &Label{}
+		c.VisitTryCatchBlock(start, end, handler, "java/lang/Exception")
+		c.VisitLabel(start)
+		c.VisitVarInsn(ILOAD, 1)
+		c.VisitJumpInsn(IFLE, negative)
+		c.VisitVarInsn(ILOAD, 1)
+		c.VisitInsn(I2D)
+		c.VisitLdcInsn(2.0)
+		c.VisitMethodInsn(INVOKESTATIC, "java/lang/Math", "pow", "(DD)D", false)
+		c.VisitInsn(DRETURN)
+		c.VisitLabel(negative)
+		c.VisitVarInsn(ALOAD, 0)
+		c.VisitInsn(ACONST_NULL)
+		c.VisitInsn(ACONST_NULL)
+		c.VisitInsn(LCONST_0)
+		c.VisitMethodInsn(INVOKEVIRTUAL, "gen/Maxs", "g", "(Ljava/lang/Object;Ljava/lang/Object;J)D", false)
+		c.VisitLabel(end)
+		c.VisitInsn(DRETURN)
+		c.VisitLabel(handler)
+		c.VisitVarInsn(ASTORE, 2)
+		c.VisitInsn(DCONST_0)
+		c.VisitVarInsn(DSTORE, 3)
+		c.VisitVarInsn(DLOAD, 3)
+		c.VisitInsn(DRETURN)
+		c.VisitMaxs(100, 100)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, maxStack)
+	assert.Equal(t, 5, maxLocals)
+}
+
+func TestComputeMaxsReportsUnderflows(t *testing.T) {
+	_, _, err := computedMaxs(t, ACC_STATIC, "()V", func(c *CodeWriter) {
+		c.VisitInsn(POP)
+		c.VisitInsn(RETURN)
+	})
+	assert.True(t, errors.Is(err, InvalidInstructionError))
+}
+
+func TestStackEffects(t *testing.T) {
+	assert.Equal(t, 1, stackEffect(&codeInsn{opcode: GETFIELD, descriptor: "J"}))
+	assert.Equal(t, -3, stackEffect(&codeInsn{opcode: PUTFIELD, descriptor: "D"}))
+	assert.Equal(t, -4, stackEffect(&codeInsn{opcode: INVOKEINTERFACE, descriptor: "(IJ)V"}))
+	assert.Equal(t, 0, stackEffect(&codeInsn{opcode: INVOKEDYNAMIC, descriptor: "(Ljava/lang/String;)Ljava/lang/String;"}))
+	assert.Equal(t, -2, stackEffect(&codeInsn{opcode: MULTIANEWARRAY, operand: 3}))
+	assert.Equal(t, 2, stackEffect(&codeInsn{opcode: LDC, value: int64(1)}))
+}
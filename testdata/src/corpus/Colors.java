@@ -0,0 +1,31 @@
+package corpus;
+
+public enum Colors {
+  RED("r"),
+  GREEN("g") {
+    @Override
+    public String code() {
+      return "G";
+    }
+  },
+  BLUE("b");
+
+  private final String code;
+
+  Colors(String code) {
+    this.code = code;
+  }
+
+  public String code() {
+    return code;
+  }
+
+  public static Colors parse(String code) {
+    for (Colors color : values()) {
+      if (color.code().equalsIgnoreCase(code)) {
+        return color;
+      }
+    }
+    throw new IllegalArgumentException(code);
+  }
+}
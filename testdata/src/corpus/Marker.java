@@ -0,0 +1,16 @@
+package corpus;
+
+import java.lang.annotation.ElementType;
+import java.lang.annotation.Retention;
+import java.lang.annotation.RetentionPolicy;
+import java.lang.annotation.Target;
+
+@Retention(RetentionPolicy.RUNTIME)
+@Target({ElementType.TYPE, ElementType.METHOD, ElementType.PARAMETER, ElementType.TYPE_USE})
+public @interface Marker {
+  String value() default "none";
+
+  int level() default 1;
+
+  Class<?>[] types() default {};
+}
@@ -0,0 +1,64 @@
+package corpus;
+
+import java.util.ArrayList;
+import java.util.Comparator;
+import java.util.List;
+import java.util.function.Function;
+import java.util.function.Supplier;
+
+@Marker(value = "nested", level = 2, types = {String.class, int.class})
+public class Nested {
+  private int count;
+
+  public class Inner {
+    int next() {
+      return ++count;
+    }
+  }
+
+  public static class Counter implements Comparable<Counter> {
+    private final String name;
+
+    Counter(String name) {
+      this.name = name;
+    }
+
+    @Override
+    public int compareTo(Counter other) {
+      return name.compareTo(other.name);
+    }
+  }
+
+  public Runnable anonymous() {
+    return new Runnable() {
+      @Override
+      public void run() {
+        count++;
+      }
+    };
+  }
+
+  public List<@Marker String> local(final String prefix) {
+    class Prefixer {
+      String apply(String s) {
+        return prefix + s;
+      }
+    }
+    Prefixer prefixer = new Prefixer();
+    List<String> result = new ArrayList<>();
+    result.add(prefixer.apply("a"));
+    return result;
+  }
+
+  public static Function<String, String> lambda(@Marker("suffix") String suffix) {
+    return s -> s + suffix;
+  }
+
+  public static Supplier<List<String>> reference() {
+    return ArrayList::new;
+  }
+
+  public static Comparator<Counter> comparator() {
+    return Comparator.comparing(c -> c.name);
+  }
+}
@@ -0,0 +1,60 @@
+package corpus;
+
+import java.io.IOException;
+import java.io.Reader;
+
+public class Switches {
+  public static int size(String name) {
+    switch (name) {
+      case "small":
+        return 1;
+      case "medium":
+        return 2;
+      case "large":
+      case "huge":
+        return 3;
+      default:
+        return 0;
+    }
+  }
+
+  public static int weight(Colors color) {
+    switch (color) {
+      case RED:
+        return 1;
+      case BLUE:
+        return 3;
+      default:
+        return 2;
+    }
+  }
+
+  public static int read(Reader reader) throws IOException {
+    int total = 0;
+    try {
+      int c;
+      while ((c = reader.read()) != -1) {
+        total += c;
+      }
+    } catch (IllegalStateException e) {
+      total = -1;
+    } finally {
+      reader.close();
+    }
+    return total;
+  }
+
+  public static String first(Reader reader) throws IOException {
+    try (Reader r = reader) {
+      return String.valueOf(r.read());
+    }
+  }
+
+  public static long sum(long[] values) {
+    long sum = 0;
+    for (long value : values) {
+      sum += value;
+    }
+    return sum;
+  }
+}
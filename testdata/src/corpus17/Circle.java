@@ -0,0 +1,26 @@
+package corpus;
+
+public final class Circle implements Shape {
+  private final double radius;
+
+  public Circle(double radius) {
+    this.radius = radius;
+  }
+
+  public double radius() {
+    return radius;
+  }
+
+  @Override
+  public double area() {
+    return Math.PI * radius * radius;
+  }
+
+  @Override
+  public String toString() {
+    return """
+        Circle
+          radius: %s
+        """.formatted(radius);
+  }
+}
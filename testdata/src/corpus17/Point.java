@@ -0,0 +1,14 @@
+package corpus;
+
+public record Point(@Marker int x, int y) implements Shape {
+  public Point {
+    if (x < 0 || y < 0) {
+      throw new IllegalArgumentException("negative coordinate");
+    }
+  }
+
+  @Override
+  public double area() {
+    return 0;
+  }
+}
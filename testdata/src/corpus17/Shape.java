@@ -0,0 +1,20 @@
+package corpus;
+
+public sealed interface Shape permits Point, Circle {
+  double area();
+
+  default String describe() {
+    if (this instanceof Circle c) {
+      return "circle of radius " + c.radius();
+    }
+    return size(area()) + " shape";
+  }
+
+  private static String size(double area) {
+    return switch ((int) Math.signum(area)) {
+      case 0 -> "empty";
+      case 1 -> "positive";
+      default -> "negative";
+    };
+  }
+}
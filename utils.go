@@ -1,5 +1,13 @@
 package gytes
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
 // For some reason Golang does not offer a Max function for uint32 in the standard
 // Library, Weird!!
 func max(a, b uint32) uint32 {
@@ -9,35 +17,226 @@ func max(a, b uint32) uint32 {
 	return a
 }
 
-// Utility class used to hold written class bytes
+// Returned when a string is too long to be stored in a CONSTANT_Utf8 entry
+var StringTooLongError = errors.New("String too long")
+
+// Utility class used to hold written class bytes, all the values are written in big-endian order.
 type ByteVector struct {
 	Data          []uint8
 	Size          uint32
 	currentLength uint32
 }
 
+// NewByteVector creates an empty vector able to hold size bytes before growing.
+func NewByteVector(size int) *ByteVector {
+	return &ByteVector{Data: make([]uint8, size), Size: uint32(size)}
+}
+
+// Len returns the number of bytes written to the vector.
+func (bv *ByteVector) Len() int {
+	return int(bv.currentLength)
+}
+
+// Bytes returns the bytes written to the vector, they are shared with the vector until it grows.
+func (bv *ByteVector) Bytes() []byte {
+	return bv.Data[:bv.currentLength]
+}
+
 func (bv *ByteVector) grow(minSize uint32) {
 	doubleSize := bv.Size << 1
 	newSize := max(doubleSize, bv.Size+minSize)
 	newData := make([]uint8, newSize)
 	copy(newData, bv.Data)
+	bv.Data = newData
 	bv.Size = newSize
 }
 
-func (bv *ByteVector) putByte(byteValue uint8) {
-	if bv.currentLength+1 > bv.Size {
-		bv.grow(1)
+// Makes room for n more bytes and returns the offset they start at.
+func (bv *ByteVector) extend(n uint32) uint32 {
+	if bv.currentLength+n > bv.Size {
+		bv.grow(n)
 	}
-	bv.Data[bv.currentLength] = byteValue
-	bv.currentLength++
+	offset := bv.currentLength
+	bv.currentLength += n
+	return offset
+}
+
+func (bv *ByteVector) putByte(byteValue uint8) {
+	bv.Data[bv.extend(1)] = byteValue
 }
 
 func (bv *ByteVector) put2Bytes(byteValue1, byteValue2 uint8) {
-	if bv.currentLength+2 > bv.Size {
-		bv.grow(2)
+	offset := bv.extend(2)
+	bv.Data[offset] = byteValue1
+	bv.Data[offset+1] = byteValue2
+}
+
+func (bv *ByteVector) PutU1(value uint8) *ByteVector {
+	bv.putByte(value)
+	return bv
+}
+
+func (bv *ByteVector) PutU2(value uint16) *ByteVector {
+	bv.put2Bytes(uint8(value>>8), uint8(value))
+	return bv
+}
+
+func (bv *ByteVector) PutU4(value uint32) *ByteVector {
+	bv.PatchU4(bv.Reserve4(), value)
+	return bv
+}
+
+func (bv *ByteVector) PutU8(value uint64) *ByteVector {
+	return bv.PutU4(uint32(value >> 32)).PutU4(uint32(value))
+}
+
+func (bv *ByteVector) PutS1(value int8) *ByteVector {
+	return bv.PutU1(uint8(value))
+}
+
+func (bv *ByteVector) PutS2(value int16) *ByteVector {
+	return bv.PutU2(uint16(value))
+}
+
+func (bv *ByteVector) PutS4(value int32) *ByteVector {
+	return bv.PutU4(uint32(value))
+}
+
+func (bv *ByteVector) PutS8(value int64) *ByteVector {
+	return bv.PutU8(uint64(value))
+}
+
+func (bv *ByteVector) PutFloat(value float32) *ByteVector {
+	return bv.PutU4(math.Float32bits(value))
+}
+
+func (bv *ByteVector) PutDouble(value float64) *ByteVector {
+	return bv.PutU8(math.Float64bits(value))
+}
+
+// PutBytes appends the given bytes as is.
+func (bv *ByteVector) PutBytes(b []byte) *ByteVector {
+	copy(bv.Data[bv.extend(uint32(len(b))):], b)
+	return bv
+}
+
+// PutUTF8 appends s in the modified UTF-8 encoding of the class files, preceded by its encoded length on two bytes,
+// as found in CONSTANT_Utf8 entries.
+func (bv *ByteVector) PutUTF8(s string) error {
+	length := modifiedUTF8Length(s)
+	if length > math.MaxUint16 {
+		return fmt.Errorf("%w: %d bytes encoded, the limit is %d", StringTooLongError, length, math.MaxUint16)
+	}
+	bv.PutU2(uint16(length))
+	offset := bv.extend(uint32(length))
+	if length == len(s) {
+		copy(bv.Data[offset:], s)
+		return nil
+	}
+	for _, r := range s {
+		if r >= utf8.RuneSelf || r == 0 {
+			offset = bv.putModifiedUTF8Rune(offset, r)
+		} else {
+			bv.Data[offset] = byte(r)
+			offset++
+		}
+	}
+	return nil
+}
+
+// Reserve2 reserves two bytes, e.g. for a length that is only known once the following content is written,
+// and returns their offset to patch them later.
+func (bv *ByteVector) Reserve2() int {
+	return int(bv.extend(2))
+}
+
+// Reserve4 reserves four bytes and returns their offset to patch them later.
+func (bv *ByteVector) Reserve4() int {
+	return int(bv.extend(4))
+}
+
+// PatchU2 overwrites the two bytes at offset.
+func (bv *ByteVector) PatchU2(offset int, value uint16) {
+	bv.Data[offset] = uint8(value >> 8)
+	bv.Data[offset+1] = uint8(value)
+}
+
+// PatchU4 overwrites the four bytes at offset.
+func (bv *ByteVector) PatchU4(offset int, value uint32) {
+	bv.Data[offset] = uint8(value >> 24)
+	bv.Data[offset+1] = uint8(value >> 16)
+	bv.Data[offset+2] = uint8(value >> 8)
+	bv.Data[offset+3] = uint8(value)
+}
+
+// Writes r at offset, supplementary characters are written as two surrogates of three bytes each.
+func (bv *ByteVector) putModifiedUTF8Rune(offset uint32, r rune) uint32 {
+	if r > 0xFFFF {
+		high, low := utf16.EncodeRune(r)
+		offset = bv.putModifiedUTF8Rune(offset, high)
+		return bv.putModifiedUTF8Rune(offset, low)
+	}
+	if r < 0x800 {
+		bv.Data[offset] = byte(0xC0 | r>>6)
+		bv.Data[offset+1] = byte(0x80 | r&0x3F)
+		return offset + 2
+	}
+	bv.Data[offset] = byte(0xE0 | r>>12)
+	bv.Data[offset+1] = byte(0x80 | (r>>6)&0x3F)
+	bv.Data[offset+2] = byte(0x80 | r&0x3F)
+	return offset + 3
+}
+
+// Returns the number of bytes of s in modified UTF-8.
+func modifiedUTF8Length(s string) int {
+	length := 0
+	for _, r := range s {
+		switch {
+		case r == 0:
+			length += 2
+		case r < utf8.RuneSelf:
+			length++
+		case r < 0x800:
+			length += 2
+		case r <= 0xFFFF:
+			length += 3
+		default:
+			length += 6
+		}
+	}
+	return length
+}
+
+// Decodes the modified UTF-8 content of a CONSTANT_Utf8 entry.
+func decodeModifiedUTF8(b []byte) string {
+	ascii := true
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return string(b)
+	}
+	chars := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			chars = append(chars, uint16(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(b):
+			chars = append(chars, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(b):
+			chars = append(chars, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		default:
+			// Not valid in modified UTF-8, decoded as the replacement character
+			chars = append(chars, utf8.RuneError)
+			i++
+		}
 	}
-	bv.Data[bv.currentLength] = byteValue1
-	bv.currentLength++
-	bv.Data[bv.currentLength] = byteValue2
-	bv.currentLength++
+	return string(utf16.Decode(chars))
 }
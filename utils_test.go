@@ -0,0 +1,76 @@
+package gytes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteVectorGrows(t *testing.T) {
+	bv := NewByteVector(1)
+	for i := 0; i < 100; i++ {
+		bv.PutU1(uint8(i))
+	}
+	assert.Equal(t, 100, bv.Len())
+	for i, b := range bv.Bytes() {
+		assert.Equal(t, uint8(i), b)
+	}
+
+	var empty ByteVector
+	empty.PutU4(0xCAFEBABE)
+	assert.Equal(t, []byte{0xCA, 0xFE, 0xBA, 0xBE}, empty.Bytes())
+}
+
+func TestByteVectorValues(t *testing.T) {
+	bv := &ByteVector{}
+	bv.PutU1(1).PutU2(0x0203).PutU4(0x04050607).PutU8(0x08090A0B0C0D0E0F)
+	bv.PutS1(-1).PutS2(-2).PutS4(-3).PutS8(-4)
+	bv.PutFloat(1.5).PutDouble(-2.5).PutBytes([]byte{0xAA, 0xBB})
+	assert.Equal(t, []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		0xFF, 0xFF, 0xFE, 0xFF, 0xFF, 0xFF, 0xFD, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFC,
+		0x3F, 0xC0, 0, 0, 0xC0, 0x04, 0, 0, 0, 0, 0, 0, 0xAA, 0xBB,
+	}, bv.Bytes())
+
+	// Values read back by the class reader
+	b := bv.Bytes()
+	assert.Equal(t, uint16(0x0203), readUnsignedShort(b, 1))
+	assert.Equal(t, int32(-3), readInt(b, 18))
+	assert.Equal(t, int64(-4), readLong(b, 22))
+}
+
+func TestByteVectorBackPatching(t *testing.T) {
+	bv := &ByteVector{}
+	bv.PutU2(7)
+	length := bv.Reserve4()
+	count := bv.Reserve2()
+	bv.PutBytes([]byte("content"))
+	bv.PatchU4(length, uint32(bv.Len()-length-4))
+	bv.PatchU2(count, 1)
+	assert.Equal(t, []byte{0, 7, 0, 0, 0, 9, 0, 1, 'c', 'o', 'n', 't', 'e', 'n', 't'}, bv.Bytes())
+}
+
+func TestModifiedUTF8(t *testing.T) {
+	tests := []struct {
+		value   string
+		encoded []byte
+	}{
+		{"java/lang/Object", []byte("java/lang/Object")},
+		{"a\x00b", []byte{'a', 0xC0, 0x80, 'b'}},
+		{"é", []byte{0xC3, 0xA9}},
+		{"€", []byte{0xE2, 0x82, 0xAC}},
+		// Supplementary characters are encoded as surrogate pairs
+		{"😀", []byte{0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80}},
+	}
+	for _, test := range tests {
+		bv := &ByteVector{}
+		assert.Nil(t, bv.PutUTF8(test.value))
+		assert.Equal(t, append([]byte{0, byte(len(test.encoded))}, test.encoded...), bv.Bytes(), test.value)
+		assert.Equal(t, test.value, decodeModifiedUTF8(test.encoded))
+	}
+
+	err := (&ByteVector{}).PutUTF8(strings.Repeat("é", 40000))
+	assert.True(t, errors.Is(err, StringTooLongError))
+}
@@ -0,0 +1,131 @@
+package gytes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClassVersion is the major version of a class file, each JDK feature release
+// bumps it by one starting with 45 for JDK 1.1
+type ClassVersion uint16
+
+const (
+	V1_1 ClassVersion = 45 + iota
+	V1_2
+	V1_3
+	V1_4
+	V5
+	V6
+	V7
+	V8
+	V9
+	V10
+	V11
+	V12
+	V13
+	V14
+	V15
+	V16
+	V17
+	V18
+	V19
+	V20
+	V21
+	V22
+	V23
+	V24
+	V25
+	V26
+	V27
+)
+
+// The minor version of class files that depend on the preview features of their JDK release.
+const PreviewMinorVersion = 0xFFFF
+
+var UnsupportedVersionError = errors.New("Unsupported class file version")
+
+// JDK returns the name of the JDK release that introduced this version, e.g. 1.4 or 17
+func (v ClassVersion) JDK() string {
+	if v < V5 {
+		return fmt.Sprintf("1.%d", int(v)-44)
+	}
+	return fmt.Sprintf("%d", int(v)-44)
+}
+
+func (v ClassVersion) String() string {
+	return fmt.Sprintf("%d (JDK %s)", uint16(v), v.JDK())
+}
+
+// Supported returns true if gytes knows how to handle class files of this version.
+func (v ClassVersion) Supported() bool {
+	return v >= MIN_VERSION && v <= MAJ_VERSION
+}
+
+// Supports returns true if the feature can be used in class files of this version.
+func (v ClassVersion) Supports(feature Feature) bool {
+	return v >= feature.MinVersion()
+}
+
+// VersionForJDK returns the class file version of a JDK release, releases before 5 are numbered 1.x
+// and should be passed as their minor number, i.e. 4 for 1.4
+func VersionForJDK(release int) (ClassVersion, error) {
+	v := ClassVersion(release + 44)
+	if release < 1 || !v.Supported() {
+		return 0, fmt.Errorf("%w: unknown JDK release %d", UnsupportedVersionError, release)
+	}
+	return v, nil
+}
+
+// Checks that the major and minor versions read from a class file can be handled.
+func checkVersion(major, minor uint16) error {
+	v := ClassVersion(major)
+	if !v.Supported() {
+		return fmt.Errorf("%w: %d.%d, supported versions are %d to %d", UnsupportedVersionError, major, minor, MIN_VERSION, MAJ_VERSION)
+	}
+	if v >= V12 && minor != 0 && minor != PreviewMinorVersion {
+		return fmt.Errorf("%w: %d.%d, the minor version must be 0 or %d", UnsupportedVersionError, major, minor, PreviewMinorVersion)
+	}
+	return nil
+}
+
+// A Feature of the class file format that is only legal starting with a given version.
+type Feature int
+
+const (
+	FeatureInvokeDynamic Feature = iota
+	FeatureMethodHandles
+	FeatureModules
+	FeatureConstantDynamic
+	FeatureNestMates
+	FeatureRecords
+	FeatureSealed
+)
+
+var featureVersions = map[Feature]ClassVersion{
+	FeatureInvokeDynamic:   V7,
+	FeatureMethodHandles:   V7,
+	FeatureModules:         V9,
+	FeatureConstantDynamic: V11,
+	FeatureNestMates:       V11,
+	FeatureRecords:         V16,
+	FeatureSealed:          V17,
+}
+
+var featureNames = map[Feature]string{
+	FeatureInvokeDynamic:   "invokedynamic",
+	FeatureMethodHandles:   "method handles",
+	FeatureModules:         "modules",
+	FeatureConstantDynamic: "dynamic constants",
+	FeatureNestMates:       "nest mates",
+	FeatureRecords:         "records",
+	FeatureSealed:          "sealed classes",
+}
+
+// MinVersion returns the first class file version in which the feature is legal.
+func (f Feature) MinVersion() ClassVersion {
+	return featureVersions[f]
+}
+
+func (f Feature) String() string {
+	return featureNames[f]
+}
@@ -0,0 +1,79 @@
+package gytes
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the bytes of a compiled test class with its version replaced.
+func classWithVersion(t *testing.T, src string, major, minor uint16) []byte {
+	b, err := ioutil.ReadFile(src)
+	assert.Nil(t, err)
+	b[4], b[5] = byte(minor>>8), byte(minor)
+	b[6], b[7] = byte(major>>8), byte(major)
+	return b
+}
+
+func TestClassVersionsMapToJDKReleases(t *testing.T) {
+	assert.Equal(t, "1.1", V1_1.JDK())
+	assert.Equal(t, "1.4", V1_4.JDK())
+	assert.Equal(t, "8", V8.JDK())
+	assert.Equal(t, ClassVersion(61), V17)
+	assert.Equal(t, ClassVersion(MAJ_VERSION), V27)
+
+	v, err := VersionForJDK(21)
+	assert.Nil(t, err)
+	assert.Equal(t, V21, v)
+	_, err = VersionForJDK(99)
+	assert.True(t, errors.Is(err, UnsupportedVersionError))
+
+	assert.True(t, V8.Supports(FeatureInvokeDynamic))
+	assert.False(t, V8.Supports(FeatureRecords))
+}
+
+func TestReaderRejectsUnsupportedVersions(t *testing.T) {
+	b := classWithVersion(t, "testdata/compiled/Hello.class", MAJ_VERSION+1, 0)
+	_, err := (&ClassReader{}).ReadClass(bytes.NewReader(b))
+	assert.True(t, errors.Is(err, UnsupportedVersionError))
+
+	jclass, err := (&ClassReader{AllowUnsupportedVersions: true}).ReadClass(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, ClassVersion(MAJ_VERSION+1), jclass.Version())
+
+	b = classWithVersion(t, "testdata/compiled/Hello.class", uint16(V17), 3)
+	_, err = (&ClassReader{}).ReadClass(bytes.NewReader(b))
+	assert.True(t, errors.Is(err, UnsupportedVersionError))
+}
+
+func TestReaderDetectsPreviewClasses(t *testing.T) {
+	b := classWithVersion(t, "testdata/compiled/Hello.class", uint16(V21), PreviewMinorVersion)
+	jclass, err := (&ClassReader{}).ReadClass(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.True(t, jclass.IsPreview())
+
+	jclass, err = readClass("testdata/compiled/Hello.class")
+	assert.Nil(t, err)
+	assert.False(t, jclass.IsPreview())
+	assert.Equal(t, V14, jclass.Version())
+	assert.Nil(t, jclass.ValidateVersion())
+}
+
+func TestFeaturesMustBeLegalForTargetVersion(t *testing.T) {
+	jclass := NewJavaClass("Point").Target(V11)
+	jclass.RecordComponents = []RecordComponent{{Name: "x", Descriptor: "I"}}
+	err := jclass.ValidateVersion()
+	assert.True(t, errors.Is(err, UnsupportedVersionError))
+	assert.Equal(t, []Feature{FeatureRecords}, jclass.Features())
+
+	assert.Nil(t, jclass.Target(V16).ValidateVersion())
+
+	jclass.PermittedSubclasses = []ClassName{"Point3D"}
+	assert.NotNil(t, jclass.ValidateVersion())
+	assert.Nil(t, jclass.Target(V17).ValidateVersion())
+
+	assert.NotNil(t, NewJavaClass("Old").TargetPreview(V8).ValidateVersion())
+}
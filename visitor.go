@@ -0,0 +1,439 @@
+package gytes
+
+// The visitor API lets a ClassReader stream the content of a class file as a sequence of events,
+// without building a JavaClass. Visitors can be chained to build transformations and scanners,
+// every adapter forwards the events it receives to the Next visitor, so a filter only needs to
+// embed the adapter and override the events it's interested in:
+//
+//	type methodNames struct {
+//		ClassVisitorAdapter
+//		names []string
+//	}
+//
+//	func (v *methodNames) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+//		v.names = append(v.names, name)
+//		return nil
+//	}
+//
+// Returning a nil FieldVisitor, RecordComponentVisitor, MethodVisitor, CodeVisitor or AnnotationVisitor tells the reader
+// to skip the corresponding part of the class.
+
+// ClassVisitor receives the events of a class, in the following order:
+//
+//	Visit
+//	VisitSource, VisitNestHost
+//	(VisitAnnotation | VisitAttribute)*
+//	(VisitNestMember | VisitPermittedSubclass | VisitRecordComponent | VisitField | VisitMethod)*
+//	VisitEnd
+type ClassVisitor interface {
+	Visit(version ClassVersion, minorVersion uint16, access ClassAccess, name ClassName, signature string, superName ClassName, interfaces []ClassName)
+	VisitSource(source, debug string)
+	VisitNestHost(host ClassName)
+	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
+	VisitAttribute(attr JAttribute)
+	VisitNestMember(member ClassName)
+	VisitPermittedSubclass(subclass ClassName)
+	VisitRecordComponent(name, descriptor, signature string) RecordComponentVisitor
+	// value is the ConstantValue of the field if it has any, one of int32, int64, float32, float64 or string
+	VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor
+	VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor
+	VisitEnd()
+}
+
+// FieldVisitor receives the events of a field: (VisitAnnotation | VisitAttribute)* VisitEnd
+type FieldVisitor interface {
+	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
+	VisitAttribute(attr JAttribute)
+	VisitEnd()
+}
+
+// RecordComponentVisitor receives the events of a record component: (VisitAnnotation | VisitAttribute)* VisitEnd
+type RecordComponentVisitor interface {
+	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
+	VisitAttribute(attr JAttribute)
+	VisitEnd()
+}
+
+// MethodVisitor receives the events of a method: (VisitAnnotation | VisitAttribute)* VisitCode? VisitEnd
+type MethodVisitor interface {
+	VisitAnnotation(descriptor string, visible bool) AnnotationVisitor
+	VisitAttribute(attr JAttribute)
+	// VisitCode is called for methods that have a body
+	VisitCode() CodeVisitor
+	VisitEnd()
+}
+
+// CodeVisitor receives the events of a method body, in the following order:
+//
+//	VisitTryCatchBlock*
+//	(Visit*Insn | VisitLabel | VisitLineNumber | VisitFrame)*
+//	(VisitLocalVariable | VisitAttribute)*
+//	VisitMaxs
+//	VisitEnd
+//
+// Instructions are reported in their canonical form, i.e. iload_0 is visited as VisitVarInsn(ILOAD, 0),
+// wide instructions as their regular counterpart, ldc_w and ldc2_w as VisitLdcInsn, goto_w and jsr_w as
+// VisitJumpInsn(GOTO) and VisitJumpInsn(JSR). Labels are visited before the instructions they precede,
+// followed by the line numbers and the stack map frame starting at them.
+type CodeVisitor interface {
+	// Instructions without operands
+	VisitInsn(opcode int)
+	// bipush, sipush and newarray
+	VisitIntInsn(opcode, operand int)
+	// Loads, stores and ret
+	VisitVarInsn(opcode, index int)
+	// new, anewarray, checkcast and instanceof
+	VisitTypeInsn(opcode int, typ ClassName)
+	VisitFieldInsn(opcode int, owner ClassName, name, descriptor string)
+	VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool)
+	VisitInvokeDynamicInsn(name, descriptor string, bootstrap Handle, arguments []interface{})
+	VisitJumpInsn(opcode int, target *Label)
+	VisitLabel(label *Label)
+	// value is one of int32, float32, int64, float64, string, ClassName, MethodType, Handle or ConstantDynamic
+	VisitLdcInsn(value interface{})
+	VisitIincInsn(index, increment int)
+	VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label)
+	VisitLookupSwitchInsn(dflt *Label, keys []int, labels []*Label)
+	VisitMultiANewArrayInsn(descriptor string, dimensions int)
+	// typ is empty for handlers that catch any exception, i.e. finally blocks
+	VisitTryCatchBlock(start, end, handler *Label, typ ClassName)
+	VisitLocalVariable(name, descriptor, signature string, start, end *Label, index int)
+	VisitLineNumber(line int, start *Label)
+	VisitFrame(frame Frame)
+	VisitAttribute(attr JAttribute)
+	VisitMaxs(maxStack, maxLocals int)
+	VisitEnd()
+}
+
+// AnnotationVisitor receives the values of an annotation, or of an array value, followed by VisitEnd.
+// Values that are elements of an array have an empty name.
+type AnnotationVisitor interface {
+	// value is one of int8, uint16, int16, bool, int32, int64, float32, float64, string or ClassLiteral
+	Visit(name string, value interface{})
+	VisitEnum(name, descriptor, value string)
+	VisitAnnotation(name, descriptor string) AnnotationVisitor
+	VisitArray(name string) AnnotationVisitor
+	VisitEnd()
+}
+
+// ClassVisitorAdapter forwards all the events to the Next visitor, if any.
+type ClassVisitorAdapter struct {
+	Next ClassVisitor
+}
+
+func (a *ClassVisitorAdapter) Visit(version ClassVersion, minorVersion uint16, access ClassAccess, name ClassName, signature string, superName ClassName, interfaces []ClassName) {
+	if a.Next != nil {
+		a.Next.Visit(version, minorVersion, access, name, signature, superName, interfaces)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitSource(source, debug string) {
+	if a.Next != nil {
+		a.Next.VisitSource(source, debug)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitNestHost(host ClassName) {
+	if a.Next != nil {
+		a.Next.VisitNestHost(host)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitAnnotation(descriptor, visible)
+	}
+	return nil
+}
+
+func (a *ClassVisitorAdapter) VisitAttribute(attr JAttribute) {
+	if a.Next != nil {
+		a.Next.VisitAttribute(attr)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitNestMember(member ClassName) {
+	if a.Next != nil {
+		a.Next.VisitNestMember(member)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitPermittedSubclass(subclass ClassName) {
+	if a.Next != nil {
+		a.Next.VisitPermittedSubclass(subclass)
+	}
+}
+
+func (a *ClassVisitorAdapter) VisitRecordComponent(name, descriptor, signature string) RecordComponentVisitor {
+	if a.Next != nil {
+		return a.Next.VisitRecordComponent(name, descriptor, signature)
+	}
+	return nil
+}
+
+func (a *ClassVisitorAdapter) VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor {
+	if a.Next != nil {
+		return a.Next.VisitField(access, name, descriptor, signature, value)
+	}
+	return nil
+}
+
+func (a *ClassVisitorAdapter) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	if a.Next != nil {
+		return a.Next.VisitMethod(access, name, descriptor, signature, exceptions)
+	}
+	return nil
+}
+
+func (a *ClassVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
+
+// FieldVisitorAdapter forwards all the events to the Next visitor, if any.
+type FieldVisitorAdapter struct {
+	Next FieldVisitor
+}
+
+func (a *FieldVisitorAdapter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitAnnotation(descriptor, visible)
+	}
+	return nil
+}
+
+func (a *FieldVisitorAdapter) VisitAttribute(attr JAttribute) {
+	if a.Next != nil {
+		a.Next.VisitAttribute(attr)
+	}
+}
+
+func (a *FieldVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
+
+// RecordComponentVisitorAdapter forwards all the events to the Next visitor, if any.
+type RecordComponentVisitorAdapter struct {
+	Next RecordComponentVisitor
+}
+
+func (a *RecordComponentVisitorAdapter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitAnnotation(descriptor, visible)
+	}
+	return nil
+}
+
+func (a *RecordComponentVisitorAdapter) VisitAttribute(attr JAttribute) {
+	if a.Next != nil {
+		a.Next.VisitAttribute(attr)
+	}
+}
+
+func (a *RecordComponentVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
+
+// MethodVisitorAdapter forwards all the events to the Next visitor, if any.
+type MethodVisitorAdapter struct {
+	Next MethodVisitor
+}
+
+func (a *MethodVisitorAdapter) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitAnnotation(descriptor, visible)
+	}
+	return nil
+}
+
+func (a *MethodVisitorAdapter) VisitAttribute(attr JAttribute) {
+	if a.Next != nil {
+		a.Next.VisitAttribute(attr)
+	}
+}
+
+func (a *MethodVisitorAdapter) VisitCode() CodeVisitor {
+	if a.Next != nil {
+		return a.Next.VisitCode()
+	}
+	return nil
+}
+
+func (a *MethodVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
+
+// CodeVisitorAdapter forwards all the events to the Next visitor, if any.
+type CodeVisitorAdapter struct {
+	Next CodeVisitor
+}
+
+func (a *CodeVisitorAdapter) VisitInsn(opcode int) {
+	if a.Next != nil {
+		a.Next.VisitInsn(opcode)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitIntInsn(opcode, operand int) {
+	if a.Next != nil {
+		a.Next.VisitIntInsn(opcode, operand)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitVarInsn(opcode, index int) {
+	if a.Next != nil {
+		a.Next.VisitVarInsn(opcode, index)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitTypeInsn(opcode int, typ ClassName) {
+	if a.Next != nil {
+		a.Next.VisitTypeInsn(opcode, typ)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitFieldInsn(opcode int, owner ClassName, name, descriptor string) {
+	if a.Next != nil {
+		a.Next.VisitFieldInsn(opcode, owner, name, descriptor)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool) {
+	if a.Next != nil {
+		a.Next.VisitMethodInsn(opcode, owner, name, descriptor, isInterface)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitInvokeDynamicInsn(name, descriptor string, bootstrap Handle, arguments []interface{}) {
+	if a.Next != nil {
+		a.Next.VisitInvokeDynamicInsn(name, descriptor, bootstrap, arguments)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitJumpInsn(opcode int, target *Label) {
+	if a.Next != nil {
+		a.Next.VisitJumpInsn(opcode, target)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitLabel(label *Label) {
+	if a.Next != nil {
+		a.Next.VisitLabel(label)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitLdcInsn(value interface{}) {
+	if a.Next != nil {
+		a.Next.VisitLdcInsn(value)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitIincInsn(index, increment int) {
+	if a.Next != nil {
+		a.Next.VisitIincInsn(index, increment)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label) {
+	if a.Next != nil {
+		a.Next.VisitTableSwitchInsn(min, max, dflt, labels)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitLookupSwitchInsn(dflt *Label, keys []int, labels []*Label) {
+	if a.Next != nil {
+		a.Next.VisitLookupSwitchInsn(dflt, keys, labels)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitMultiANewArrayInsn(descriptor string, dimensions int) {
+	if a.Next != nil {
+		a.Next.VisitMultiANewArrayInsn(descriptor, dimensions)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitTryCatchBlock(start, end, handler *Label, typ ClassName) {
+	if a.Next != nil {
+		a.Next.VisitTryCatchBlock(start, end, handler, typ)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitLocalVariable(name, descriptor, signature string, start, end *Label, index int) {
+	if a.Next != nil {
+		a.Next.VisitLocalVariable(name, descriptor, signature, start, end, index)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitLineNumber(line int, start *Label) {
+	if a.Next != nil {
+		a.Next.VisitLineNumber(line, start)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitFrame(frame Frame) {
+	if a.Next != nil {
+		a.Next.VisitFrame(frame)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitAttribute(attr JAttribute) {
+	if a.Next != nil {
+		a.Next.VisitAttribute(attr)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitMaxs(maxStack, maxLocals int) {
+	if a.Next != nil {
+		a.Next.VisitMaxs(maxStack, maxLocals)
+	}
+}
+
+func (a *CodeVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
+
+// AnnotationVisitorAdapter forwards all the events to the Next visitor, if any.
+type AnnotationVisitorAdapter struct {
+	Next AnnotationVisitor
+}
+
+func (a *AnnotationVisitorAdapter) Visit(name string, value interface{}) {
+	if a.Next != nil {
+		a.Next.Visit(name, value)
+	}
+}
+
+func (a *AnnotationVisitorAdapter) VisitEnum(name, descriptor, value string) {
+	if a.Next != nil {
+		a.Next.VisitEnum(name, descriptor, value)
+	}
+}
+
+func (a *AnnotationVisitorAdapter) VisitAnnotation(name, descriptor string) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitAnnotation(name, descriptor)
+	}
+	return nil
+}
+
+func (a *AnnotationVisitorAdapter) VisitArray(name string) AnnotationVisitor {
+	if a.Next != nil {
+		return a.Next.VisitArray(name)
+	}
+	return nil
+}
+
+func (a *AnnotationVisitorAdapter) VisitEnd() {
+	if a.Next != nil {
+		a.Next.VisitEnd()
+	}
+}
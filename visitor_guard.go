@@ -0,0 +1,353 @@
+package gytes
+
+// The guards wrap the visitors given to a ClassReader, and count the calls to them that haven't returned, so that
+// protect propagates the panics raised by the visitors instead of reporting them as malformed content.
+// The count isn't decremented when a visitor panics, as protect only sees the panic once the stack is unwound.
+
+func guardClass(c *classParser, cv ClassVisitor) ClassVisitor {
+	if cv == nil {
+		return nil
+	}
+	return &classGuard{c, cv}
+}
+
+func guardAnnotation(c *classParser, av AnnotationVisitor) AnnotationVisitor {
+	if av == nil {
+		return nil
+	}
+	return &annotationGuard{c, av}
+}
+
+type classGuard struct {
+	c    *classParser
+	next ClassVisitor
+}
+
+func (g *classGuard) Visit(version ClassVersion, minorVersion uint16, access ClassAccess, name ClassName, signature string, superName ClassName, interfaces []ClassName) {
+	g.c.visiting++
+	g.next.Visit(version, minorVersion, access, name, signature, superName, interfaces)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitSource(source, debug string) {
+	g.c.visiting++
+	g.next.VisitSource(source, debug)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitNestHost(host ClassName) {
+	g.c.visiting++
+	g.next.VisitNestHost(host)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitAnnotation(descriptor, visible)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *classGuard) VisitAttribute(attr JAttribute) {
+	g.c.visiting++
+	g.next.VisitAttribute(attr)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitNestMember(member ClassName) {
+	g.c.visiting++
+	g.next.VisitNestMember(member)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitPermittedSubclass(subclass ClassName) {
+	g.c.visiting++
+	g.next.VisitPermittedSubclass(subclass)
+	g.c.visiting--
+}
+
+func (g *classGuard) VisitRecordComponent(name, descriptor, signature string) RecordComponentVisitor {
+	g.c.visiting++
+	rv := g.next.VisitRecordComponent(name, descriptor, signature)
+	g.c.visiting--
+	if rv == nil {
+		return nil
+	}
+	return &recordComponentGuard{g.c, rv}
+}
+
+func (g *classGuard) VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor {
+	g.c.visiting++
+	fv := g.next.VisitField(access, name, descriptor, signature, value)
+	g.c.visiting--
+	if fv == nil {
+		return nil
+	}
+	return &fieldGuard{g.c, fv}
+}
+
+func (g *classGuard) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	g.c.visiting++
+	mv := g.next.VisitMethod(access, name, descriptor, signature, exceptions)
+	g.c.visiting--
+	if mv == nil {
+		return nil
+	}
+	return &methodGuard{g.c, mv}
+}
+
+func (g *classGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
+
+type fieldGuard struct {
+	c    *classParser
+	next FieldVisitor
+}
+
+func (g *fieldGuard) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitAnnotation(descriptor, visible)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *fieldGuard) VisitAttribute(attr JAttribute) {
+	g.c.visiting++
+	g.next.VisitAttribute(attr)
+	g.c.visiting--
+}
+
+func (g *fieldGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
+
+type recordComponentGuard struct {
+	c    *classParser
+	next RecordComponentVisitor
+}
+
+func (g *recordComponentGuard) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitAnnotation(descriptor, visible)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *recordComponentGuard) VisitAttribute(attr JAttribute) {
+	g.c.visiting++
+	g.next.VisitAttribute(attr)
+	g.c.visiting--
+}
+
+func (g *recordComponentGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
+
+type methodGuard struct {
+	c    *classParser
+	next MethodVisitor
+}
+
+func (g *methodGuard) VisitAnnotation(descriptor string, visible bool) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitAnnotation(descriptor, visible)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *methodGuard) VisitAttribute(attr JAttribute) {
+	g.c.visiting++
+	g.next.VisitAttribute(attr)
+	g.c.visiting--
+}
+
+func (g *methodGuard) VisitCode() CodeVisitor {
+	g.c.visiting++
+	cv := g.next.VisitCode()
+	g.c.visiting--
+	if cv == nil {
+		return nil
+	}
+	return &codeGuard{g.c, cv}
+}
+
+func (g *methodGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
+
+type codeGuard struct {
+	c    *classParser
+	next CodeVisitor
+}
+
+func (g *codeGuard) VisitInsn(opcode int) {
+	g.c.visiting++
+	g.next.VisitInsn(opcode)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitIntInsn(opcode, operand int) {
+	g.c.visiting++
+	g.next.VisitIntInsn(opcode, operand)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitVarInsn(opcode, index int) {
+	g.c.visiting++
+	g.next.VisitVarInsn(opcode, index)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitTypeInsn(opcode int, typ ClassName) {
+	g.c.visiting++
+	g.next.VisitTypeInsn(opcode, typ)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitFieldInsn(opcode int, owner ClassName, name, descriptor string) {
+	g.c.visiting++
+	g.next.VisitFieldInsn(opcode, owner, name, descriptor)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool) {
+	g.c.visiting++
+	g.next.VisitMethodInsn(opcode, owner, name, descriptor, isInterface)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitInvokeDynamicInsn(name, descriptor string, bootstrap Handle, arguments []interface{}) {
+	g.c.visiting++
+	g.next.VisitInvokeDynamicInsn(name, descriptor, bootstrap, arguments)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitJumpInsn(opcode int, target *Label) {
+	g.c.visiting++
+	g.next.VisitJumpInsn(opcode, target)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitLabel(label *Label) {
+	g.c.visiting++
+	g.next.VisitLabel(label)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitLdcInsn(value interface{}) {
+	g.c.visiting++
+	g.next.VisitLdcInsn(value)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitIincInsn(index, increment int) {
+	g.c.visiting++
+	g.next.VisitIincInsn(index, increment)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitTableSwitchInsn(min, max int, dflt *Label, labels []*Label) {
+	g.c.visiting++
+	g.next.VisitTableSwitchInsn(min, max, dflt, labels)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitLookupSwitchInsn(dflt *Label, keys []int, labels []*Label) {
+	g.c.visiting++
+	g.next.VisitLookupSwitchInsn(dflt, keys, labels)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitMultiANewArrayInsn(descriptor string, dimensions int) {
+	g.c.visiting++
+	g.next.VisitMultiANewArrayInsn(descriptor, dimensions)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitTryCatchBlock(start, end, handler *Label, typ ClassName) {
+	g.c.visiting++
+	g.next.VisitTryCatchBlock(start, end, handler, typ)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitLocalVariable(name, descriptor, signature string, start, end *Label, index int) {
+	g.c.visiting++
+	g.next.VisitLocalVariable(name, descriptor, signature, start, end, index)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitLineNumber(line int, start *Label) {
+	g.c.visiting++
+	g.next.VisitLineNumber(line, start)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitFrame(frame Frame) {
+	g.c.visiting++
+	g.next.VisitFrame(frame)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitAttribute(attr JAttribute) {
+	g.c.visiting++
+	g.next.VisitAttribute(attr)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitMaxs(maxStack, maxLocals int) {
+	g.c.visiting++
+	g.next.VisitMaxs(maxStack, maxLocals)
+	g.c.visiting--
+}
+
+func (g *codeGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
+
+type annotationGuard struct {
+	c    *classParser
+	next AnnotationVisitor
+}
+
+func (g *annotationGuard) Visit(name string, value interface{}) {
+	g.c.visiting++
+	g.next.Visit(name, value)
+	g.c.visiting--
+}
+
+func (g *annotationGuard) VisitEnum(name, descriptor, value string) {
+	g.c.visiting++
+	g.next.VisitEnum(name, descriptor, value)
+	g.c.visiting--
+}
+
+func (g *annotationGuard) VisitAnnotation(name, descriptor string) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitAnnotation(name, descriptor)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *annotationGuard) VisitArray(name string) AnnotationVisitor {
+	g.c.visiting++
+	av := g.next.VisitArray(name)
+	g.c.visiting--
+	return guardAnnotation(g.c, av)
+}
+
+func (g *annotationGuard) VisitEnd() {
+	g.c.visiting++
+	g.next.VisitEnd()
+	g.c.visiting--
+}
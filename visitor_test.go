@@ -0,0 +1,136 @@
+package gytes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type methodNameScanner struct {
+	ClassVisitorAdapter
+	names []string
+}
+
+func (s *methodNameScanner) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	s.names = append(s.names, name+descriptor)
+	return nil
+}
+
+type codeTracer struct {
+	CodeVisitorAdapter
+	trace []string
+}
+
+func (t *codeTracer) VisitInsn(opcode int) {
+	t.trace = append(t.trace, ByteCodes[opcode].Name)
+}
+
+func (t *codeTracer) VisitVarInsn(opcode, index int) {
+	t.trace = append(t.trace, fmt.Sprintf("%s %d", ByteCodes[opcode].Name, index))
+}
+
+func (t *codeTracer) VisitFieldInsn(opcode int, owner ClassName, name, descriptor string) {
+	t.trace = append(t.trace, fmt.Sprintf("%s %s.%s:%s", ByteCodes[opcode].Name, owner, name, descriptor))
+}
+
+func (t *codeTracer) VisitMethodInsn(opcode int, owner ClassName, name, descriptor string, isInterface bool) {
+	t.trace = append(t.trace, fmt.Sprintf("%s %s.%s%s", ByteCodes[opcode].Name, owner, name, descriptor))
+}
+
+func (t *codeTracer) VisitLdcInsn(value interface{}) {
+	t.trace = append(t.trace, fmt.Sprintf("ldc %v", value))
+}
+
+func (t *codeTracer) VisitLineNumber(line int, start *Label) {
+	t.trace = append(t.trace, fmt.Sprintf("line %d", line))
+}
+
+func (t *codeTracer) VisitMaxs(maxStack, maxLocals int) {
+	t.trace = append(t.trace, fmt.Sprintf("maxs %d %d", maxStack, maxLocals))
+}
+
+type methodTracer struct {
+	ClassVisitorAdapter
+	method string
+	code   *codeTracer
+}
+
+func (t *methodTracer) VisitMethod(access MethodAccess, name, descriptor, signature string, exceptions []ClassName) MethodVisitor {
+	if name != t.method {
+		return nil
+	}
+	return &tracingMethod{tracer: t}
+}
+
+type tracingMethod struct {
+	MethodVisitorAdapter
+	tracer *methodTracer
+}
+
+func (m *tracingMethod) VisitCode() CodeVisitor {
+	return m.tracer.code
+}
+
+// Drops the private fields of a class before forwarding it to the next visitor
+type privateFieldFilter struct {
+	ClassVisitorAdapter
+}
+
+func (f *privateFieldFilter) VisitField(access FieldAccess, name, descriptor, signature string, value interface{}) FieldVisitor {
+	if access.Has(ACC_PRIVATE) {
+		return nil
+	}
+	return f.ClassVisitorAdapter.VisitField(access, name, descriptor, signature, value)
+}
+
+func TestCanScanMethodNamesWithVisitor(t *testing.T) {
+	scanner := &methodNameScanner{}
+	err := (&ClassReader{}).Accept(readClassFile("testdata/compiled/Hello.class"), scanner)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"<init>(Ljava/lang/String;)V", "main([Ljava/lang/String;)V"}, scanner.names)
+}
+
+func TestCanVisitMethodCode(t *testing.T) {
+	tracer := &methodTracer{method: "<init>", code: &codeTracer{}}
+	err := (&ClassReader{}).Accept(readClassFile("testdata/compiled/Hello.class"), tracer)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"line 8",
+		"aload 0",
+		"invokespecial java/lang/Object.<init>()V",
+		"line 9",
+		"aload 0",
+		"aload 1",
+		"putfield Hello.message:Ljava/lang/String;",
+		"line 10",
+		"return",
+		"maxs 2 2",
+	}, tracer.code.trace)
+
+	tracer = &methodTracer{method: "main", code: &codeTracer{}}
+	err = (&ClassReader{}).Accept(readClassFile("testdata/compiled/Hello.class"), tracer)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"line 13",
+		"getstatic java/lang/System.out:Ljava/io/PrintStream;",
+		"ldc Hello world",
+		"invokevirtual java/io/PrintStream.println(Ljava/lang/String;)V",
+		"line 14",
+		"return",
+		"maxs 2 1",
+	}, tracer.code.trace)
+}
+
+func TestVisitorsCanBeChained(t *testing.T) {
+	collector := newClassCollector()
+	err := (&ClassReader{}).Accept(readClassFile("testdata/compiled/Hello.class"), &privateFieldFilter{ClassVisitorAdapter{Next: collector}})
+	assert.Nil(t, err)
+
+	jclass := collector.class
+	assert.Equal(t, ClassName("Hello"), jclass.Name)
+	assert.Equal(t, 1, len(jclass.Fields))
+	assert.Equal(t, "MAGIC", jclass.Fields[0].Name)
+	assert.Equal(t, int32(42), jclass.Fields[0].Value)
+	assert.Equal(t, 2, len(jclass.Methods))
+}